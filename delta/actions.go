@@ -8,52 +8,132 @@ import (
 )
 
 type Action struct {
-	Path            string
-	DataChange      bool
-	PartitionValues map[string]string
-	Size            int64
-	Tags            map[string]string
+	Path            string            `json:"path"`
+	DataChange      bool              `json:"dataChange"`
+	PartitionValues map[string]string `json:"partitionValues"`
+	Size            int64             `json:"size"`
+	Tags            map[string]string `json:"tags,omitempty"`
 }
 
 type RemoveAction struct {
 	Action
-	DeletionTimestamp    int64
-	ExtendedFileMetadata bool
+	DeletionTimestamp    int64 `json:"deletionTimestamp,omitempty"`
+	ExtendedFileMetadata bool  `json:"extendedFileMetadata,omitempty"`
 }
 
 type AddAction struct {
 	Action
-	ModificationTime      int64
-	PartitionValuesParsed parquet.RowGroup
-	Stats                 string
-	StatsParsed           parquet.RowGroup
+	ModificationTime      int64            `json:"modificationTime"`
+	PartitionValuesParsed parquet.RowGroup `json:"-"`
+	Stats                 string           `json:"stats,omitempty"`
+	StatsParsed           parquet.RowGroup `json:"-"`
+}
+
+// numRecords returns the number of rows recorded in the statistics of the file.
+func (a AddAction) numRecords() (int64, bool) {
+	var stats struct {
+		NumRecords *int64 `json:"numRecords"`
+	}
+	if a.Stats == "" || json.Unmarshal([]byte(a.Stats), &stats) != nil || stats.NumRecords == nil {
+		return 0, false
+	}
+	return *stats.NumRecords, true
 }
 
 type ActionFormat struct {
-	Provider string
-	Options  map[string]string
+	Provider string            `json:"provider"`
+	Options  map[string]string `json:"options"`
 }
 
 type Metadata struct {
-	ID               uuid.UUID
-	Name             string
-	Description      string
-	Format           ActionFormat
-	SchemaString     string
-	PartitionColumns []string
-	CreatedTime      int64
-	Configuration    map[string]string
+	ID               uuid.UUID         `json:"id"`
+	Name             string            `json:"name,omitempty"`
+	Description      string            `json:"description,omitempty"`
+	Format           ActionFormat      `json:"format"`
+	SchemaString     string            `json:"schemaString"`
+	PartitionColumns []string          `json:"partitionColumns"`
+	CreatedTime      int64             `json:"createdTime,omitempty"`
+	Configuration    map[string]string `json:"configuration"`
+}
+
+// clone returns a copy of the metadata that does not share its slices and maps.
+func (m Metadata) clone() Metadata {
+	c := m
+	c.PartitionColumns = append([]string(nil), m.PartitionColumns...)
+	c.Configuration = make(map[string]string, len(m.Configuration))
+	for k, v := range m.Configuration {
+		c.Configuration[k] = v
+	}
+	if m.Format.Options != nil {
+		c.Format.Options = make(map[string]string, len(m.Format.Options))
+		for k, v := range m.Format.Options {
+			c.Format.Options[k] = v
+		}
+	}
+	return c
 }
 
 type Protocol struct {
-	MinReaderVersion int32
-	MinWriterVersion int32
+	MinReaderVersion int32 `json:"minReaderVersion"`
+	MinWriterVersion int32 `json:"minWriterVersion"`
+	// ReaderFeatures and WriterFeatures list the table features of a table with reader
+	// version 3 or writer version 7.
+	ReaderFeatures []string `json:"readerFeatures,omitempty"`
+	WriterFeatures []string `json:"writerFeatures,omitempty"`
+}
+
+type SetTransaction struct {
+	AppID       string `json:"appId"`
+	Version     int64  `json:"version"`
+	LastUpdated int64  `json:"lastUpdated,omitempty"`
+}
+
+type DomainMetadata struct {
+	Domain        string `json:"domain"`
+	Configuration string `json:"configuration"`
+	Removed       bool   `json:"removed"`
+}
+
+type Sidecar struct {
+	Path             string            `json:"path"`
+	SizeInBytes      int64             `json:"sizeInBytes"`
+	ModificationTime int64             `json:"modificationTime"`
+	Tags             map[string]string `json:"tags,omitempty"`
+}
+
+type CheckpointMetadata struct {
+	Version int64             `json:"version"`
+	Tags    map[string]string `json:"tags,omitempty"`
 }
 
 type CommitInfo map[string]interface{}
 
+// Timestamp returns the commit timestamp in milliseconds since the epoch, if the
+// commit info carries one.
+func (ci CommitInfo) Timestamp() (int64, bool) {
+	return ci.int64Value("timestamp")
+}
+
+// InCommitTimestamp returns the timestamp written by writers that enable the
+// inCommitTimestamp table feature, in milliseconds since the epoch.
+func (ci CommitInfo) InCommitTimestamp() (int64, bool) {
+	return ci.int64Value("inCommitTimestamp")
+}
+
+func (ci CommitInfo) int64Value(key string) (int64, bool) {
+	switch v := ci[key].(type) {
+	case float64:
+		return int64(v), true
+	case int64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
 type ActionTypes interface {
-	AddAction | RemoveAction | Metadata | CommitInfo | Protocol
+	AddAction | RemoveAction | Metadata | CommitInfo | Protocol | SetTransaction | DomainMetadata |
+		Sidecar | CheckpointMetadata
 }
 
 func deserializeAction[T ActionTypes](b []byte) (T, error) {
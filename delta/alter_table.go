@@ -0,0 +1,689 @@
+package delta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/delta-golang/delta-go/delta/expr"
+	"github.com/delta-golang/delta-go/delta/schema"
+	"github.com/google/uuid"
+)
+
+const (
+	// ColumnMappingMaxIDKey is the table property that holds the largest column mapping id
+	// assigned so far. New columns are assigned ids above it.
+	ColumnMappingMaxIDKey = "delta.columnMapping.maxColumnId"
+	// CommentKey is the field metadata key of column comments.
+	CommentKey = "comment"
+)
+
+// invalidColumnNameChars cannot be used in column names without column mapping, because
+// the names are used as-is in Parquet files.
+const invalidColumnNameChars = " ,;{}()\n\t="
+
+// SchemaChange is a change to the table schema, applied by Transaction.AlterSchema.
+type SchemaChange interface {
+	operation() string
+	apply(a *alteration) error
+}
+
+// ColumnPosition is the position of a column among the fields of its struct. The zero
+// value places the column last.
+type ColumnPosition struct {
+	// First places the column before all other fields.
+	First bool
+	// After places the column right after the field with this name.
+	After string
+}
+
+// AddColumn adds a field to the table schema, or to the nested struct at Parent. The
+// field gets column mapping metadata if column mapping is enabled. A field that is not
+// nullable can only be added to a table without data.
+type AddColumn struct {
+	Parent   []string
+	Field    schema.StructField
+	Position ColumnPosition
+}
+
+// SetColumnComment sets the comment of a column. An empty comment removes it.
+type SetColumnComment struct {
+	Path    []string
+	Comment string
+}
+
+// SetColumnNullable changes the nullability of a column. A column can only be made not
+// nullable in a table without data.
+type SetColumnNullable struct {
+	Path     []string
+	Nullable bool
+}
+
+// MoveColumn moves a column to another position within its struct.
+type MoveColumn struct {
+	Path     []string
+	Position ColumnPosition
+}
+
+// RenameColumn renames a column. It requires column mapping, so that data files written
+// with the old name can still be read.
+type RenameColumn struct {
+	Path    []string
+	NewName string
+}
+
+// DropColumn removes a column from the schema. It requires column mapping, so that data
+// files keep their column without it being read. Partition columns cannot be dropped.
+type DropColumn struct {
+	Path []string
+}
+
+// SetColumnMappingMode enables column mapping. Only the upgrade from none to name mode is
+// supported: every column is assigned an id and keeps its current name as physical name.
+type SetColumnMappingMode struct {
+	Mode ColumnMappingMode
+}
+
+// alteration is the table metadata being changed by a sequence of schema changes.
+type alteration struct {
+	md       *Metadata
+	schema   *schema.StructType
+	mode     ColumnMappingMode
+	maxID    int64
+	hasFiles bool
+}
+
+// AlterTable applies changes to the table schema in order and commits the new metadata,
+// upgrading the table protocol if the new metadata requires it. It returns the committed
+// version.
+func (t *Table) AlterTable(ctx context.Context, changes ...SchemaChange) (int64, error) {
+	tx, err := t.NewTransaction()
+	if err != nil {
+		return -1, err
+	}
+	if err := tx.AlterSchema(changes...); err != nil {
+		return -1, err
+	}
+
+	operation := "ALTER TABLE"
+	for i, c := range changes {
+		if i == 0 {
+			operation = c.operation()
+		} else if c.operation() != operation {
+			operation = "ALTER TABLE"
+			break
+		}
+	}
+	return tx.Commit(ctx, operation)
+}
+
+// AlterSchema applies changes in order to the metadata the transaction will commit. The
+// resulting metadata is validated: names must be unique, partition columns must exist,
+// and constraints and generated columns must only reference existing columns. If the
+// new metadata uses features the table protocol does not support, the protocol is
+// upgraded as well. Nothing is staged if a change fails.
+func (tx *Transaction) AlterSchema(changes ...SchemaChange) error {
+	a, err := tx.newAlteration()
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if err := c.apply(a); err != nil {
+			return err
+		}
+	}
+	return tx.stageAlteration(a)
+}
+
+// newAlteration starts changing a copy of the metadata the transaction will commit.
+func (tx *Transaction) newAlteration() (*alteration, error) {
+	md := tx.Metadata().clone()
+	sch, err := schema.Parse(md.SchemaString)
+	if err != nil {
+		return nil, err
+	}
+	conf, err := ParseTableConfig(md.Configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &alteration{
+		md:       &md,
+		schema:   sch,
+		mode:     conf.ColumnMappingMode,
+		maxID:    maxColumnMappingID(sch),
+		hasFiles: len(tx.snapshot.Files()) > 0 || len(tx.adds) > 0,
+	}
+	if v, ok := md.Configuration[ColumnMappingMaxIDKey]; ok {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, &InvalidConfigError{Key: ColumnMappingMaxIDKey, Value: v, Reason: "must be an integer"}
+		}
+		if n > a.maxID {
+			a.maxID = n
+		}
+	}
+	return a, nil
+}
+
+// stageAlteration validates the changed metadata and stages it, along with a protocol
+// upgrade if the metadata uses features the protocol does not support.
+func (tx *Transaction) stageAlteration(a *alteration) error {
+	md := a.md
+	var err error
+	if md.SchemaString, err = schema.Serialize(a.schema); err != nil {
+		return err
+	}
+	if a.mode != ColumnMappingNone {
+		if md.Configuration == nil {
+			md.Configuration = map[string]string{}
+		}
+		md.Configuration[ColumnMappingMaxIDKey] = strconv.FormatInt(a.maxID, 10)
+	}
+
+	features, err := validateMetadata(tx.Metadata(), *md)
+	if err != nil {
+		return err
+	}
+
+	tx.UpdateMetadata(*md)
+	current := tx.Protocol()
+	if upgraded := current.withFeatures(features...); !reflect.DeepEqual(upgraded, current) {
+		tx.UpdateProtocol(upgraded)
+	}
+	return nil
+}
+
+func (c AddColumn) operation() string { return "ADD COLUMNS" }
+
+func (c AddColumn) apply(a *alteration) error {
+	parent, err := a.lookupStruct(c.Parent)
+	if err != nil {
+		return err
+	}
+	name := pathString(append(append([]string(nil), c.Parent...), c.Field.Name))
+	if err := a.checkName(parent, c.Field.Name, -1); err != nil {
+		return err
+	}
+	if !c.Field.Nullable && a.hasFiles {
+		return fmt.Errorf("cannot add column %s that is not nullable to a table with data", name)
+	}
+
+	f := c.Field
+	f.Type = cloneType(f.Type)
+	f.Metadata = cloneFieldMetadata(f.Metadata)
+	delete(f.Metadata, schema.ColumnMappingIDKey)
+	delete(f.Metadata, schema.ColumnMappingPhysicalNameKey)
+	if a.mode != ColumnMappingNone {
+		a.assignColumnMapping(&f, false)
+	}
+
+	parent.Fields, err = insertField(parent.Fields, f, c.Position)
+	return err
+}
+
+func (c SetColumnComment) operation() string { return "CHANGE COLUMN" }
+
+func (c SetColumnComment) apply(a *alteration) error {
+	parent, i, err := a.lookupField(c.Path)
+	if err != nil {
+		return err
+	}
+	f := &parent.Fields[i]
+	f.Metadata = cloneFieldMetadata(f.Metadata)
+	if c.Comment == "" {
+		delete(f.Metadata, CommentKey)
+	} else {
+		f.Metadata[CommentKey] = c.Comment
+	}
+	return nil
+}
+
+func (c SetColumnNullable) operation() string { return "CHANGE COLUMN" }
+
+func (c SetColumnNullable) apply(a *alteration) error {
+	parent, i, err := a.lookupField(c.Path)
+	if err != nil {
+		return err
+	}
+	f := &parent.Fields[i]
+	if f.Nullable && !c.Nullable && a.hasFiles {
+		return fmt.Errorf("cannot make column %s not nullable in a table with data", pathString(c.Path))
+	}
+	f.Nullable = c.Nullable
+	return nil
+}
+
+func (c MoveColumn) operation() string { return "CHANGE COLUMN" }
+
+func (c MoveColumn) apply(a *alteration) error {
+	parent, i, err := a.lookupField(c.Path)
+	if err != nil {
+		return err
+	}
+	f := parent.Fields[i]
+	if strings.EqualFold(c.Position.After, f.Name) {
+		return fmt.Errorf("cannot move column %s after itself", pathString(c.Path))
+	}
+
+	rest := append(append([]schema.StructField(nil), parent.Fields[:i]...), parent.Fields[i+1:]...)
+	parent.Fields, err = insertField(rest, f, c.Position)
+	return err
+}
+
+func (c RenameColumn) operation() string { return "RENAME COLUMN" }
+
+func (c RenameColumn) apply(a *alteration) error {
+	if a.mode == ColumnMappingNone {
+		return fmt.Errorf("renaming column %s requires column mapping, set %s to name first", pathString(c.Path), ColumnMappingModeKey)
+	}
+	parent, i, err := a.lookupField(c.Path)
+	if err != nil {
+		return err
+	}
+	if err := a.checkName(parent, c.NewName, i); err != nil {
+		return err
+	}
+
+	old := parent.Fields[i].Name
+	parent.Fields[i].Name = c.NewName
+	if len(c.Path) == 1 {
+		for j, p := range a.md.PartitionColumns {
+			if strings.EqualFold(p, old) {
+				a.md.PartitionColumns[j] = c.NewName
+			}
+		}
+	}
+	return nil
+}
+
+func (c DropColumn) operation() string { return "DROP COLUMNS" }
+
+func (c DropColumn) apply(a *alteration) error {
+	if a.mode == ColumnMappingNone {
+		return fmt.Errorf("dropping column %s requires column mapping, set %s to name first", pathString(c.Path), ColumnMappingModeKey)
+	}
+	parent, i, err := a.lookupField(c.Path)
+	if err != nil {
+		return err
+	}
+	if len(c.Path) == 1 {
+		for _, p := range a.md.PartitionColumns {
+			if strings.EqualFold(p, parent.Fields[i].Name) {
+				return fmt.Errorf("cannot drop partition column %s", p)
+			}
+		}
+	}
+	if len(parent.Fields) == 1 {
+		return fmt.Errorf("cannot drop column %s, the only field of its struct", pathString(c.Path))
+	}
+	parent.Fields = append(parent.Fields[:i:i], parent.Fields[i+1:]...)
+	return nil
+}
+
+func (c SetColumnMappingMode) operation() string { return "SET TBLPROPERTIES" }
+
+func (c SetColumnMappingMode) apply(a *alteration) error {
+	if c.Mode == a.mode {
+		return nil
+	}
+	if a.mode != ColumnMappingNone || c.Mode != ColumnMappingName {
+		return fmt.Errorf("cannot change column mapping mode from %s to %s", a.mode, c.Mode)
+	}
+
+	for i := range a.schema.Fields {
+		a.assignColumnMapping(&a.schema.Fields[i], true)
+	}
+	if a.md.Configuration == nil {
+		a.md.Configuration = map[string]string{}
+	}
+	a.md.Configuration[ColumnMappingModeKey] = string(c.Mode)
+	a.mode = c.Mode
+	return nil
+}
+
+// lookupStruct returns the struct at path, or the table schema if path is empty.
+func (a *alteration) lookupStruct(path []string) (*schema.StructType, error) {
+	s := a.schema
+	for i, name := range path {
+		f, ok := s.Field(name)
+		if !ok {
+			return nil, fmt.Errorf("column %s not found", pathString(path[:i+1]))
+		}
+		if s = structOf(f.Type); s == nil {
+			return nil, fmt.Errorf("column %s is a %s, not a struct", pathString(path[:i+1]), f.Type)
+		}
+	}
+	return s, nil
+}
+
+// lookupField returns the struct that holds the field at path and the field's index.
+func (a *alteration) lookupField(path []string) (*schema.StructType, int, error) {
+	if len(path) == 0 {
+		return nil, -1, fmt.Errorf("empty column path")
+	}
+	parent, err := a.lookupStruct(path[:len(path)-1])
+	if err != nil {
+		return nil, -1, err
+	}
+	i := parent.FieldIndex(path[len(path)-1])
+	if i < 0 {
+		return nil, -1, fmt.Errorf("column %s not found", pathString(path))
+	}
+	return parent, i, nil
+}
+
+// checkName verifies that name can be given to the field at index self of s, or to a
+// new field of s if self is -1.
+func (a *alteration) checkName(s *schema.StructType, name string, self int) error {
+	if name == "" {
+		return fmt.Errorf("column name must not be empty")
+	}
+	if i := s.FieldIndex(name); i >= 0 && i != self {
+		return fmt.Errorf("column %s already exists", name)
+	}
+	if a.mode == ColumnMappingNone && strings.ContainsAny(name, invalidColumnNameChars) {
+		return fmt.Errorf("column name %q contains characters that require column mapping", name)
+	}
+	return nil
+}
+
+// assignColumnMapping assigns a new column mapping id to f and its nested fields. The
+// physical name is a random name, or the logical name if keepNames is set; keepNames is
+// used when enabling column mapping on a table whose data files use the logical names.
+func (a *alteration) assignColumnMapping(f *schema.StructField, keepNames bool) {
+	a.maxID++
+	f.Metadata = cloneFieldMetadata(f.Metadata)
+	f.Metadata[schema.ColumnMappingIDKey] = json.Number(strconv.FormatInt(a.maxID, 10))
+	physical := f.Name
+	if !keepNames {
+		physical = "col-" + uuid.New().String()
+	}
+	f.Metadata[schema.ColumnMappingPhysicalNameKey] = physical
+
+	for _, nested := range nestedStructs(f.Type) {
+		for i := range nested.Fields {
+			a.assignColumnMapping(&nested.Fields[i], keepNames)
+		}
+	}
+}
+
+func maxColumnMappingID(s *schema.StructType) int64 {
+	var max int64
+	for _, f := range s.Fields {
+		if id, ok := f.ColumnMappingID(); ok && id > max {
+			max = id
+		}
+		for _, nested := range nestedStructs(f.Type) {
+			if id := maxColumnMappingID(nested); id > max {
+				max = id
+			}
+		}
+	}
+	return max
+}
+
+func insertField(fields []schema.StructField, f schema.StructField, pos ColumnPosition) ([]schema.StructField, error) {
+	i := len(fields)
+	switch {
+	case pos.First:
+		i = 0
+	case pos.After != "":
+		i = -1
+		for j, g := range fields {
+			if strings.EqualFold(g.Name, pos.After) {
+				i = j + 1
+			}
+		}
+		if i < 0 {
+			return nil, fmt.Errorf("column %s not found", pos.After)
+		}
+	}
+
+	out := make([]schema.StructField, 0, len(fields)+1)
+	out = append(out, fields[:i]...)
+	out = append(out, f)
+	return append(out, fields[i:]...), nil
+}
+
+// cloneType returns a copy of t whose nested structs can be modified without affecting t.
+func cloneType(t schema.DataType) schema.DataType {
+	switch t := t.(type) {
+	case *schema.StructType:
+		c := &schema.StructType{Fields: make([]schema.StructField, len(t.Fields))}
+		for i, f := range t.Fields {
+			f.Type = cloneType(f.Type)
+			c.Fields[i] = f
+		}
+		return c
+	case *schema.ArrayType:
+		return &schema.ArrayType{ElementType: cloneType(t.ElementType), ContainsNull: t.ContainsNull}
+	case *schema.MapType:
+		return &schema.MapType{KeyType: cloneType(t.KeyType), ValueType: cloneType(t.ValueType), ValueContainsNull: t.ValueContainsNull}
+	}
+	return t
+}
+
+func cloneFieldMetadata(md map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(md)+2)
+	for k, v := range md {
+		out[k] = v
+	}
+	return out
+}
+
+func pathString(path []string) string {
+	return expr.Col(path...).String()
+}
+
+// validateMetadata checks that md, which replaces old, is consistent and returns the table
+// features it uses.
+func validateMetadata(old, md Metadata) ([]string, error) {
+	conf, err := ParseTableConfig(md.Configuration)
+	if err != nil {
+		return nil, err
+	}
+	sch, err := schema.Parse(md.SchemaString)
+	if err != nil {
+		return nil, err
+	}
+	if len(sch.Fields) == 0 {
+		return nil, fmt.Errorf("table schema must have at least one column")
+	}
+	if err := checkDuplicateNames(sch, nil); err != nil {
+		return nil, err
+	}
+
+	for _, p := range md.PartitionColumns {
+		f, ok := sch.Field(p)
+		if !ok {
+			return nil, fmt.Errorf("partition column %s not found in schema", p)
+		}
+		if _, ok := f.Type.(schema.PrimitiveType); !ok {
+			if _, ok := f.Type.(schema.DecimalType); !ok {
+				return nil, fmt.Errorf("partition column %s must have a primitive type, not %s", p, f.Type)
+			}
+		}
+	}
+
+	if _, err := NewColumnMapping(conf.ColumnMappingMode, sch); err != nil {
+		return nil, err
+	}
+	kinds, err := checkConstraintColumns(old, md, sch)
+	if err != nil {
+		return nil, err
+	}
+	identity, err := ParseIdentityColumns(sch)
+	if err != nil {
+		return nil, err
+	}
+
+	var features []string
+	if kinds[ConstraintInvariant] {
+		features = append(features, FeatureInvariants)
+	}
+	if kinds[ConstraintCheck] {
+		features = append(features, FeatureCheckConstraints)
+	}
+	if kinds[ConstraintGenerated] {
+		features = append(features, FeatureGeneratedColumns)
+	}
+	if conf.AppendOnly {
+		features = append(features, FeatureAppendOnly)
+	}
+	if conf.EnableChangeDataFeed {
+		features = append(features, FeatureChangeDataFeed)
+	}
+	if conf.ColumnMappingMode != ColumnMappingNone {
+		features = append(features, FeatureColumnMapping)
+	}
+	if len(identity) > 0 {
+		features = append(features, FeatureIdentityColumns)
+	}
+	if conf.EnableTypeWidening || hasTypeChanges(sch) {
+		features = append(features, FeatureTypeWidening)
+	}
+	if containsType(sch, schema.TimestampNtz) {
+		features = append(features, FeatureTimestampNtz)
+	}
+	if containsType(sch, schema.Variant) {
+		features = append(features, FeatureVariantType)
+	}
+	return features, nil
+}
+
+// checkConstraintColumns checks that the CHECK constraints, invariants and generation
+// expressions of md reference existing columns of sch, and returns the kinds it found.
+// An expression that cannot be parsed is only an error if it differs from old, so that a
+// table with an expression this package does not support can still be altered.
+func checkConstraintColumns(old, md Metadata, sch *schema.StructType) (map[ConstraintKind]bool, error) {
+	oldSch, err := schema.Parse(old.SchemaString)
+	if err != nil {
+		oldSch = &schema.StructType{}
+	}
+
+	kinds := make(map[ConstraintKind]bool)
+	check := func(kind ConstraintKind, name string, e expr.Expr, err error, unchanged bool) error {
+		kinds[kind] = true
+		if err != nil {
+			if unchanged {
+				return nil
+			}
+			return fmt.Errorf("%s constraint %s: %w", kind, name, err)
+		}
+		for _, col := range expr.Columns(e) {
+			if !hasColumn(sch, col.Path) {
+				return fmt.Errorf("%s constraint %s references column %s, which does not exist", kind, name, col)
+			}
+		}
+		return nil
+	}
+
+	var keys []string
+	for k := range md.Configuration {
+		if strings.HasPrefix(k, ConstraintKeyPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		e, err := expr.Parse(md.Configuration[k])
+		prev, ok := old.Configuration[k]
+		if err := check(ConstraintCheck, strings.TrimPrefix(k, ConstraintKeyPrefix), e, err, ok && prev == md.Configuration[k]); err != nil {
+			return nil, err
+		}
+	}
+
+	var walk func(s *schema.StructType, parent []string) error
+	walk = func(s *schema.StructType, parent []string) error {
+		for _, f := range s.Fields {
+			path := append(append([]string(nil), parent...), f.Name)
+			prev, _ := fieldAt(oldSch, path)
+			if raw, ok := f.Metadata[InvariantsKey]; ok {
+				e, err := parseInvariant(raw)
+				if err := check(ConstraintInvariant, expr.Col(path...).String(), e, err, reflect.DeepEqual(prev.Metadata[InvariantsKey], raw)); err != nil {
+					return err
+				}
+			}
+			if raw, ok := f.Metadata[GenerationExpressionKey]; ok && parent == nil {
+				c, _, err := parseGeneratedColumn(f)
+				if err := check(ConstraintGenerated, f.Name, c.Expr, err, reflect.DeepEqual(prev.Metadata[GenerationExpressionKey], raw)); err != nil {
+					return err
+				}
+			}
+			if nested, ok := f.Type.(*schema.StructType); ok {
+				if err := walk(nested, path); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(sch, nil); err != nil {
+		return nil, err
+	}
+	return kinds, nil
+}
+
+// fieldAt returns the field at path in s, through nested structs.
+func fieldAt(s *schema.StructType, path []string) (schema.StructField, bool) {
+	for i, name := range path {
+		f, ok := s.Field(name)
+		if !ok {
+			return schema.StructField{}, false
+		}
+		if i == len(path)-1 {
+			return f, true
+		}
+		if s = structOf(f.Type); s == nil {
+			return schema.StructField{}, false
+		}
+	}
+	return schema.StructField{}, false
+}
+
+func checkDuplicateNames(s *schema.StructType, parent []string) error {
+	seen := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		path := append(append([]string(nil), parent...), f.Name)
+		key := strings.ToLower(f.Name)
+		if seen[key] {
+			return fmt.Errorf("duplicate column %s", pathString(path))
+		}
+		seen[key] = true
+		for _, nested := range nestedStructs(f.Type) {
+			if err := checkDuplicateNames(nested, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hasColumn(s *schema.StructType, path []string) bool {
+	_, ok := fieldAt(s, path)
+	return ok
+}
+
+// containsType reports whether t is the primitive type p or has it nested anywhere.
+func containsType(t schema.DataType, p schema.PrimitiveType) bool {
+	switch t := t.(type) {
+	case schema.PrimitiveType:
+		return t == p
+	case *schema.ArrayType:
+		return containsType(t.ElementType, p)
+	case *schema.MapType:
+		return containsType(t.KeyType, p) || containsType(t.ValueType, p)
+	case *schema.StructType:
+		for _, f := range t.Fields {
+			if containsType(f.Type, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
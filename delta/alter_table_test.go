@@ -0,0 +1,162 @@
+package delta
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+func TestAlterTable(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[`+
+		`{"name":"a","type":"long","nullable":true,"metadata":{}},`+
+		`{"name":"s","type":{"type":"struct","fields":[{"name":"x","type":"integer","nullable":true,"metadata":{}}]},"nullable":true,"metadata":{}}]}`, nil)
+	ctx := context.Background()
+
+	_, err := tbl.AlterTable(ctx,
+		AddColumn{Field: schema.StructField{Name: "b", Type: schema.String, Nullable: true}, Position: ColumnPosition{First: true}},
+		AddColumn{Parent: []string{"s"}, Field: schema.StructField{Name: "y", Type: schema.Date, Nullable: true}},
+		SetColumnComment{Path: []string{"a"}, Comment: "the a column"},
+		SetColumnNullable{Path: []string{"S", "x"}, Nullable: false},
+		MoveColumn{Path: []string{"s"}, Position: ColumnPosition{After: "b"}},
+	)
+	if err != nil {
+		t.Fatalf("error altering table: %s", err)
+	}
+
+	sch, _ := tbl.Snapshot().Schema()
+	if got := strings.Join(sch.FieldNames(), ","); got != "b,s,a" {
+		t.Errorf("expected columns b,s,a, got %s", got)
+	}
+	s, _ := sch.Field("s")
+	nested := s.Type.(*schema.StructType)
+	if got := strings.Join(nested.FieldNames(), ","); got != "x,y" || nested.Fields[0].Nullable {
+		t.Errorf("expected non-nullable x followed by y, got %s", nested)
+	}
+	if a, _ := sch.Field("a"); a.Metadata[CommentKey] != "the a column" {
+		t.Errorf("expected comment on a, got %v", a.Metadata)
+	}
+	infos := tbl.Snapshot().CommitInfos()
+	if op := infos[len(infos)-1]["operation"]; op != "ALTER TABLE" {
+		t.Errorf("expected ALTER TABLE operation, got %v", op)
+	}
+
+	for _, tt := range []struct {
+		change SchemaChange
+		err    string
+	}{
+		{AddColumn{Field: schema.StructField{Name: "A", Type: schema.Long, Nullable: true}}, "already exists"},
+		{AddColumn{Field: schema.StructField{Name: "c d", Type: schema.Long, Nullable: true}}, "require column mapping"},
+		{AddColumn{Parent: []string{"a"}, Field: schema.StructField{Name: "c", Type: schema.Long, Nullable: true}}, "not a struct"},
+		{AddColumn{Field: schema.StructField{Name: "c", Type: schema.Long, Nullable: true}, Position: ColumnPosition{After: "z"}}, "not found"},
+		{RenameColumn{Path: []string{"a"}, NewName: "c"}, "requires column mapping"},
+		{DropColumn{Path: []string{"a"}}, "requires column mapping"},
+		{SetColumnMappingMode{Mode: ColumnMappingID}, "cannot change column mapping mode"},
+	} {
+		if _, err := tbl.AlterTable(ctx, tt.change); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%+v: expected error containing %q, got %v", tt.change, tt.err, err)
+		}
+	}
+	if tbl.Version() != 1 {
+		t.Errorf("expected failed changes not to be committed, got version %d", tbl.Version())
+	}
+}
+
+func TestAlterTableUnsupportedExpressions(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[`+
+		`{"name":"ts","type":"timestamp_ntz","nullable":true,"metadata":{}},`+
+		`{"name":"m","type":"string","nullable":true,"metadata":{"delta.generationExpression":"date_format(ts, 'yyyy-MM')"}}]}`,
+		map[string]string{"delta.constraints.recent": "date_format(ts, 'yyyy') > '2000'"})
+	ctx := context.Background()
+
+	// expressions the table already has are not parsed again
+	if _, err := tbl.AlterTable(ctx, AddColumn{Field: schema.StructField{Name: "a", Type: schema.Long, Nullable: true}}); err != nil {
+		t.Fatalf("error adding a column: %s", err)
+	}
+
+	// new ones are
+	_, err := tbl.AlterTable(ctx, SetTableProperties{Properties: map[string]string{"delta.constraints.recent": "date_format(ts, 'yyyy') > '2010'"}})
+	if err == nil || !strings.Contains(err.Error(), "CHECK constraint recent") {
+		t.Errorf("expected error for a changed unsupported CHECK constraint, got %v", err)
+	}
+	_, err = tbl.AlterTable(ctx, AddColumn{Field: schema.StructField{Name: "d", Type: schema.String, Nullable: true,
+		Metadata: map[string]interface{}{GenerationExpressionKey: "date_format(ts, 'yyyy-MM-dd')"}}})
+	if err == nil || !strings.Contains(err.Error(), "GENERATED COLUMN constraint d") {
+		t.Errorf("expected error for a new unsupported generated column, got %v", err)
+	}
+}
+
+func TestAlterTableColumnMapping(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[`+
+		`{"name":"a","type":"long","nullable":true,"metadata":{}},`+
+		`{"name":"p","type":"string","nullable":true,"metadata":{}},`+
+		`{"name":"s","type":{"type":"struct","fields":[{"name":"x","type":"integer","nullable":true,"metadata":{}}]},"nullable":true,"metadata":{}},`+
+		`{"name":"m","type":{"type":"map","keyType":"string","valueType":{"type":"struct","fields":[{"name":"v","type":"long","nullable":true,"metadata":{}}]},"valueContainsNull":true},"nullable":true,"metadata":{}}]}`,
+		map[string]string{ConstraintKeyPrefix + "positive": "a > 0"})
+	ctx := context.Background()
+
+	if _, err := tbl.AlterTable(ctx, SetColumnMappingMode{Mode: ColumnMappingName}); err != nil {
+		t.Fatalf("error enabling column mapping: %s", err)
+	}
+	if p := tbl.Snapshot().Protocol(); p.MinReaderVersion != 2 || p.MinWriterVersion != 5 {
+		t.Errorf("expected protocol upgrade to 2/5, got %+v", p)
+	}
+	m, err := tbl.Snapshot().ColumnMapping()
+	if err != nil {
+		t.Fatalf("error reading column mapping: %s", err)
+	}
+	if path, _ := m.PhysicalPath("s", "x"); strings.Join(path, ".") != "s.x" {
+		t.Errorf("expected existing columns to keep their physical names, got %v", path)
+	}
+	if got := tbl.Snapshot().Metadata().Configuration[ColumnMappingMaxIDKey]; got != "6" {
+		t.Errorf("expected max column id 6, got %s", got)
+	}
+
+	items := &schema.ArrayType{ElementType: schema.NewStructType(schema.StructField{Name: "sku", Type: schema.String, Nullable: true}), ContainsNull: true}
+	_, err = tbl.AlterTable(ctx,
+		RenameColumn{Path: []string{"s", "x"}, NewName: "x y"},
+		AddColumn{Field: schema.StructField{Name: "new col", Type: schema.Long, Nullable: true}},
+		AddColumn{Field: schema.StructField{Name: "items", Type: items, Nullable: true}},
+		DropColumn{Path: []string{"p"}},
+	)
+	if err != nil {
+		t.Fatalf("error altering table: %s", err)
+	}
+	m, _ = tbl.Snapshot().ColumnMapping()
+	if path, err := m.PhysicalPath("s", "x y"); err != nil || strings.Join(path, ".") != "s.x" {
+		t.Errorf("expected renamed column to keep its physical name, got %v (%v)", path, err)
+	}
+	sch, _ := tbl.Snapshot().Schema()
+	f, _ := sch.Field("new col")
+	if id, _ := f.ColumnMappingID(); id != 7 {
+		t.Errorf("expected new column to get id 7, got %d", id)
+	}
+	if physical, _ := f.PhysicalName(); !strings.HasPrefix(physical, "col-") {
+		t.Errorf("expected a generated physical name, got %s", physical)
+	}
+	// fields of structs inside maps and arrays get column mapping metadata as well
+	m2, _ := sch.Field("m")
+	v := m2.Type.(*schema.MapType).ValueType.(*schema.StructType).Fields[0]
+	if physical, _ := v.PhysicalName(); physical != "v" {
+		t.Errorf("expected map value field to keep its physical name, got %s", physical)
+	}
+	f, _ = sch.Field("items")
+	sku := f.Type.(*schema.ArrayType).ElementType.(*schema.StructType).Fields[0]
+	if id, _ := sku.ColumnMappingID(); id != 9 {
+		t.Errorf("expected array element field to get id 9, got %d", id)
+	}
+	if physical, _ := sku.PhysicalName(); !strings.HasPrefix(physical, "col-") {
+		t.Errorf("expected a generated physical name for the array element field, got %s", physical)
+	}
+	if _, ok := items.ElementType.(*schema.StructType).Fields[0].ColumnMappingID(); ok {
+		t.Errorf("expected the added column type not to be modified")
+	}
+	if _, ok := sch.Field("p"); ok {
+		t.Errorf("expected p to be dropped")
+	}
+
+	if _, err := tbl.AlterTable(ctx, DropColumn{Path: []string{"a"}}); err == nil || !strings.Contains(err.Error(), "references column a") {
+		t.Errorf("expected error dropping a column referenced by a constraint, got %v", err)
+	}
+}
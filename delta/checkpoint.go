@@ -0,0 +1,403 @@
+package delta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/apache/arrow/go/v8/parquet/file"
+	"github.com/apache/arrow/go/v8/parquet/pqarrow"
+	"github.com/delta-golang/delta-go/delta/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	checkpointBatchSize      = 1024
+	defaultCheckpointWorkers = 8
+)
+
+// checkpointActions are the checkpoint columns that make up a table snapshot. Other
+// columns, such as commitInfo or cdc, are never read.
+var checkpointActions = map[string]struct{}{
+	"add":                {},
+	"remove":             {},
+	"metaData":           {},
+	"protocol":           {},
+	"txn":                {},
+	"domainMetadata":     {},
+	"checkpointMetadata": {},
+	"sidecar":            {},
+}
+
+func (t *Table) restoreCheckpoint(cp Checkpoint, state *TableState) error {
+
+	if v2 := cp.V2Checkpoint; v2 != nil && v2.NonFileActions != nil && v2.SidecarFiles != nil {
+		// _last_checkpoint already carries everything but the file actions, so the
+		// top-level checkpoint file does not need to be read
+		for _, a := range v2.NonFileActions {
+			err := applyJSONAction(a, state)
+			if err != nil {
+				return fmt.Errorf("decoding checkpoint %d hints: %w", cp.Version, err)
+			}
+		}
+		return t.readSidecars(cp, v2.SidecarFiles, state)
+	}
+
+	paths := checkpointPathsForCheckpoint(cp)
+	if len(paths) == 1 {
+		err := t.readCheckpointFile(paths[0], state)
+		if err != nil {
+			return fmt.Errorf("reading checkpoint file %s: %w", paths[0], err)
+		}
+	} else {
+		parts, err := t.readCheckpointFiles(paths, func(i int, err error) error {
+			if errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("checkpoint %d is missing part %d of %d (%s): %w", cp.Version, i+1, cp.Parts, paths[i], err)
+			}
+			return fmt.Errorf("reading checkpoint file %s: %w", paths[i], err)
+		})
+		if err != nil {
+			return err
+		}
+
+		// parts are combined in part order so the resulting state does not depend on
+		// which worker finished first
+		for _, p := range parts {
+			state.addCheckpointPart(p)
+		}
+	}
+
+	sidecars := state.sidecars
+	state.sidecars = nil
+	return t.readSidecars(cp, sidecars, state)
+}
+
+// readSidecars decodes the file actions stored in the sidecar files of a V2 checkpoint.
+func (t *Table) readSidecars(cp Checkpoint, sidecars []Sidecar, state *TableState) error {
+	if len(sidecars) == 0 {
+		return nil
+	}
+
+	paths := make([]string, len(sidecars))
+	for i, sc := range sidecars {
+		p, err := sidecarPath(sc)
+		if err != nil {
+			return err
+		}
+		paths[i] = p
+	}
+
+	parts, err := t.readCheckpointFiles(paths, func(i int, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("checkpoint %d is missing sidecar %s: %w", cp.Version, paths[i], err)
+		}
+		return fmt.Errorf("reading sidecar file %s: %w", paths[i], err)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range parts {
+		// sidecars only hold add and remove actions
+		p.sidecars = nil
+		state.addCheckpointPart(p)
+	}
+	return nil
+}
+
+// sidecarPath returns the location of a sidecar file relative to the table root. Sidecars
+// always live in the _delta_log/_sidecars directory, so only the file name is used.
+func sidecarPath(sc Sidecar) (string, error) {
+	p, err := url.PathUnescape(sc.Path)
+	if err != nil {
+		return "", fmt.Errorf("invalid sidecar path %s: %w", sc.Path, err)
+	}
+	return filepath.Join(LogDir, SidecarDir, path.Base(p)), nil
+}
+
+// readCheckpointFiles decodes checkpoint files using a bounded number of concurrent
+// readers. The returned states are in the order of paths. The error of a failed file is
+// passed through wrap together with its index.
+func (t *Table) readCheckpointFiles(paths []string, wrap func(int, error) error) ([]*TableState, error) {
+	workers := t.Options.CheckpointWorkers
+	if workers <= 0 {
+		workers = defaultCheckpointWorkers
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	var (
+		parts = make([]*TableState, len(paths))
+		errs  = make([]error, len(paths))
+		next  = make(chan int)
+		done  = make(chan struct{})
+		once  sync.Once
+		wg    sync.WaitGroup
+	)
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				part := newTableState()
+				err := t.readCheckpointFile(paths[i], &part)
+				if err != nil {
+					errs[i] = wrap(i, err)
+					once.Do(func() { close(done) })
+					continue
+				}
+				parts[i] = &part
+			}
+		}()
+	}
+
+	// stop handing out files as soon as one of them fails
+dispatch:
+	for i := range paths {
+		select {
+		case next <- i:
+		case <-done:
+			break dispatch
+		}
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parts, nil
+}
+
+// addCheckpointPart adds the actions of one checkpoint part to the state. The parts of a
+// checkpoint never overlap, so unlike merge no reconciliation between them is needed.
+func (s *TableState) addCheckpointPart(p *TableState) {
+	s.Files = append(s.Files, p.Files...)
+	s.sidecars = append(s.sidecars, p.sidecars...)
+	for k, v := range p.Tombstones {
+		s.Tombstones[k] = v
+	}
+	for k, v := range p.AppTransactionVersion {
+		s.AppTransactionVersion[k] = v
+	}
+	for k, v := range p.DomainMetadata {
+		s.DomainMetadata[k] = v
+	}
+
+	if p.MinReaderVersion > 0 {
+		s.MinReaderVersion = p.MinReaderVersion
+		s.MinWriterVersion = p.MinWriterVersion
+		s.ReaderFeatures = p.ReaderFeatures
+		s.WriterFeatures = p.WriterFeatures
+	}
+	if p.CurrentMetadata.ID != uuid.Nil {
+		s.CurrentMetadata = p.CurrentMetadata
+	}
+}
+
+// readCheckpointFile decodes every action stored in a checkpoint file into state. V2
+// checkpoints may be stored as JSON, all other checkpoint files are parquet.
+func (t *Table) readCheckpointFile(path string, state *TableState) error {
+	if strings.HasSuffix(path, ".json") {
+		return t.readJSONCheckpointFile(path, state)
+	}
+
+	r, err := t.Storage.OpenObject(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	pf, err := file.NewParquetReader(storage.NewReadSeekerAt(r))
+	if err != nil {
+		return err
+	}
+	defer pf.Close()
+
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{BatchSize: checkpointBatchSize}, memory.DefaultAllocator)
+	if err != nil {
+		return err
+	}
+
+	// only read the leaf columns of the actions that belong to a snapshot
+	sc := pf.MetaData().Schema
+	var cols []int
+	for i := 0; i < sc.NumColumns(); i++ {
+		if _, ok := checkpointActions[sc.Column(i).ColumnPath()[0]]; ok {
+			cols = append(cols, i)
+		}
+	}
+	if len(cols) == 0 {
+		return nil
+	}
+
+	rr, err := fr.GetRecordReader(context.Background(), cols, nil)
+	if err != nil {
+		return err
+	}
+	defer rr.Release()
+
+	for {
+		rec, err := rr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = applyCheckpointRecord(rec, state)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readJSONCheckpointFile decodes a V2 checkpoint stored as newline delimited JSON.
+func (t *Table) readJSONCheckpointFile(path string, state *TableState) error {
+	scanner, c, err := t.Storage.GetObject(path)
+	if err != nil {
+		return err
+	}
+	defer c()
+
+	for scanner.Scan() {
+		err = applyJSONAction(scanner.Bytes(), state)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// applyJSONAction applies a single line of a JSON checkpoint, or a single non-file action
+// hint from _last_checkpoint, to state.
+func applyJSONAction(b []byte, state *TableState) error {
+	var ac map[string]json.RawMessage
+	err := json.Unmarshal(b, &ac)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range ac {
+		err = applyCheckpointAction(k, v, state)
+		if err != nil {
+			return fmt.Errorf("decoding %s action: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// applyCheckpointAction applies an action read from a checkpoint. Sidecar actions are
+// collected so that the files they point to can be read once the checkpoint is decoded.
+func applyCheckpointAction(kind string, v []byte, state *TableState) error {
+	if kind != "sidecar" {
+		return state.applyAction(kind, v)
+	}
+
+	sc, err := deserializeAction[Sidecar](v)
+	if err != nil {
+		return err
+	}
+	state.sidecars = append(state.sidecars, sc)
+	return nil
+}
+
+// applyCheckpointRecord applies the non-null action of every checkpoint row in rec.
+// Actions are converted to their JSON form so that they are decoded exactly like the
+// actions of a commit file.
+func applyCheckpointRecord(rec arrow.Record, state *TableState) error {
+	for c, f := range rec.Schema().Fields() {
+		col := rec.Column(c)
+		for i := 0; i < col.Len(); i++ {
+			if col.IsNull(i) {
+				continue
+			}
+
+			b, err := json.Marshal(arrowValue(col, i))
+			if err != nil {
+				return err
+			}
+
+			err = applyCheckpointAction(f.Name, b, state)
+			if err != nil {
+				return fmt.Errorf("decoding %s action: %w", f.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// arrowValue converts the value at index i of arr into the Go value encoding/json would
+// produce for the same value in a commit file. Maps become JSON objects keyed by the
+// string form of their keys.
+func arrowValue(arr arrow.Array, i int) interface{} {
+	if arr.IsNull(i) {
+		return nil
+	}
+
+	switch a := arr.(type) {
+	case *array.Boolean:
+		return a.Value(i)
+	case *array.Int8:
+		return a.Value(i)
+	case *array.Int16:
+		return a.Value(i)
+	case *array.Int32:
+		return a.Value(i)
+	case *array.Int64:
+		return a.Value(i)
+	case *array.Float32:
+		return a.Value(i)
+	case *array.Float64:
+		return a.Value(i)
+	case *array.String:
+		return a.Value(i)
+	case *array.Binary:
+		return a.Value(i)
+	case *array.Timestamp:
+		unit := a.DataType().(*arrow.TimestampType).Unit
+		return int64(a.Value(i)) * int64(unit.Multiplier()) / int64(time.Millisecond)
+	case *array.Struct:
+		st := a.DataType().(*arrow.StructType)
+		m := make(map[string]interface{}, a.NumField())
+		for j := 0; j < a.NumField(); j++ {
+			m[st.Field(j).Name] = arrowValue(a.Field(j), i)
+		}
+		return m
+	case *array.Map:
+		offsets := a.Offsets()
+		m := make(map[string]interface{}, offsets[i+1]-offsets[i])
+		for j := int(offsets[i]); j < int(offsets[i+1]); j++ {
+			m[fmt.Sprint(arrowValue(a.Keys(), j))] = arrowValue(a.Items(), j)
+		}
+		return m
+	case *array.List:
+		offsets := a.Offsets()
+		l := make([]interface{}, 0, offsets[i+1]-offsets[i])
+		for j := int(offsets[i]); j < int(offsets[i+1]); j++ {
+			l = append(l, arrowValue(a.ListValues(), j))
+		}
+		return l
+	default:
+		// only the parsed stats and partition value columns use other types, and
+		// those are not part of the JSON actions
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+package delta
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/apache/arrow/go/v8/parquet"
+	"github.com/apache/arrow/go/v8/parquet/pqarrow"
+)
+
+var testCheckpointSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "add", Nullable: true, Type: arrow.StructOf(
+		arrow.Field{Name: "path", Type: arrow.BinaryTypes.String},
+		arrow.Field{Name: "size", Type: arrow.PrimitiveTypes.Int64},
+		arrow.Field{Name: "modificationTime", Type: arrow.PrimitiveTypes.Int64},
+		arrow.Field{Name: "dataChange", Type: arrow.FixedWidthTypes.Boolean},
+	)},
+	{Name: "remove", Nullable: true, Type: arrow.StructOf(
+		arrow.Field{Name: "path", Type: arrow.BinaryTypes.String},
+		arrow.Field{Name: "deletionTimestamp", Type: arrow.PrimitiveTypes.Int64},
+		arrow.Field{Name: "dataChange", Type: arrow.FixedWidthTypes.Boolean},
+	)},
+	{Name: "protocol", Nullable: true, Type: arrow.StructOf(
+		arrow.Field{Name: "minReaderVersion", Type: arrow.PrimitiveTypes.Int32},
+		arrow.Field{Name: "minWriterVersion", Type: arrow.PrimitiveTypes.Int32},
+	)},
+}, nil)
+
+// writeTestCheckpoint writes a checkpoint file with one row group per action row.
+func writeTestCheckpoint(t *testing.T, path string, rows ...string) {
+	t.Helper()
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, testCheckpointSchema, strings.NewReader("["+strings.Join(rows, ",")+"]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+
+	tbl := array.NewTableFromRecords(testCheckpointSchema, []arrow.Record{rec})
+	defer tbl.Release()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	err = pqarrow.WriteTable(tbl, f, 1, parquet.NewWriterProperties(), pqarrow.DefaultWriterProps())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestoreMultiPartCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, LogDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cp := Checkpoint{Version: 5, Parts: 3}
+	paths := checkpointPathsForCheckpoint(cp)
+	add := func(p string) string {
+		return fmt.Sprintf(`{"add":{"path":%q,"size":1,"modificationTime":1,"dataChange":false},"remove":null,"protocol":null}`, p)
+	}
+	writeTestCheckpoint(t, filepath.Join(dir, paths[0]), `{"add":null,"remove":null,"protocol":{"minReaderVersion":1,"minWriterVersion":2}}`, add("a"), add("b"))
+	writeTestCheckpoint(t, filepath.Join(dir, paths[1]), add("c"), add("d"), add("e"))
+	writeTestCheckpoint(t, filepath.Join(dir, paths[2]), add("f"), `{"add":null,"remove":{"path":"g","deletionTimestamp":1,"dataChange":true},"protocol":null}`)
+
+	tbl := NewTable(dir)
+	tbl.Options.CheckpointWorkers = 2
+	state := newTableState()
+	if err := tbl.restoreCheckpoint(cp, &state); err != nil {
+		t.Fatalf("error restoring checkpoint: %s", err)
+	}
+
+	var got []string
+	for _, f := range state.Files {
+		got = append(got, f.Path)
+	}
+	if strings.Join(got, ",") != "a,b,c,d,e,f" {
+		t.Errorf("expected files in part order, got %v", got)
+	}
+	if _, ok := state.Tombstones["g"]; !ok || len(state.Tombstones) != 1 {
+		t.Errorf("expected tombstone g, got %v", state.Tombstones)
+	}
+	if state.MinReaderVersion != 1 || state.MinWriterVersion != 2 {
+		t.Errorf("unexpected protocol %d/%d", state.MinReaderVersion, state.MinWriterVersion)
+	}
+
+	if err := os.Remove(filepath.Join(dir, paths[1])); err != nil {
+		t.Fatal(err)
+	}
+	state = newTableState()
+	err := tbl.restoreCheckpoint(cp, &state)
+	if !errors.Is(err, os.ErrNotExist) || !strings.Contains(err.Error(), "part 2 of 3") {
+		t.Errorf("expected missing part error, got %v", err)
+	}
+}
+
+func TestRestoreV2Checkpoint(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, LogDir, SidecarDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	add := func(p string) string {
+		return fmt.Sprintf(`{"add":{"path":%q,"size":1,"modificationTime":1,"dataChange":false},"remove":null,"protocol":null}`, p)
+	}
+	writeTestCheckpoint(t, filepath.Join(dir, LogDir, SidecarDir, "016ae953-37a9-438e-8683-9a9a4a79a395.parquet"), add("a"), add("b"))
+	writeTestCheckpoint(t, filepath.Join(dir, LogDir, SidecarDir, "3a0d65cd-4056-49b8-937b-95f9e3ee90e5.parquet"), add("c"))
+
+	protocol := `{"protocol":{"minReaderVersion":3,"minWriterVersion":7,"readerFeatures":["v2Checkpoint"],"writerFeatures":["v2Checkpoint"]}}`
+	metadata := `{"metaData":{"id":"5fba94ed-9794-4965-ba6e-6ee3c0d22af9","format":{"provider":"parquet","options":{}},"schemaString":"{\"type\":\"struct\",\"fields\":[]}","partitionColumns":[],"configuration":{},"createdTime":1}}`
+	sidecars := []string{
+		`{"sidecar":{"path":"016ae953-37a9-438e-8683-9a9a4a79a395.parquet","sizeInBytes":1,"modificationTime":1}}`,
+		`{"sidecar":{"path":"3a0d65cd-4056-49b8-937b-95f9e3ee90e5.parquet","sizeInBytes":1,"modificationTime":1}}`,
+	}
+	top := "00000000000000000002.checkpoint.80a083e8-7026-4e79-81be-64bd76c43a11.json"
+	lines := append([]string{`{"checkpointMetadata":{"version":2}}`, protocol, metadata}, sidecars...)
+	if err := os.WriteFile(filepath.Join(dir, LogDir, top), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	commit := `{"add":{"path":"d","partitionValues":{},"size":1,"modificationTime":1,"dataChange":true}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, commitPathForVersion(3)), []byte(commit), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	hints := fmt.Sprintf(`,"nonFileActions":[%s,%s,{"checkpointMetadata":{"version":2}}],"sidecarFiles":[%s,%s]`,
+		protocol, metadata, sidecars[0][11:len(sidecars[0])-1], sidecars[1][11:len(sidecars[1])-1])
+	for _, lastCheckpoint := range []string{
+		fmt.Sprintf(`{"version":2,"size":6,"v2Checkpoint":{"path":%q,"sizeInBytes":1,"modificationTime":1}}`, top),
+		fmt.Sprintf(`{"version":2,"size":6,"v2Checkpoint":{"path":%q,"sizeInBytes":1,"modificationTime":1%s}}`, "missing.json", hints),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, LogDir, LastCheckPointFile), []byte(lastCheckpoint), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		tbl, err := LoadTable(dir)
+		if err != nil {
+			t.Fatalf("error loading table: %s", err)
+		}
+
+		s := tbl.Snapshot()
+		var got []string
+		for _, f := range s.Files() {
+			got = append(got, f.Path)
+		}
+		if s.Version() != 3 || strings.Join(got, ",") != "a,b,c,d" {
+			t.Errorf("expected version 3 with files a,b,c,d, got version %d with %v", s.Version(), got)
+		}
+		if s.Protocol().MinReaderVersion != 3 || s.Metadata().SchemaString == "" {
+			t.Errorf("unexpected protocol %+v or metadata %+v", s.Protocol(), s.Metadata())
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package delta
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+// ColumnMapping translates between the logical column names of the table schema and the
+// physical names used in data files, partition values and file statistics. When column
+// mapping is disabled both names are the same.
+type ColumnMapping struct {
+	mode    ColumnMappingMode
+	schema  *schema.StructType
+	columns *columnIndex
+}
+
+type columnIndex struct {
+	byLogical  map[string]*columnNode
+	byPhysical map[string]*columnNode
+}
+
+type columnNode struct {
+	logical  string
+	physical string
+	children *columnIndex
+}
+
+// ColumnMapping returns the column mapping of the table at the snapshot version.
+func (s *Snapshot) ColumnMapping() (*ColumnMapping, error) {
+	if s.configErr != nil {
+		return nil, s.configErr
+	}
+	sch, err := s.Schema()
+	if err != nil {
+		return nil, err
+	}
+	return NewColumnMapping(s.config.ColumnMappingMode, sch)
+}
+
+// NewColumnMapping returns the column mapping for a table schema. In name and id mode
+// every field, including nested fields, must have a physical name and a column mapping id.
+func NewColumnMapping(mode ColumnMappingMode, sch *schema.StructType) (*ColumnMapping, error) {
+	columns, err := indexColumns(mode, sch, "")
+	if err != nil {
+		return nil, err
+	}
+	return &ColumnMapping{mode: mode, schema: sch, columns: columns}, nil
+}
+
+func indexColumns(mode ColumnMappingMode, s *schema.StructType, prefix string) (*columnIndex, error) {
+	idx := &columnIndex{
+		byLogical:  make(map[string]*columnNode, len(s.Fields)),
+		byPhysical: make(map[string]*columnNode, len(s.Fields)),
+	}
+
+	for _, f := range s.Fields {
+		n := &columnNode{logical: f.Name, physical: f.Name}
+		if mode != ColumnMappingNone {
+			physical, ok := f.PhysicalName()
+			if !ok {
+				return nil, fmt.Errorf("column %s%s has no physical name in column mapping mode %s", prefix, f.Name, mode)
+			}
+			if _, ok := f.ColumnMappingID(); !ok {
+				return nil, fmt.Errorf("column %s%s has no column mapping id in column mapping mode %s", prefix, f.Name, mode)
+			}
+			n.physical = physical
+		}
+
+		if nested := structOf(f.Type); nested != nil {
+			children, err := indexColumns(mode, nested, prefix+f.Name+".")
+			if err != nil {
+				return nil, err
+			}
+			n.children = children
+		} else {
+			// structs inside arrays and maps are not addressable, but their fields
+			// still need column mapping metadata
+			for _, nested := range nestedStructs(f.Type) {
+				if _, err := indexColumns(mode, nested, prefix+f.Name+"."); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		idx.byLogical[strings.ToLower(n.logical)] = n
+		idx.byPhysical[n.physical] = n
+	}
+	return idx, nil
+}
+
+// structOf returns the struct type t is, or nil. Structs inside arrays and maps are not
+// addressable by column path and are not returned; see nestedStructs.
+func structOf(t schema.DataType) *schema.StructType {
+	s, _ := t.(*schema.StructType)
+	return s
+}
+
+// nestedStructs returns the structs whose fields are nested directly in a field of type
+// t: t itself if it is a struct, or the structs found in the element, key and value types
+// of arrays and maps, at any depth.
+func nestedStructs(t schema.DataType) []*schema.StructType {
+	switch t := t.(type) {
+	case *schema.StructType:
+		return []*schema.StructType{t}
+	case *schema.ArrayType:
+		return nestedStructs(t.ElementType)
+	case *schema.MapType:
+		return append(nestedStructs(t.KeyType), nestedStructs(t.ValueType)...)
+	}
+	return nil
+}
+
+// Mode returns the column mapping mode of the table.
+func (m *ColumnMapping) Mode() ColumnMappingMode {
+	return m.mode
+}
+
+// PhysicalPath translates a logical column path, such as ["address", "city"], to the
+// names used in data files. Logical names are matched case-insensitively.
+func (m *ColumnMapping) PhysicalPath(logical ...string) ([]string, error) {
+	return m.translate(logical, func(idx *columnIndex, name string) *columnNode {
+		return idx.byLogical[strings.ToLower(name)]
+	}, func(n *columnNode) string {
+		return n.physical
+	})
+}
+
+// LogicalPath translates a column path as found in data files or statistics to the
+// logical names of the table schema.
+func (m *ColumnMapping) LogicalPath(physical ...string) ([]string, error) {
+	return m.translate(physical, func(idx *columnIndex, name string) *columnNode {
+		return idx.byPhysical[name]
+	}, func(n *columnNode) string {
+		return n.logical
+	})
+}
+
+func (m *ColumnMapping) translate(path []string, lookup func(*columnIndex, string) *columnNode, name func(*columnNode) string) ([]string, error) {
+	out := make([]string, len(path))
+	idx := m.columns
+	for i, p := range path {
+		if idx == nil {
+			return nil, fmt.Errorf("column %s is not a struct", strings.Join(path[:i], "."))
+		}
+		n := lookup(idx, p)
+		if n == nil {
+			return nil, fmt.Errorf("column %s not found", strings.Join(path[:i+1], "."))
+		}
+		out[i] = name(n)
+		idx = n.children
+	}
+	return out, nil
+}
+
+// PhysicalSchema returns the table schema with every field renamed to its physical name,
+// which is the schema of the data files. Field metadata, including column mapping ids
+// used to match columns in id mode, is kept.
+func (m *ColumnMapping) PhysicalSchema() *schema.StructType {
+	if m.mode == ColumnMappingNone {
+		return m.schema
+	}
+	return physicalStruct(m.schema)
+}
+
+func physicalStruct(s *schema.StructType) *schema.StructType {
+	out := &schema.StructType{Fields: make([]schema.StructField, len(s.Fields))}
+	for i, f := range s.Fields {
+		if physical, ok := f.PhysicalName(); ok {
+			f.Name = physical
+		}
+		f.Type = physicalType(f.Type)
+		out.Fields[i] = f
+	}
+	return out
+}
+
+func physicalType(t schema.DataType) schema.DataType {
+	switch t := t.(type) {
+	case *schema.StructType:
+		return physicalStruct(t)
+	case *schema.ArrayType:
+		return &schema.ArrayType{ElementType: physicalType(t.ElementType), ContainsNull: t.ContainsNull}
+	case *schema.MapType:
+		return &schema.MapType{KeyType: physicalType(t.KeyType), ValueType: physicalType(t.ValueType), ValueContainsNull: t.ValueContainsNull}
+	default:
+		return t
+	}
+}
+
+// LogicalPartitionValues returns the partition values of a file keyed by logical column
+// name. Partition values are keyed by physical name when column mapping is enabled.
+// Values of columns that are not in the schema are dropped.
+func (m *ColumnMapping) LogicalPartitionValues(values map[string]string) map[string]string {
+	if m.mode == ColumnMappingNone {
+		return values
+	}
+
+	out := make(map[string]string, len(values))
+	for k, v := range values {
+		if n, ok := m.columns.byPhysical[k]; ok {
+			out[n.logical] = v
+		}
+	}
+	return out
+}
+
+// LogicalStats renames the column keys of parsed file statistics to logical names. The
+// per-column sections minValues, maxValues and nullCount are translated, including
+// nested structs; other entries such as numRecords are copied as is.
+func (m *ColumnMapping) LogicalStats(stats map[string]interface{}) map[string]interface{} {
+	if m.mode == ColumnMappingNone {
+		return stats
+	}
+
+	out := make(map[string]interface{}, len(stats))
+	for k, v := range stats {
+		switch k {
+		case "minValues", "maxValues", "nullCount":
+			if values, ok := v.(map[string]interface{}); ok {
+				v = logicalStatsValues(m.columns, values)
+			}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func logicalStatsValues(idx *columnIndex, values map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		n, ok := idx.byPhysical[k]
+		if !ok {
+			continue
+		}
+		if nested, ok := v.(map[string]interface{}); ok && n.children != nil {
+			v = logicalStatsValues(n.children, nested)
+		}
+		out[n.logical] = v
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package delta
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+const columnMappingSchema = `{"type":"struct","fields":[` +
+	`{"name":"id","type":"long","nullable":true,"metadata":{"delta.columnMapping.id":1,"delta.columnMapping.physicalName":"col-7ad3"}},` +
+	`{"name":"Address","type":{"type":"struct","fields":[` +
+	`{"name":"city","type":"string","nullable":true,"metadata":{"delta.columnMapping.id":3,"delta.columnMapping.physicalName":"col-91b0"}}]},` +
+	`"nullable":true,"metadata":{"delta.columnMapping.id":2,"delta.columnMapping.physicalName":"col-0c55"}},` +
+	`{"name":"day","type":"date","nullable":true,"metadata":{"delta.columnMapping.id":4,"delta.columnMapping.physicalName":"col-e2f1"}}]}`
+
+func TestColumnMapping(t *testing.T) {
+	sch, err := schema.Parse(columnMappingSchema)
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+	m, err := NewColumnMapping(ColumnMappingName, sch)
+	if err != nil {
+		t.Fatalf("error creating column mapping: %s", err)
+	}
+
+	physical, err := m.PhysicalPath("address", "CITY")
+	if err != nil || !reflect.DeepEqual(physical, []string{"col-0c55", "col-91b0"}) {
+		t.Errorf("expected physical path col-0c55.col-91b0, got %v (%v)", physical, err)
+	}
+	logical, err := m.LogicalPath("col-0c55", "col-91b0")
+	if err != nil || !reflect.DeepEqual(logical, []string{"Address", "city"}) {
+		t.Errorf("expected logical path Address.city, got %v (%v)", logical, err)
+	}
+	if _, err := m.PhysicalPath("id", "x"); err == nil {
+		t.Errorf("expected error for path into non-struct column")
+	}
+	if _, err := m.LogicalPath("id"); err == nil {
+		t.Errorf("expected error for logical name used as physical name")
+	}
+
+	if names := m.PhysicalSchema().FieldNames(); !reflect.DeepEqual(names, []string{"col-7ad3", "col-0c55", "col-e2f1"}) {
+		t.Errorf("unexpected physical schema fields %v", names)
+	}
+	if sch.Fields[0].Name != "id" {
+		t.Errorf("physical schema modified the table schema")
+	}
+
+	pv := m.LogicalPartitionValues(map[string]string{"col-e2f1": "2021-01-01"})
+	if !reflect.DeepEqual(pv, map[string]string{"day": "2021-01-01"}) {
+		t.Errorf("unexpected logical partition values %v", pv)
+	}
+
+	stats := m.LogicalStats(map[string]interface{}{
+		"numRecords": 2.0,
+		"minValues":  map[string]interface{}{"col-7ad3": 1.0, "col-0c55": map[string]interface{}{"col-91b0": "Berlin"}},
+		"nullCount":  map[string]interface{}{"col-7ad3": 0.0},
+	})
+	expected := map[string]interface{}{
+		"numRecords": 2.0,
+		"minValues":  map[string]interface{}{"id": 1.0, "Address": map[string]interface{}{"city": "Berlin"}},
+		"nullCount":  map[string]interface{}{"id": 0.0},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("expected stats %v, got %v", expected, stats)
+	}
+
+	sch.Fields[2].Metadata = map[string]interface{}{}
+	if _, err := NewColumnMapping(ColumnMappingID, sch); err == nil {
+		t.Errorf("expected error for field without column mapping metadata")
+	}
+	if _, err := NewColumnMapping(ColumnMappingNone, sch); err != nil {
+		t.Errorf("unexpected error without column mapping: %s", err)
+	}
+}
@@ -0,0 +1,216 @@
+package delta
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table properties understood by TableConfig.
+const (
+	LogRetentionDurationKey         = "delta.logRetentionDuration"
+	DeletedFileRetentionDurationKey = "delta.deletedFileRetentionDuration"
+	EnableExpiredLogCleanupKey      = "delta.enableExpiredLogCleanup"
+	CheckpointIntervalKey           = "delta.checkpointInterval"
+	AppendOnlyKey                   = "delta.appendOnly"
+	DataSkippingNumIndexedColsKey   = "delta.dataSkippingNumIndexedCols"
+	EnableChangeDataFeedKey         = "delta.enableChangeDataFeed"
+	ColumnMappingModeKey            = "delta.columnMapping.mode"
+	EnableTypeWideningKey           = "delta.enableTypeWidening"
+	EnableInCommitTimestampsKey     = "delta.enableInCommitTimestamps"
+	// InCommitTimestampEnablementVersionKey is set when in-commit timestamps were enabled
+	// on an existing table. Commits before that version do not carry them.
+	InCommitTimestampEnablementVersionKey = "delta.inCommitTimestampEnablementVersion"
+)
+
+type ColumnMappingMode string
+
+const (
+	ColumnMappingNone ColumnMappingMode = "none"
+	ColumnMappingName ColumnMappingMode = "name"
+	ColumnMappingID   ColumnMappingMode = "id"
+)
+
+// TableConfig holds the typed values of the well-known delta.* table properties. Every
+// property that is not set in the table metadata has its protocol default.
+type TableConfig struct {
+	LogRetention               time.Duration
+	DeletedFileRetention       time.Duration
+	EnableExpiredLogCleanup    bool
+	CheckpointInterval         int
+	AppendOnly                 bool
+	DataSkippingNumIndexedCols int
+	EnableChangeDataFeed       bool
+	ColumnMappingMode          ColumnMappingMode
+	EnableTypeWidening         bool
+	EnableInCommitTimestamps   bool
+	// InCommitTimestampEnablementVersion is the first version with in-commit timestamps,
+	// or 0 if they were enabled when the table was created.
+	InCommitTimestampEnablementVersion int64
+}
+
+// InCommitTimestampsAt reports whether the commit at version carries the in-commit
+// timestamp that defines its commit time.
+func (c TableConfig) InCommitTimestampsAt(version int64) bool {
+	return c.EnableInCommitTimestamps && version >= c.InCommitTimestampEnablementVersion
+}
+
+// InvalidConfigError is returned when a table property has a value that cannot be parsed.
+type InvalidConfigError struct {
+	Key    string
+	Value  string
+	Reason string
+}
+
+func (e *InvalidConfigError) Error() string {
+	return fmt.Sprintf("invalid value %q for table property %s: %s", e.Value, e.Key, e.Reason)
+}
+
+// DefaultTableConfig returns the configuration of a table that sets no properties.
+func DefaultTableConfig() TableConfig {
+	return TableConfig{
+		LogRetention:               30 * 24 * time.Hour,
+		DeletedFileRetention:       7 * 24 * time.Hour,
+		EnableExpiredLogCleanup:    true,
+		CheckpointInterval:         10,
+		DataSkippingNumIndexedCols: 32,
+		ColumnMappingMode:          ColumnMappingNone,
+	}
+}
+
+// ParseTableConfig parses the well-known properties of a table's Metadata.Configuration.
+// Properties it does not know about are ignored. The first invalid value is reported as
+// an *InvalidConfigError.
+func ParseTableConfig(conf map[string]string) (TableConfig, error) {
+	c := DefaultTableConfig()
+
+	parsers := []struct {
+		key   string
+		parse func(string) error
+	}{
+		{LogRetentionDurationKey, durationParser(&c.LogRetention)},
+		{DeletedFileRetentionDurationKey, durationParser(&c.DeletedFileRetention)},
+		{EnableExpiredLogCleanupKey, boolParser(&c.EnableExpiredLogCleanup)},
+		{CheckpointIntervalKey, intParser(&c.CheckpointInterval, 1)},
+		{AppendOnlyKey, boolParser(&c.AppendOnly)},
+		{DataSkippingNumIndexedColsKey, intParser(&c.DataSkippingNumIndexedCols, -1)},
+		{EnableChangeDataFeedKey, boolParser(&c.EnableChangeDataFeed)},
+		{EnableTypeWideningKey, boolParser(&c.EnableTypeWidening)},
+		{EnableInCommitTimestampsKey, boolParser(&c.EnableInCommitTimestamps)},
+		{InCommitTimestampEnablementVersionKey, func(v string) error {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return fmt.Errorf("must be a table version")
+			}
+			c.InCommitTimestampEnablementVersion = n
+			return nil
+		}},
+		{ColumnMappingModeKey, func(v string) error {
+			switch m := ColumnMappingMode(strings.ToLower(v)); m {
+			case ColumnMappingNone, ColumnMappingName, ColumnMappingID:
+				c.ColumnMappingMode = m
+				return nil
+			default:
+				return fmt.Errorf("must be one of none, name or id")
+			}
+		}},
+	}
+
+	for _, p := range parsers {
+		v, ok := conf[p.key]
+		if !ok {
+			continue
+		}
+		if err := p.parse(strings.TrimSpace(v)); err != nil {
+			return c, &InvalidConfigError{Key: p.key, Value: v, Reason: err.Error()}
+		}
+	}
+	return c, nil
+}
+
+var intervalUnits = map[string]time.Duration{
+	"nanosecond":  time.Nanosecond,
+	"microsecond": time.Microsecond,
+	"millisecond": time.Millisecond,
+	"second":      time.Second,
+	"minute":      time.Minute,
+	"hour":        time.Hour,
+	"day":         24 * time.Hour,
+	"week":        7 * 24 * time.Hour,
+}
+
+// ParseInterval parses a calendar interval string such as "interval 7 days" or
+// "interval 1 day 12 hours". The "interval" prefix is optional. Months and years are
+// rejected because they do not have a fixed length.
+func ParseInterval(s string) (time.Duration, error) {
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) > 0 && fields[0] == "interval" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return 0, fmt.Errorf("expected pairs of value and unit in interval %q", s)
+	}
+
+	var d time.Duration
+	for i := 0; i < len(fields); i += 2 {
+		n, err := strconv.ParseInt(fields[i], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid interval value %q", fields[i])
+		}
+
+		unit, ok := intervalUnits[strings.TrimSuffix(fields[i+1], "s")]
+		if !ok {
+			return 0, fmt.Errorf("unsupported interval unit %q", fields[i+1])
+		}
+		if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+			return 0, fmt.Errorf("interval %q overflows a duration", s)
+		}
+		term := time.Duration(n) * unit
+		if term > 0 && d > math.MaxInt64-term || term < 0 && d < math.MinInt64-term {
+			return 0, fmt.Errorf("interval %q overflows a duration", s)
+		}
+		d += term
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("interval %q must not be negative", s)
+	}
+	return d, nil
+}
+
+func durationParser(d *time.Duration) func(string) error {
+	return func(v string) (err error) {
+		*d, err = ParseInterval(v)
+		return err
+	}
+}
+
+func boolParser(b *bool) func(string) error {
+	return func(v string) error {
+		switch strings.ToLower(v) {
+		case "true":
+			*b = true
+		case "false":
+			*b = false
+		default:
+			return fmt.Errorf("must be true or false")
+		}
+		return nil
+	}
+}
+
+func intParser(i *int, min int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		if n < min {
+			return fmt.Errorf("must be at least %d", min)
+		}
+		*i = n
+		return nil
+	}
+}
@@ -0,0 +1,95 @@
+package delta
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		s   string
+		d   time.Duration
+		err bool
+	}{
+		{s: "interval 7 days", d: 7 * 24 * time.Hour},
+		{s: "interval 1 week", d: 7 * 24 * time.Hour},
+		{s: "INTERVAL 30 DAYS", d: 30 * 24 * time.Hour},
+		{s: "interval 1 day 12 hours", d: 36 * time.Hour},
+		{s: "2 minutes", d: 2 * time.Minute},
+		{s: "interval 500 milliseconds", d: 500 * time.Millisecond},
+		{s: "interval 1 month", err: true},
+		{s: "interval", err: true},
+		{s: "interval seven days", err: true},
+		{s: "interval -1 day", err: true},
+		{s: "interval 30501 weeks", err: true},
+		{s: "interval 15250 weeks 15251 weeks", err: true},
+	}
+
+	for _, tt := range tests {
+		d, err := ParseInterval(tt.s)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.s)
+			}
+			continue
+		}
+		if err != nil || d != tt.d {
+			t.Errorf("%q: expected %s, got %s (%v)", tt.s, tt.d, d, err)
+		}
+	}
+}
+
+func TestParseTableConfig(t *testing.T) {
+	c, err := ParseTableConfig(map[string]string{})
+	if err != nil || c != DefaultTableConfig() {
+		t.Errorf("expected defaults, got %+v (%v)", c, err)
+	}
+
+	c, err = ParseTableConfig(map[string]string{
+		LogRetentionDurationKey:               "interval 60 days",
+		DeletedFileRetentionDurationKey:       "interval 1 day",
+		CheckpointIntervalKey:                 "5",
+		AppendOnlyKey:                         "true",
+		DataSkippingNumIndexedColsKey:         "-1",
+		EnableChangeDataFeedKey:               "TRUE",
+		ColumnMappingModeKey:                  "name",
+		EnableTypeWideningKey:                 "true",
+		EnableInCommitTimestampsKey:           "true",
+		InCommitTimestampEnablementVersionKey: "12",
+		"delta.unknown":                       "ignored",
+	})
+	if err != nil {
+		t.Fatalf("error parsing config: %s", err)
+	}
+	expected := TableConfig{
+		LogRetention:                       60 * 24 * time.Hour,
+		DeletedFileRetention:               24 * time.Hour,
+		EnableExpiredLogCleanup:            true,
+		CheckpointInterval:                 5,
+		AppendOnly:                         true,
+		DataSkippingNumIndexedCols:         -1,
+		EnableChangeDataFeed:               true,
+		ColumnMappingMode:                  ColumnMappingName,
+		EnableTypeWidening:                 true,
+		EnableInCommitTimestamps:           true,
+		InCommitTimestampEnablementVersion: 12,
+	}
+	if c != expected {
+		t.Errorf("expected %+v, got %+v", expected, c)
+	}
+
+	for key, value := range map[string]string{
+		CheckpointIntervalKey:           "0",
+		AppendOnlyKey:                   "yes",
+		ColumnMappingModeKey:            "position",
+		LogRetentionDurationKey:         "forever",
+		DeletedFileRetentionDurationKey: "interval 30501 weeks",
+	} {
+		_, err := ParseTableConfig(map[string]string{key: value})
+		var invalid *InvalidConfigError
+		if !errors.As(err, &invalid) || invalid.Key != key || invalid.Value != value {
+			t.Errorf("%s=%s: expected InvalidConfigError, got %v", key, value, err)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package delta
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/delta-golang/delta-go/delta/expr"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+const (
+	// ConstraintKeyPrefix is the prefix of the table properties that hold CHECK constraints.
+	// The rest of the key is the name of the constraint.
+	ConstraintKeyPrefix = "delta.constraints."
+	// InvariantsKey is the field metadata key of legacy column invariants.
+	InvariantsKey = "delta.invariants"
+)
+
+// ConstraintKind is the origin of a Constraint.
+type ConstraintKind string
+
+const (
+	// ConstraintCheck is a CHECK constraint defined by a delta.constraints.<name> property.
+	ConstraintCheck ConstraintKind = "CHECK"
+	// ConstraintInvariant is a legacy invariant defined in the delta.invariants field metadata.
+	ConstraintInvariant ConstraintKind = "INVARIANT"
+	// ConstraintNotNull is implied by a field that is not nullable.
+	ConstraintNotNull ConstraintKind = "NOT NULL"
+	// ConstraintGenerated requires a generated column to hold the value of its expression.
+	ConstraintGenerated ConstraintKind = "GENERATED COLUMN"
+)
+
+// Constraint is a condition every row written to the table must satisfy.
+type Constraint struct {
+	Kind ConstraintKind
+	// Name is the name of a CHECK constraint, or the column path for the other kinds.
+	Name string
+	Expr expr.Expr
+}
+
+// ConstraintViolationError is returned when a row does not satisfy a constraint.
+type ConstraintViolationError struct {
+	Constraint Constraint
+	// Row is the index of the offending row in the record.
+	Row int
+	// Values holds the values of the columns referenced by the constraint, keyed by column path.
+	Values map[string]interface{}
+}
+
+func (e *ConstraintViolationError) Error() string {
+	names := make([]string, 0, len(e.Values))
+	for k := range e.Values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	values := make([]string, len(names))
+	for i, k := range names {
+		v := e.Values[k]
+		if v == nil {
+			v = "NULL"
+		}
+		values[i] = fmt.Sprintf("%s = %v", k, v)
+	}
+	return fmt.Sprintf("%s constraint %s %s violated by row %d with values: %s",
+		e.Constraint.Kind, e.Constraint.Name, e.Constraint.Expr, e.Row, strings.Join(values, ", "))
+}
+
+// Constraints returns the constraints that data written to the table must satisfy: the
+// CHECK constraints of the table properties ordered by name, followed by the NOT NULL
+// constraints and invariants of the schema in field order and the generated columns.
+func (s *Snapshot) Constraints() ([]Constraint, error) {
+	sch, err := s.Schema()
+	if err != nil {
+		return nil, err
+	}
+	return ParseConstraints(s.state.CurrentMetadata.Configuration, sch)
+}
+
+// ParseConstraints parses the constraints defined by the table properties and the schema
+// of a table.
+func ParseConstraints(conf map[string]string, sch *schema.StructType) ([]Constraint, error) {
+	var names []string
+	for k := range conf {
+		if strings.HasPrefix(k, ConstraintKeyPrefix) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	var constraints []Constraint
+	for _, k := range names {
+		name := strings.TrimPrefix(k, ConstraintKeyPrefix)
+		e, err := expr.Parse(conf[k])
+		if err != nil {
+			return nil, fmt.Errorf("CHECK constraint %s: %w", name, err)
+		}
+		constraints = append(constraints, Constraint{Kind: ConstraintCheck, Name: name, Expr: e})
+	}
+
+	schemaConstraints, err := fieldConstraints(sch, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	constraints = append(constraints, schemaConstraints...)
+
+	generated, err := ParseGeneratedColumns(sch)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range generated {
+		constraints = append(constraints, c.constraint())
+	}
+	return constraints, nil
+}
+
+// fieldConstraints returns the constraints of the fields of s. nullableParents holds the
+// paths of the enclosing structs that may be NULL: a NOT NULL field inside a NULL struct
+// does not violate its constraint.
+func fieldConstraints(s *schema.StructType, parent []string, nullableParents [][]string) ([]Constraint, error) {
+	var constraints []Constraint
+	for _, f := range s.Fields {
+		path := append(append([]string(nil), parent...), f.Name)
+		name := expr.Col(path...).String()
+
+		if !f.Nullable {
+			var e expr.Expr = expr.IsNull{Expr: expr.Col(path...), Negated: true}
+			for i := len(nullableParents) - 1; i >= 0; i-- {
+				e = expr.Binary{Op: expr.OpOr, Left: expr.IsNull{Expr: expr.Col(nullableParents[i]...)}, Right: e}
+			}
+			constraints = append(constraints, Constraint{Kind: ConstraintNotNull, Name: name, Expr: e})
+		}
+
+		if raw, ok := f.Metadata[InvariantsKey]; ok {
+			e, err := parseInvariant(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invariant of column %s: %w", name, err)
+			}
+			constraints = append(constraints, Constraint{Kind: ConstraintInvariant, Name: name, Expr: e})
+		}
+
+		if nested, ok := f.Type.(*schema.StructType); ok {
+			np := nullableParents
+			if f.Nullable {
+				np = append(append([][]string(nil), nullableParents...), path)
+			}
+			nestedConstraints, err := fieldConstraints(nested, path, np)
+			if err != nil {
+				return nil, err
+			}
+			constraints = append(constraints, nestedConstraints...)
+		}
+	}
+	return constraints, nil
+}
+
+// parseInvariant parses the delta.invariants field metadata, a JSON string of the form
+// {"expression":{"expression":"<sql>"}}.
+func parseInvariant(raw interface{}) (expr.Expr, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a JSON string, got %T", raw)
+	}
+
+	var inv struct {
+		Expression struct {
+			Expression string `json:"expression"`
+		} `json:"expression"`
+	}
+	if err := json.Unmarshal([]byte(s), &inv); err != nil {
+		return nil, err
+	}
+	if inv.Expression.Expression == "" {
+		return nil, fmt.Errorf("missing expression in %s", s)
+	}
+	return expr.Parse(inv.Expression.Expression)
+}
+
+// CheckConstraints validates every row of rec against the constraints of the table. A
+// row violates a constraint when the constraint evaluates to false or NULL. The first
+// violation is returned as a *ConstraintViolationError.
+func (s *Snapshot) CheckConstraints(rec arrow.Record) error {
+	constraints, err := s.Constraints()
+	if err != nil {
+		return err
+	}
+	return CheckConstraints(constraints, rec)
+}
+
+// CheckConstraints validates every row of rec against constraints. See
+// Snapshot.CheckConstraints.
+func CheckConstraints(constraints []Constraint, rec arrow.Record) error {
+	for _, c := range constraints {
+		p, err := expr.Compile(c.Expr, rec.Schema())
+		if err != nil {
+			return fmt.Errorf("%s constraint %s: %w", c.Kind, c.Name, err)
+		}
+
+		for row := 0; row < int(rec.NumRows()); row++ {
+			v, err := p.Eval(rec, row)
+			if err != nil {
+				return fmt.Errorf("%s constraint %s: %w", c.Kind, c.Name, err)
+			}
+			if !expr.IsTrue(v) {
+				return &ConstraintViolationError{Constraint: c, Row: row, Values: constraintValues(c, rec, row)}
+			}
+		}
+	}
+	return nil
+}
+
+// constraintValues evaluates the columns referenced by c for the error message.
+func constraintValues(c Constraint, rec arrow.Record, row int) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, col := range expr.Columns(c.Expr) {
+		p, err := expr.Compile(col, rec.Schema())
+		if err != nil {
+			continue
+		}
+		v, err := p.Eval(rec, row)
+		if err != nil {
+			continue
+		}
+		values[col.String()] = v
+	}
+	return values
+}
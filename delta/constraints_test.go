@@ -0,0 +1,137 @@
+package delta
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+func TestCheckConstraints(t *testing.T) {
+	sch, err := schema.Parse(`{"type":"struct","fields":[` +
+		`{"name":"id","type":"long","nullable":false,"metadata":{}},` +
+		`{"name":"amount","type":"double","nullable":true,"metadata":{"delta.invariants":"{\"expression\":{\"expression\":\"amount < 1000\"}}"}},` +
+		`{"name":"address","type":{"type":"struct","fields":[{"name":"city","type":"string","nullable":false,"metadata":{}}]},"nullable":true,"metadata":{}}]}`)
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+	conf := map[string]string{
+		"delta.constraints.amount_positive": "amount >= 0",
+		"delta.appendOnly":                  "false",
+	}
+
+	constraints, err := ParseConstraints(conf, sch)
+	if err != nil {
+		t.Fatalf("error parsing constraints: %s", err)
+	}
+	var names []string
+	for _, c := range constraints {
+		names = append(names, string(c.Kind)+" "+c.Name)
+	}
+	if got := strings.Join(names, ","); got != "CHECK amount_positive,NOT NULL id,INVARIANT amount,NOT NULL address.city" {
+		t.Errorf("unexpected constraints %s", got)
+	}
+
+	arrowSchema, err := schema.ToArrow(sch)
+	if err != nil {
+		t.Fatalf("error converting schema: %s", err)
+	}
+	tests := []struct {
+		rows      string
+		violation string
+		row       int
+	}{
+		{rows: `[{"id":1,"amount":5,"address":{"city":"Oslo"}},{"id":2,"amount":0,"address":null}]`},
+		{rows: `[{"id":1,"amount":5,"address":null},{"id":2,"amount":-5,"address":null}]`, violation: "amount_positive", row: 1},
+		{rows: `[{"id":1,"amount":null,"address":null}]`, violation: "amount_positive"},
+		{rows: `[{"id":1,"amount":1000,"address":null}]`, violation: "amount"},
+		{rows: `[{"id":null,"amount":1,"address":null}]`, violation: "id"},
+		{rows: `[{"id":1,"amount":1,"address":{"city":null}}]`, violation: "address.city"},
+	}
+
+	for _, tt := range tests {
+		rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, arrowSchema, strings.NewReader(tt.rows))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = CheckConstraints(constraints, rec)
+		rec.Release()
+		if tt.violation == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %s", tt.rows, err)
+			}
+			continue
+		}
+
+		var violation *ConstraintViolationError
+		if !errors.As(err, &violation) || violation.Constraint.Name != tt.violation || violation.Row != tt.row {
+			t.Errorf("%s: expected violation of %s in row %d, got %v", tt.rows, tt.violation, tt.row, err)
+		}
+	}
+
+	conf["delta.constraints.broken"] = "amount >"
+	if _, err := ParseConstraints(conf, sch); err == nil {
+		t.Errorf("expected error for invalid constraint expression")
+	}
+}
+
+func TestPrepareRecordConstraints(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[{"name":"amount","type":"decimal(38,18)","nullable":true,"metadata":{}}]}`,
+		map[string]string{"delta.constraints.min_amount": "amount >= 0.01"})
+	ctx := context.Background()
+	sch, _ := tbl.Snapshot().Schema()
+	arrowSchema, err := schema.ToArrow(sch)
+	if err != nil {
+		t.Fatalf("error converting schema: %s", err)
+	}
+
+	// data that was not prepared is rejected
+	tx, _ := tbl.NewTransaction()
+	tx.AddFiles(AddAction{Action: Action{Path: "part-1.parquet", Size: 1, DataChange: true}})
+	var unprepared *UnpreparedDataError
+	if _, err := tx.Commit(ctx, "WRITE"); !errors.As(err, &unprepared) {
+		t.Fatalf("expected UnpreparedDataError, got %v", err)
+	}
+
+	// 0.009999999999999999999 rounds to 0.01 as a float64 but violates the constraint
+	tx, _ = tbl.NewTransaction()
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, arrowSchema, strings.NewReader(`[{"amount": "0.5"}, {"amount": "0.009999999999999999"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	var violation *ConstraintViolationError
+	if _, err := tx.PrepareRecord(rec, memory.DefaultAllocator); !errors.As(err, &violation) || violation.Row != 1 {
+		t.Fatalf("expected violation in row 1, got %v", err)
+	}
+
+	rec, _, err = array.RecordFromJSON(memory.DefaultAllocator, arrowSchema, strings.NewReader(`[{"amount": "0.5"}, {"amount": "0.01"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	prepared, err := tx.PrepareRecord(rec, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatalf("error preparing record: %s", err)
+	}
+	prepared.Release()
+
+	// the added files must hold the prepared rows
+	tx.AddFiles(AddAction{Action: Action{Path: "part-1.parquet", Size: 1, DataChange: true}, Stats: `{"numRecords":3}`})
+	if _, err := tx.Commit(ctx, "WRITE"); !errors.As(err, &unprepared) {
+		t.Fatalf("expected UnpreparedDataError for a row count mismatch, got %v", err)
+	}
+	tx.adds[0].Stats = ""
+	if _, err := tx.Commit(ctx, "WRITE"); !errors.As(err, &unprepared) {
+		t.Fatalf("expected UnpreparedDataError for a file without a row count, got %v", err)
+	}
+	tx.adds[0].Stats = `{"numRecords":2}`
+	if v, err := tx.Commit(ctx, "WRITE"); err != nil || v != 1 {
+		t.Errorf("expected commit at version 1, got %d (%v)", v, err)
+	}
+}
@@ -1,9 +1,9 @@
 package delta
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"os"
+	"time"
 )
 
 func LoadTable(uri string) (*Table, error) {
@@ -13,24 +13,48 @@ func LoadTable(uri string) (*Table, error) {
 		return nil, errors.New("could not create table")
 	}
 
-	cp, err := t.getLastCheckpoint()
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
+	err := t.Update(context.Background())
+	if err != nil {
 		return nil, err
 	}
+	return t, nil
+}
 
-	t.lastCheckPoint = cp
-	err = t.restoreCheckpoint()
+// LoadTableAtVersion loads the table state as it was committed at the given version.
+// A *VersionNotFoundError is returned when the version can no longer be reconstructed
+// from the log.
+func LoadTableAtVersion(uri string, version int64) (*Table, error) {
+
+	t := NewTable(uri)
+	if t == nil {
+		return nil, errors.New("could not create table")
+	}
+
+	err := t.LoadVersion(version)
 	if err != nil {
-		// we don't want to abandon if a checkpoint is corrupted for any reason
-		// we can still process the individual commits
-		fmt.Printf("error restoring checkpoint with version: %d", cp.Version)
+		return nil, err
+	}
+	return t, nil
+}
+
+// LoadTableAtTimestamp loads the table state of the latest version committed at or
+// before ts. A *TimestampNotFoundError is returned when ts precedes every commit that
+// is still present in the log.
+func LoadTableAtTimestamp(uri string, ts time.Time) (*Table, error) {
+
+	t := NewTable(uri)
+	if t == nil {
+		return nil, errors.New("could not create table")
 	}
 
-	state, err := t.incrementalState(t.Version + 1)
+	version, err := t.VersionAtTimestamp(ts)
 	if err != nil {
 		return nil, err
 	}
 
-	t.mergeState(state)
+	err = t.LoadVersion(version)
+	if err != nil {
+		return nil, err
+	}
 	return t, nil
 }
@@ -0,0 +1,183 @@
+package delta
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+func TestLoadTable(t *testing.T) {
+	tests := []struct {
+		uri       string
+		version   int64
+		timestamp int64
+		files     int
+	}{
+		{uri: "../tests/data/simple_table", version: 4, timestamp: 1587968626537, files: 5},
+		{uri: "../tests/data/delta-0.8.0", version: 1, files: 2},
+		{uri: "../tests/data/delta-0.8-empty", version: 1, files: 0},
+		{uri: "../tests/data/checkpoints", version: 12, files: 12},
+		{uri: "../tests/data/simple_table_with_checkpoint", version: 10, files: 11},
+		{uri: "../tests/data/delta-0.2.0", version: 3, files: 3},
+	}
+
+	for _, tt := range tests {
+		tbl, err := LoadTable(tt.uri)
+		if err != nil {
+			t.Fatalf("error loading table %s: %s", tt.uri, err)
+		}
+
+		if tbl.Version() != tt.version {
+			t.Errorf("%s: expected version %d, got %d", tt.uri, tt.version, tbl.Version())
+		}
+
+		if tt.timestamp != 0 && tbl.Snapshot().VersionTimestamp() != tt.timestamp {
+			t.Errorf("%s: expected version timestamp %d, got %d", tt.uri, tt.timestamp, tbl.Snapshot().VersionTimestamp())
+		}
+
+		if len(tbl.Snapshot().Files()) != tt.files {
+			t.Errorf("%s: expected %d files, got %d", tt.uri, tt.files, len(tbl.Snapshot().Files()))
+		}
+
+		c, err := tbl.Snapshot().Config()
+		if err != nil || c != DefaultTableConfig() {
+			t.Errorf("%s: expected default config, got %+v (%v)", tt.uri, c, err)
+		}
+		if tbl.Snapshot().state.TombstoneRetentionMillis != 7*24*60*60*1000 {
+			t.Errorf("%s: unexpected tombstone retention %d", tt.uri, tbl.Snapshot().state.TombstoneRetentionMillis)
+		}
+	}
+}
+
+func TestLoadTableNotExist(t *testing.T) {
+	_, err := LoadTable("../tests/data/does_not_exist")
+	if err == nil {
+		t.Errorf("expected error loading a missing table")
+	}
+}
+
+func TestLoadTableAtVersion(t *testing.T) {
+	tests := []struct {
+		uri     string
+		version int64
+		files   int
+	}{
+		{uri: "../tests/data/simple_table", version: 0, files: 6},
+		{uri: "../tests/data/simple_table", version: 2, files: 6},
+		{uri: "../tests/data/simple_table", version: 4, files: 5},
+		{uri: "../tests/data/simple_table_with_checkpoint", version: 5, files: 6},
+	}
+
+	for _, tt := range tests {
+		tbl, err := LoadTableAtVersion(tt.uri, tt.version)
+		if err != nil {
+			t.Fatalf("error loading table %s at version %d: %s", tt.uri, tt.version, err)
+		}
+
+		if tbl.Version() != tt.version {
+			t.Errorf("%s: expected version %d, got %d", tt.uri, tt.version, tbl.Version())
+		}
+
+		if len(tbl.Snapshot().Files()) != tt.files {
+			t.Errorf("%s@%d: expected %d files, got %d", tt.uri, tt.version, tt.files, len(tbl.Snapshot().Files()))
+		}
+	}
+}
+
+func TestLoadTableAtVersionNotFound(t *testing.T) {
+	for _, v := range []int64{-1, 5, 100} {
+		_, err := LoadTableAtVersion("../tests/data/simple_table", v)
+		var notFound *VersionNotFoundError
+		if !errors.As(err, &notFound) {
+			t.Fatalf("expected VersionNotFoundError for version %d, got %v", v, err)
+		}
+
+		if notFound.Version != v {
+			t.Errorf("expected error for version %d, got %d", v, notFound.Version)
+		}
+	}
+}
+
+func TestLoadTableAtTimestamp(t *testing.T) {
+	tests := []struct {
+		ts      int64
+		version int64
+	}{
+		{ts: 1587968586154, version: 0},
+		{ts: 1587968604143, version: 2},
+		{ts: 1587968614186, version: 2},
+		{ts: 1587968626537, version: 4},
+		{ts: 1700000000000, version: 4},
+	}
+
+	for _, tt := range tests {
+		tbl, err := LoadTableAtTimestamp("../tests/data/simple_table", time.UnixMilli(tt.ts))
+		if err != nil {
+			t.Fatalf("error loading table at %d: %s", tt.ts, err)
+		}
+
+		if tbl.Version() != tt.version {
+			t.Errorf("timestamp %d: expected version %d, got %d", tt.ts, tt.version, tbl.Version())
+		}
+	}
+
+	_, err := LoadTableAtTimestamp("../tests/data/simple_table", time.UnixMilli(1587968586153))
+	var notFound *TimestampNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("expected TimestampNotFoundError, got %v", err)
+	}
+}
+
+func TestAppTransactionVersion(t *testing.T) {
+	appID := "e4a20b59-dd0e-4c50-b074-e8ae4786df30"
+
+	// version 3 is restored from the checkpoint
+	tbl, err := LoadTable("../tests/data/delta-0.2.0")
+	if err != nil {
+		t.Fatalf("error loading table: %s", err)
+	}
+	if v, ok := tbl.Snapshot().AppTransactionVersion(appID); !ok || v != 0 {
+		t.Errorf("expected app transaction version 0 from checkpoint, got %d (%t)", v, ok)
+	}
+
+	// without the checkpoint the txn action comes from the commit
+	dir := t.TempDir()
+	copyCommits(t, "../tests/data/delta-0.2.0", dir, 0, 1, 2, 3)
+	tbl, err = LoadTable(dir)
+	if err != nil {
+		t.Fatalf("error loading table: %s", err)
+	}
+	if v, ok := tbl.Snapshot().AppTransactionVersion(appID); !ok || v != 0 {
+		t.Errorf("expected app transaction version 0 from commit, got %d (%t)", v, ok)
+	}
+
+	tbl, err = LoadTableAtVersion("../tests/data/delta-0.2.0", 2)
+	if err != nil {
+		t.Fatalf("error loading table: %s", err)
+	}
+	if _, ok := tbl.Snapshot().AppTransactionVersion(appID); ok {
+		t.Errorf("expected no app transaction before version 3")
+	}
+}
+
+func TestSnapshotSchema(t *testing.T) {
+	tbl, err := LoadTable("../tests/data/delta-0.8.0-partitioned")
+	if err != nil {
+		t.Fatalf("error loading table: %s", err)
+	}
+
+	s, err := tbl.Snapshot().Schema()
+	if err != nil {
+		t.Fatalf("error reading schema: %s", err)
+	}
+	expected := []string{"value", "year", "month", "day"}
+	if !reflect.DeepEqual(s.FieldNames(), expected) {
+		t.Errorf("expected fields %v, got %v", expected, s.FieldNames())
+	}
+	if f, _ := s.Field("year"); f.Type != schema.String || !f.Nullable {
+		t.Errorf("expected nullable string year column, got %v", f)
+	}
+}
@@ -0,0 +1,98 @@
+package delta
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// VersionNotFoundError is returned when a requested table version does not exist,
+// either because it was never committed or because its log files have been cleaned up.
+type VersionNotFoundError struct {
+	Version int64
+}
+
+func (e *VersionNotFoundError) Error() string {
+	return fmt.Sprintf("table version %d not found in log", e.Version)
+}
+
+// TimestampNotFoundError is returned when no version of the table was committed at or
+// before the requested timestamp.
+type TimestampNotFoundError struct {
+	Timestamp time.Time
+}
+
+func (e *TimestampNotFoundError) Error() string {
+	return fmt.Sprintf("no table version committed at or before %s", e.Timestamp.Format(time.RFC3339Nano))
+}
+
+// SchemaMismatchError is returned when the schema of data being written is not
+// compatible with the table schema under the requested SchemaMode.
+type SchemaMismatchError struct {
+	Reason string
+}
+
+func (e *SchemaMismatchError) Error() string {
+	return fmt.Sprintf("schema mismatch: %s", e.Reason)
+}
+
+// UnsupportedProtocolError is returned when a table requires a reader or writer protocol
+// version or features that are not implemented.
+type UnsupportedProtocolError struct {
+	ReaderVersion int32
+	// WriterVersion is set instead of ReaderVersion when writing is unsupported.
+	WriterVersion int32
+	// Features lists the unsupported features, if the version is supported.
+	Features []string
+}
+
+func (e *UnsupportedProtocolError) Error() string {
+	kind, version := "reader", e.ReaderVersion
+	if e.WriterVersion != 0 {
+		kind, version = "writer", e.WriterVersion
+	}
+	if len(e.Features) == 0 {
+		return fmt.Sprintf("table requires unsupported %s version %d", kind, version)
+	}
+	return fmt.Sprintf("table requires unsupported %s features: %s", kind, strings.Join(e.Features, ", "))
+}
+
+// AppendOnlyError is returned by Commit when a transaction removes data from a table with
+// delta.appendOnly set.
+type AppendOnlyError struct {
+	Path string
+}
+
+func (e *AppendOnlyError) Error() string {
+	return fmt.Sprintf("table is append-only, cannot remove %s", e.Path)
+}
+
+// UnpreparedDataError is returned by Commit when a transaction adds data files to a table
+// with constraints or identity columns whose rows were not prepared by
+// Transaction.PrepareRecord.
+type UnpreparedDataError struct {
+	Reason string
+}
+
+func (e *UnpreparedDataError) Error() string {
+	return fmt.Sprintf("data was not prepared for the table: %s", e.Reason)
+}
+
+// CheckpointRestoreError is returned when a checkpoint could not be restored and
+// replaying the commits in its place failed as well. It unwraps to the replay error.
+type CheckpointRestoreError struct {
+	// Version is the version of the newest checkpoint that could not be restored.
+	Version int64
+	// Err is the error restoring the checkpoint.
+	Err error
+	// ReplayErr is the error replaying the commits instead.
+	ReplayErr error
+}
+
+func (e *CheckpointRestoreError) Error() string {
+	return fmt.Sprintf("restoring checkpoint %d: %s; replaying commits instead: %s", e.Version, e.Err, e.ReplayErr)
+}
+
+func (e *CheckpointRestoreError) Unwrap() error {
+	return e.ReplayErr
+}
@@ -0,0 +1,228 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/decimal128"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+)
+
+// EvalArray evaluates the program on every row of rec and returns the results as an
+// array of type dt. Like Filter, it calls Eval once per row.
+func (p *Program) EvalArray(rec arrow.Record, dt arrow.DataType, mem memory.Allocator) (arrow.Array, error) {
+	b := array.NewBuilder(mem, dt)
+	defer b.Release()
+
+	b.Reserve(int(rec.NumRows()))
+	for row := 0; row < int(rec.NumRows()); row++ {
+		v, err := p.Eval(rec, row)
+		if err != nil {
+			return nil, err
+		}
+		if err := appendValue(b, dt, v); err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+	}
+	return b.NewArray(), nil
+}
+
+// Filter returns the rows of rec for which the program is true. Rows for which it is
+// false or NULL are dropped. The returned record must be released.
+//
+// Filter is not vectorized: it calls Eval for each row, which boxes every referenced
+// column value in an interface{} and walks the expression tree, so its cost is the number
+// of rows times the size of the expression. Files should be pruned by partition values
+// and statistics before their rows are filtered. The selected rows are copied by
+// concatenating one slice per run of consecutive rows.
+func (p *Program) Filter(rec arrow.Record, mem memory.Allocator) (arrow.Record, error) {
+	// runs of consecutive matching rows, as [start, end) pairs
+	var runs [][2]int64
+	n := int(rec.NumRows())
+	for row := 0; row < n; row++ {
+		v, err := p.Eval(rec, row)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		if !IsTrue(v) {
+			continue
+		}
+		if last := len(runs) - 1; last >= 0 && runs[last][1] == int64(row) {
+			runs[last][1]++
+		} else {
+			runs = append(runs, [2]int64{int64(row), int64(row) + 1})
+		}
+	}
+
+	if len(runs) == 1 && runs[0] == [2]int64{0, int64(n)} {
+		rec.Retain()
+		return rec, nil
+	}
+	if len(runs) == 0 {
+		return rec.NewSlice(0, 0), nil
+	}
+
+	cols := make([]arrow.Array, rec.NumCols())
+	defer func() {
+		for _, c := range cols {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+	var rows int64
+	for _, r := range runs {
+		rows += r[1] - r[0]
+	}
+	for i, col := range rec.Columns() {
+		slices := make([]arrow.Array, len(runs))
+		for j, r := range runs {
+			slices[j] = array.NewSlice(col, r[0], r[1])
+		}
+		var err error
+		cols[i], err = array.Concatenate(slices, mem)
+		for _, s := range slices {
+			s.Release()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return array.NewRecord(rec.Schema(), cols, rows), nil
+}
+
+// Repeat returns an array of type dt that holds v n times. A nil v yields an array of
+// NULLs.
+func Repeat(v interface{}, dt arrow.DataType, n int, mem memory.Allocator) (arrow.Array, error) {
+	b := array.NewBuilder(mem, dt)
+	defer b.Release()
+
+	b.Reserve(n)
+	for i := 0; i < n; i++ {
+		if err := appendValue(b, dt, v); err != nil {
+			return nil, err
+		}
+	}
+	return b.NewArray(), nil
+}
+
+// appendValue appends v to b, a builder of type dt, converting it to that type. A nil v
+// appends a NULL. Integers that do not fit the builder type are an error.
+func appendValue(b array.Builder, dt arrow.DataType, v interface{}) error {
+	if v == nil {
+		appendNull(b)
+		return nil
+	}
+
+	switch b := b.(type) {
+	case *array.Int8Builder:
+		i, err := intValue(v, math.MinInt8, math.MaxInt8)
+		b.Append(int8(i))
+		return err
+	case *array.Int16Builder:
+		i, err := intValue(v, math.MinInt16, math.MaxInt16)
+		b.Append(int16(i))
+		return err
+	case *array.Int32Builder:
+		i, err := intValue(v, math.MinInt32, math.MaxInt32)
+		b.Append(int32(i))
+		return err
+	case *array.Int64Builder:
+		i, err := intValue(v, math.MinInt64, math.MaxInt64)
+		b.Append(i)
+		return err
+	case *array.Float32Builder:
+		f, ok := toFloat(v)
+		b.Append(float32(f))
+		return typeError(ok, v, "float")
+	case *array.Float64Builder:
+		f, ok := toFloat(v)
+		b.Append(f)
+		return typeError(ok, v, "double")
+	case *array.BooleanBuilder:
+		t, ok := v.(bool)
+		b.Append(t)
+		return typeError(ok, v, "boolean")
+	case *array.StringBuilder:
+		s, ok := v.(string)
+		b.Append(s)
+		return typeError(ok, v, "string")
+	case *array.BinaryBuilder:
+		switch s := v.(type) {
+		case []byte:
+			b.Append(s)
+		case string:
+			b.AppendString(s)
+		default:
+			b.AppendNull()
+			return typeError(false, v, "binary")
+		}
+		return nil
+	case *array.Date32Builder:
+		t, ok := v.(time.Time)
+		b.Append(arrow.Date32FromTime(t.UTC()))
+		return typeError(ok, v, "date")
+	case *array.TimestampBuilder:
+		t, ok := v.(time.Time)
+		unit := dt.(*arrow.TimestampType).Unit
+		b.Append(arrow.Timestamp(t.UnixNano() / int64(unit.Multiplier())))
+		return typeError(ok, v, "timestamp")
+	case *array.Decimal128Builder:
+		n, err := decimalValue(v, dt.(*arrow.Decimal128Type).Scale)
+		b.Append(n)
+		return err
+	}
+	b.AppendNull()
+	return fmt.Errorf("unsupported builder %T", b)
+}
+
+func typeError(ok bool, v interface{}, name string) error {
+	if ok {
+		return nil
+	}
+	return fmt.Errorf("cannot store %T as %s", v, name)
+}
+
+func intValue(v interface{}, min, max int64) (int64, error) {
+	i, ok := v.(int64)
+	if !ok {
+		return 0, typeError(false, v, "integer")
+	}
+	if i < min || i > max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", i, min, max)
+	}
+	return i, nil
+}
+
+// decimalValue returns the unscaled value of v at scale, rounding half away from zero. A
+// decimal128.Num is taken to be unscaled already, and a float is taken to be the shortest
+// decimal that parses to it.
+func decimalValue(v interface{}, scale int32) (decimal128.Num, error) {
+	if n, ok := v.(decimal128.Num); ok {
+		return n, nil
+	}
+	var d Decimal
+	ok := false
+	switch v.(type) {
+	case Decimal, int64, float64:
+		d, ok = toDecimal(v)
+	}
+	if !ok {
+		return decimal128.Num{}, typeError(false, v, "decimal")
+	}
+	return decimal128.FromBigInt(d.Rescale(int(scale)).Unscaled), nil
+}
+
+// appendNull appends a NULL to b. Struct builders do not append to their fields, so a
+// NULL is appended to every field as well to keep their lengths in sync.
+func appendNull(b array.Builder) {
+	b.AppendNull()
+	if sb, ok := b.(*array.StructBuilder); ok {
+		for i := 0; i < sb.NumField(); i++ {
+			appendNull(sb.FieldBuilder(i))
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number: Unscaled * 10^-Scale. It is the value of decimal
+// columns and of number literals with a decimal point, so that decimals are compared
+// exactly rather than as the nearest float64. A Decimal is never modified.
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int
+}
+
+// NewDecimal returns the decimal unscaled * 10^-scale.
+func NewDecimal(unscaled *big.Int, scale int) Decimal {
+	return Decimal{Unscaled: unscaled, Scale: scale}
+}
+
+// ParseDecimal parses a number in plain or exponent notation, such as "-12.50" or
+// "1.25E+3", exactly. The scale is the number of fractional digits, which is never
+// negative.
+func ParseDecimal(s string) (Decimal, error) {
+	mantissa, exp := s, 0
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.Atoi(s[i+1:])
+		if err != nil {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+		}
+		mantissa, exp = s[:i], e
+	}
+
+	digits, scale := mantissa, 0
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		digits = mantissa[:i] + mantissa[i+1:]
+		scale = len(mantissa) - i - 1
+	}
+	unsigned := strings.TrimLeft(digits, "+-")
+	if unsigned == "" || len(digits)-len(unsigned) > 1 || strings.Trim(unsigned, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	n, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+
+	scale -= exp
+	if scale < 0 {
+		n.Mul(n, pow10(-scale))
+		scale = 0
+	}
+	return Decimal{Unscaled: n, Scale: scale}, nil
+}
+
+// decimalFromFloat returns the decimal with the shortest representation that parses to f,
+// so that the double 0.1 becomes the decimal 0.1. It is false for NaN and infinities.
+func decimalFromFloat(f float64) (Decimal, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, false
+	}
+	d, err := ParseDecimal(strconv.FormatFloat(f, 'f', -1, 64))
+	return d, err == nil
+}
+
+func decimalFromInt(i int64) Decimal {
+	return Decimal{Unscaled: big.NewInt(i), Scale: 0}
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+// Rat returns the value of d as a fraction.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.Unscaled, pow10(d.Scale))
+}
+
+func (d Decimal) String() string {
+	return d.Rat().FloatString(d.Scale)
+}
+
+// Float64 returns the closest float64 to d.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// Cmp compares d and o numerically, whatever their scales.
+func (d Decimal) Cmp(o Decimal) int {
+	switch {
+	case d.Scale == o.Scale:
+		return d.Unscaled.Cmp(o.Unscaled)
+	case d.Scale < o.Scale:
+		return new(big.Int).Mul(d.Unscaled, pow10(o.Scale-d.Scale)).Cmp(o.Unscaled)
+	default:
+		return d.Unscaled.Cmp(new(big.Int).Mul(o.Unscaled, pow10(d.Scale-o.Scale)))
+	}
+}
+
+// Rescale returns d with the given scale, rounding half away from zero when digits are
+// dropped.
+func (d Decimal) Rescale(scale int) Decimal {
+	if scale >= d.Scale {
+		return Decimal{Unscaled: new(big.Int).Mul(d.Unscaled, pow10(scale-d.Scale)), Scale: scale}
+	}
+	div := pow10(d.Scale - scale)
+	q, r := new(big.Int).QuoRem(d.Unscaled, div, new(big.Int))
+	if r.Abs(r).Lsh(r, 1).Cmp(div) >= 0 {
+		if d.Unscaled.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Decimal{Unscaled: q, Scale: scale}
+}
+
+// fits reports whether d has at most precision digits.
+func (d Decimal) fits(precision int) bool {
+	return new(big.Int).Abs(d.Unscaled).Cmp(pow10(precision)) < 0
+}
+
+// toDecimal converts an integer, decimal, float or numeric string to a decimal.
+func toDecimal(v interface{}) (Decimal, bool) {
+	switch n := v.(type) {
+	case Decimal:
+		return n, true
+	case int64:
+		return decimalFromInt(n), true
+	case float64:
+		return decimalFromFloat(n)
+	case string:
+		d, err := ParseDecimal(strings.TrimSpace(n))
+		return d, err == nil
+	}
+	return Decimal{}, false
+}
+
+// exactDecimal converts an integer or decimal to a decimal.
+func exactDecimal(v interface{}) (Decimal, bool) {
+	switch n := v.(type) {
+	case Decimal:
+		return n, true
+	case int64:
+		return decimalFromInt(n), true
+	}
+	return Decimal{}, false
+}
+
+// decimalArithmetic applies an exact arithmetic operator to integers and decimals. Only
+// addition, subtraction and multiplication are exact; other operators return false.
+func decimalArithmetic(op Op, l, r Decimal) (Decimal, bool) {
+	switch op {
+	case OpAdd, OpSub:
+		scale := l.Scale
+		if r.Scale > scale {
+			scale = r.Scale
+		}
+		a, b := l.Rescale(scale).Unscaled, r.Rescale(scale).Unscaled
+		if op == OpAdd {
+			return Decimal{Unscaled: new(big.Int).Add(a, b), Scale: scale}, true
+		}
+		return Decimal{Unscaled: new(big.Int).Sub(a, b), Scale: scale}, true
+	case OpMul:
+		return Decimal{Unscaled: new(big.Int).Mul(l.Unscaled, r.Unscaled), Scale: l.Scale + r.Scale}, true
+	}
+	return Decimal{}, false
+}
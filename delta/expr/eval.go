@@ -0,0 +1,652 @@
+package expr
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+)
+
+// Program is an expression bound to the schema of the records it is evaluated on.
+// A Program is safe for concurrent use.
+type Program struct {
+	expr Expr
+	eval evalFunc
+}
+
+// evalFunc evaluates an expression on one row of a record. NULL is returned as nil.
+type evalFunc func(rec arrow.Record, row int) (interface{}, error)
+
+// Compile binds the columns referenced by e to the fields of s.
+func Compile(e Expr, s *arrow.Schema) (*Program, error) {
+	eval, err := compile(e, s)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{expr: e, eval: eval}, nil
+}
+
+// Expr returns the expression the program was compiled from.
+func (p *Program) Expr() Expr {
+	return p.expr
+}
+
+// Eval evaluates the expression on a row of rec, which must have the schema the program
+// was compiled for. NULL is returned as nil; other values have the types of Literal.
+func (p *Program) Eval(rec arrow.Record, row int) (interface{}, error) {
+	return p.eval(rec, row)
+}
+
+func compile(e Expr, s *arrow.Schema) (evalFunc, error) {
+	switch n := e.(type) {
+	case Column:
+		return compileColumn(n, s)
+	case Literal:
+		v := n.Value
+		return func(arrow.Record, int) (interface{}, error) { return v, nil }, nil
+	case Binary:
+		left, err := compile(n.Left, s)
+		if err != nil {
+			return nil, err
+		}
+		right, err := compile(n.Right, s)
+		if err != nil {
+			return nil, err
+		}
+		return compileBinary(n.Op, left, right)
+	case Cast:
+		inner, err := compile(n.Expr, s)
+		if err != nil {
+			return nil, err
+		}
+		dt := n.Type
+		return func(rec arrow.Record, row int) (interface{}, error) {
+			v, err := inner(rec, row)
+			if err != nil || v == nil {
+				return nil, err
+			}
+			return CastValue(v, dt)
+		}, nil
+	case Call:
+		if err := checkArity(n); err != nil {
+			return nil, err
+		}
+		args := make([]evalFunc, len(n.Args))
+		for i, a := range n.Args {
+			f, err := compile(a, s)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = f
+		}
+		fn := functions[n.Name]
+		return func(rec arrow.Record, row int) (interface{}, error) {
+			values := make([]interface{}, len(args))
+			for i, a := range args {
+				v, err := a(rec, row)
+				if err != nil || v == nil {
+					return nil, err
+				}
+				values[i] = v
+			}
+			return fn.eval(values)
+		}, nil
+	case Not:
+		inner, err := compile(n.Expr, s)
+		if err != nil {
+			return nil, err
+		}
+		return func(rec arrow.Record, row int) (interface{}, error) {
+			v, err := inner(rec, row)
+			if err != nil || v == nil {
+				return nil, err
+			}
+			b, ok := v.(bool)
+			if !ok {
+				return nil, fmt.Errorf("NOT requires a boolean, got %T", v)
+			}
+			return !b, nil
+		}, nil
+	case Negate:
+		inner, err := compile(n.Expr, s)
+		if err != nil {
+			return nil, err
+		}
+		return func(rec arrow.Record, row int) (interface{}, error) {
+			v, err := inner(rec, row)
+			if err != nil || v == nil {
+				return nil, err
+			}
+			return arithmetic(OpSub, int64(0), v)
+		}, nil
+	case IsNull:
+		inner, err := compile(n.Expr, s)
+		if err != nil {
+			return nil, err
+		}
+		negated := n.Negated
+		return func(rec arrow.Record, row int) (interface{}, error) {
+			v, err := inner(rec, row)
+			if err != nil {
+				return nil, err
+			}
+			return (v == nil) != negated, nil
+		}, nil
+	case In:
+		return compileIn(n, s)
+	}
+	return nil, fmt.Errorf("unsupported expression %s", e)
+}
+
+func compileIn(n In, s *arrow.Schema) (evalFunc, error) {
+	value, err := compile(n.Expr, s)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]evalFunc, len(n.List))
+	for i, e := range n.List {
+		if list[i], err = compile(e, s); err != nil {
+			return nil, err
+		}
+	}
+	negated := n.Negated
+	return func(rec arrow.Record, row int) (interface{}, error) {
+		v, err := value(rec, row)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		sawNull := false
+		for _, f := range list {
+			l, err := f(rec, row)
+			if err != nil {
+				return nil, err
+			}
+			if l == nil {
+				sawNull = true
+				continue
+			}
+			c, err := Compare(v, l)
+			if err != nil {
+				return nil, err
+			}
+			if c == 0 {
+				return !negated, nil
+			}
+		}
+		if sawNull {
+			return nil, nil
+		}
+		return negated, nil
+	}, nil
+}
+
+func compileBinary(op Op, left, right evalFunc) (evalFunc, error) {
+	switch op {
+	case OpAnd, OpOr:
+		return func(rec arrow.Record, row int) (interface{}, error) {
+			l, err := left(rec, row)
+			if err != nil {
+				return nil, err
+			}
+			r, err := right(rec, row)
+			if err != nil {
+				return nil, err
+			}
+			return logical(op, l, r)
+		}, nil
+	case OpNullSafeEq:
+		return func(rec arrow.Record, row int) (interface{}, error) {
+			l, r, err := evalBoth(left, right, rec, row)
+			if err != nil {
+				return nil, err
+			}
+			if l == nil || r == nil {
+				return l == nil && r == nil, nil
+			}
+			return comparison(OpEq, l, r)
+		}, nil
+	case OpEq, OpNe, OpLt, OpLe, OpGt, OpGe:
+		return func(rec arrow.Record, row int) (interface{}, error) {
+			l, r, err := evalBoth(left, right, rec, row)
+			if err != nil || l == nil || r == nil {
+				return nil, err
+			}
+			return comparison(op, l, r)
+		}, nil
+	case OpAdd, OpSub, OpMul, OpDiv, OpMod:
+		return func(rec arrow.Record, row int) (interface{}, error) {
+			l, r, err := evalBoth(left, right, rec, row)
+			if err != nil || l == nil || r == nil {
+				return nil, err
+			}
+			return arithmetic(op, l, r)
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported operator %s", op)
+}
+
+func evalBoth(left, right evalFunc, rec arrow.Record, row int) (interface{}, interface{}, error) {
+	l, err := left(rec, row)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := right(rec, row)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, r, nil
+}
+
+// logical implements AND and OR with SQL three-valued logic.
+func logical(op Op, l, r interface{}) (interface{}, error) {
+	lb, lok := l.(bool)
+	rb, rok := r.(bool)
+	if l != nil && !lok || r != nil && !rok {
+		return nil, fmt.Errorf("%s requires booleans, got %T and %T", op, l, r)
+	}
+
+	// the deciding value: false for AND, true for OR
+	decisive := op == OpOr
+	if lok && lb == decisive || rok && rb == decisive {
+		return decisive, nil
+	}
+	if l == nil || r == nil {
+		return nil, nil
+	}
+	return !decisive, nil
+}
+
+func comparison(op Op, l, r interface{}) (interface{}, error) {
+	c, err := Compare(l, r)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case OpEq:
+		return c == 0, nil
+	case OpNe:
+		return c != 0, nil
+	case OpLt:
+		return c < 0, nil
+	case OpLe:
+		return c <= 0, nil
+	case OpGt:
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// Compare compares two non-NULL values. Integers, decimals and floats are compared
+// numerically, decimals exactly; strings are converted to numbers when compared with a
+// number and to times when compared with a time.
+func Compare(l, r interface{}) (int, error) {
+	_, lDec := l.(Decimal)
+	_, rDec := r.(Decimal)
+	if lDec || rDec {
+		return compareDecimal(l, r)
+	}
+
+	switch lv := l.(type) {
+	case int64:
+		switch rv := r.(type) {
+		case int64:
+			return compareOrdered(lv, rv), nil
+		case float64:
+			return compareOrdered(float64(lv), rv), nil
+		case string:
+			c, err := Compare(r, l)
+			return -c, err
+		}
+	case float64:
+		switch rv := r.(type) {
+		case int64:
+			return compareOrdered(lv, float64(rv)), nil
+		case float64:
+			return compareOrdered(lv, rv), nil
+		case string:
+			c, err := Compare(r, l)
+			return -c, err
+		}
+	case string:
+		switch rv := r.(type) {
+		case string:
+			return strings.Compare(lv, rv), nil
+		case int64:
+			if i, err := strconv.ParseInt(strings.TrimSpace(lv), 10, 64); err == nil {
+				return compareOrdered(i, rv), nil
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(lv), 64)
+			if err != nil {
+				return 0, fmt.Errorf("cannot compare %q with a number", lv)
+			}
+			return compareOrdered(f, float64(rv)), nil
+		case float64:
+			f, err := strconv.ParseFloat(strings.TrimSpace(lv), 64)
+			if err != nil {
+				return 0, fmt.Errorf("cannot compare %q with a number", lv)
+			}
+			return compareOrdered(f, rv), nil
+		case time.Time:
+			t, err := ParseTime(lv)
+			if err != nil {
+				return 0, err
+			}
+			return compareTimes(t, rv), nil
+		}
+	case bool:
+		if rv, ok := r.(bool); ok {
+			switch {
+			case lv == rv:
+				return 0, nil
+			case !lv:
+				return -1, nil
+			default:
+				return 1, nil
+			}
+		}
+	case []byte:
+		if rv, ok := r.([]byte); ok {
+			return bytes.Compare(lv, rv), nil
+		}
+	case time.Time:
+		switch rv := r.(type) {
+		case time.Time:
+			return compareTimes(lv, rv), nil
+		case string:
+			c, err := Compare(r, l)
+			return -c, err
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %T with %T", l, r)
+}
+
+// compareDecimal compares a decimal with a number or numeric string exactly. A float is
+// compared as the shortest decimal that parses to it, and infinities and NaN as floats.
+func compareDecimal(l, r interface{}) (int, error) {
+	for _, v := range []interface{}{l, r} {
+		if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			lf, _ := toFloat(l)
+			rf, _ := toFloat(r)
+			return compareOrdered(lf, rf), nil
+		}
+	}
+	ld, lok := toDecimal(l)
+	rd, rok := toDecimal(r)
+	if lok && rok {
+		return ld.Cmp(rd), nil
+	}
+	if s, ok := l.(string); ok && rok {
+		return 0, fmt.Errorf("cannot compare %q with a number", s)
+	}
+	if s, ok := r.(string); ok && lok {
+		return 0, fmt.Errorf("cannot compare %q with a number", s)
+	}
+	return 0, fmt.Errorf("cannot compare %T with %T", l, r)
+}
+
+type ordered interface {
+	~int64 | ~float64
+}
+
+func compareOrdered[T ordered](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// ParseTime parses a date or timestamp string as written in SQL literals and partition
+// values. Strings without a zone are in UTC.
+func ParseTime(s string) (time.Time, error) {
+	for _, layout := range []string{
+		"2006-01-02",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02T15:04:05.999999999",
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05.999999999Z07:00",
+	} {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date or timestamp %q", s)
+}
+
+// arithmetic applies an arithmetic operator to two non-NULL numbers. Integer operations
+// stay integers except for division, which always returns a float. Addition, subtraction
+// and multiplication of decimals and integers stay exact decimals; other operations on
+// decimals, and any operation on a float, return floats. Division by zero returns NULL.
+func arithmetic(op Op, l, r interface{}) (interface{}, error) {
+	li, lInt := l.(int64)
+	ri, rInt := r.(int64)
+	if lInt && rInt {
+		switch op {
+		case OpAdd:
+			return li + ri, nil
+		case OpSub:
+			return li - ri, nil
+		case OpMul:
+			return li * ri, nil
+		case OpMod:
+			if ri == 0 {
+				return nil, nil
+			}
+			return li % ri, nil
+		}
+	}
+
+	if ld, ok := exactDecimal(l); ok {
+		if rd, ok := exactDecimal(r); ok {
+			if d, ok := decimalArithmetic(op, ld, rd); ok {
+				return d, nil
+			}
+		}
+	}
+
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if !lok || !rok {
+		return nil, fmt.Errorf("%s requires numbers, got %T and %T", op, l, r)
+	}
+	switch op {
+	case OpAdd:
+		return lf + rf, nil
+	case OpSub:
+		return lf - rf, nil
+	case OpMul:
+		return lf * rf, nil
+	case OpDiv:
+		if rf == 0 {
+			return nil, nil
+		}
+		return lf / rf, nil
+	default:
+		if rf == 0 {
+			return nil, nil
+		}
+		return math.Mod(lf, rf), nil
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case Decimal:
+		return n.Float64(), true
+	default:
+		return 0, false
+	}
+}
+
+// compileColumn returns a function reading the column at the path of c. Each level of a
+// nested path is checked for NULL, since the fields of a NULL struct are undefined.
+func compileColumn(c Column, s *arrow.Schema) (evalFunc, error) {
+	if len(c.Path) == 0 {
+		return nil, fmt.Errorf("empty column reference")
+	}
+
+	fields := s.Fields()
+	var indices []int
+	var field arrow.Field
+	for i, name := range c.Path {
+		idx := -1
+		for j, f := range fields {
+			if strings.EqualFold(f.Name, name) {
+				idx = j
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, fmt.Errorf("column %s not found", Column{Path: c.Path[:i+1]})
+		}
+		indices = append(indices, idx)
+		field = fields[idx]
+
+		if i < len(c.Path)-1 {
+			st, ok := field.Type.(*arrow.StructType)
+			if !ok {
+				return nil, fmt.Errorf("column %s is not a struct", Column{Path: c.Path[:i+1]})
+			}
+			fields = st.Fields()
+		}
+	}
+
+	value, err := valueReader(field.Type)
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", c, err)
+	}
+
+	return func(rec arrow.Record, row int) (interface{}, error) {
+		arr := rec.Column(indices[0])
+		for _, idx := range indices[1:] {
+			if arr.IsNull(row) {
+				return nil, nil
+			}
+			arr = arr.(*array.Struct).Field(idx)
+		}
+		if arr.IsNull(row) {
+			return nil, nil
+		}
+		return value(arr, row), nil
+	}, nil
+}
+
+// valueReader returns a function that reads a non-NULL value of an array of type t.
+func valueReader(t arrow.DataType) (func(arrow.Array, int) interface{}, error) {
+	switch t := t.(type) {
+	case *arrow.Int8Type:
+		return func(a arrow.Array, i int) interface{} { return int64(a.(*array.Int8).Value(i)) }, nil
+	case *arrow.Int16Type:
+		return func(a arrow.Array, i int) interface{} { return int64(a.(*array.Int16).Value(i)) }, nil
+	case *arrow.Int32Type:
+		return func(a arrow.Array, i int) interface{} { return int64(a.(*array.Int32).Value(i)) }, nil
+	case *arrow.Int64Type:
+		return func(a arrow.Array, i int) interface{} { return a.(*array.Int64).Value(i) }, nil
+	case *arrow.Uint8Type:
+		return func(a arrow.Array, i int) interface{} { return int64(a.(*array.Uint8).Value(i)) }, nil
+	case *arrow.Uint16Type:
+		return func(a arrow.Array, i int) interface{} { return int64(a.(*array.Uint16).Value(i)) }, nil
+	case *arrow.Uint32Type:
+		return func(a arrow.Array, i int) interface{} { return int64(a.(*array.Uint32).Value(i)) }, nil
+	case *arrow.Float32Type:
+		return func(a arrow.Array, i int) interface{} { return float64(a.(*array.Float32).Value(i)) }, nil
+	case *arrow.Float64Type:
+		return func(a arrow.Array, i int) interface{} { return a.(*array.Float64).Value(i) }, nil
+	case *arrow.BooleanType:
+		return func(a arrow.Array, i int) interface{} { return a.(*array.Boolean).Value(i) }, nil
+	case *arrow.StringType:
+		return func(a arrow.Array, i int) interface{} { return a.(*array.String).Value(i) }, nil
+	case *arrow.BinaryType:
+		return func(a arrow.Array, i int) interface{} { return a.(*array.Binary).Value(i) }, nil
+	case *arrow.Date32Type:
+		return func(a arrow.Array, i int) interface{} { return a.(*array.Date32).Value(i).ToTime() }, nil
+	case *arrow.Date64Type:
+		return func(a arrow.Array, i int) interface{} { return a.(*array.Date64).Value(i).ToTime() }, nil
+	case *arrow.TimestampType:
+		unit := t.Unit
+		return func(a arrow.Array, i int) interface{} { return a.(*array.Timestamp).Value(i).ToTime(unit) }, nil
+	case *arrow.Decimal128Type:
+		scale := int(t.Scale)
+		return func(a arrow.Array, i int) interface{} {
+			return NewDecimal(a.(*array.Decimal128).Value(i).BigInt(), scale)
+		}, nil
+	case *arrow.StructType:
+		return structReader(t)
+	case *arrow.ListType:
+		elem, err := valueReader(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return func(a arrow.Array, i int) interface{} {
+			l := a.(*array.List)
+			values := l.ListValues()
+			offsets := l.Offsets()
+			out := make([]interface{}, 0, offsets[i+1]-offsets[i])
+			for j := int(offsets[i]); j < int(offsets[i+1]); j++ {
+				if values.IsNull(j) {
+					out = append(out, nil)
+				} else {
+					out = append(out, elem(values, j))
+				}
+			}
+			return out
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// structReader reads a struct as a map from field name to value.
+func structReader(t *arrow.StructType) (func(arrow.Array, int) interface{}, error) {
+	fields := make([]func(arrow.Array, int) interface{}, len(t.Fields()))
+	for i, f := range t.Fields() {
+		r, err := valueReader(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		fields[i] = r
+	}
+
+	return func(a arrow.Array, i int) interface{} {
+		s := a.(*array.Struct)
+		out := make(map[string]interface{}, len(fields))
+		for j, f := range t.Fields() {
+			child := s.Field(j)
+			if child.IsNull(i) {
+				out[f.Name] = nil
+			} else {
+				out[f.Name] = fields[j](child, i)
+			}
+		}
+		return out
+	}, nil
+}
+
+// IsTrue reports whether v is the boolean true. NULL and false are not true.
+func IsTrue(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
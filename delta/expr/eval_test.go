@@ -0,0 +1,214 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+var testSchema = arrow.NewSchema([]arrow.Field{
+	{Name: "id", Type: arrow.PrimitiveTypes.Int32},
+	{Name: "amount", Type: &arrow.Decimal128Type{Precision: 10, Scale: 2}, Nullable: true},
+	{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "day", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+	{Name: "address", Type: arrow.StructOf(
+		arrow.Field{Name: "city", Type: arrow.BinaryTypes.String, Nullable: true},
+	), Nullable: true},
+}, nil)
+
+func testRecord(t *testing.T, rows string) arrow.Record {
+	t.Helper()
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, testSchema, strings.NewReader(rows))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestEval(t *testing.T) {
+	rec := testRecord(t, `[
+		{"id": 1, "amount": "12.50", "name": "a", "day": "2021-01-01", "address": {"city": "Berlin"}},
+		{"id": 2, "amount": null, "name": null, "day": null, "address": null}
+	]`)
+	defer rec.Release()
+
+	tests := []struct {
+		s        string
+		expected []interface{}
+	}{
+		{"amount >= 0", []interface{}{true, nil}},
+		{"amount * 2 = 25", []interface{}{true, nil}},
+		{"id / 2", []interface{}{0.5, 1.0}},
+		{"id % 2 = 0 OR amount > 100", []interface{}{false, true}},
+		{"id = 1 OR amount > 100", []interface{}{true, nil}},
+		{"id > 1 AND amount > 0", []interface{}{false, nil}},
+		{"NOT (name = 'a')", []interface{}{false, nil}},
+		{"name IS NULL", []interface{}{false, true}},
+		{"address.city", []interface{}{"Berlin", nil}},
+		{"day = '2021-01-01'", []interface{}{true, nil}},
+		{"id / 0", []interface{}{nil, nil}},
+		{"-id", []interface{}{int64(-1), int64(-2)}},
+		{"name <=> NULL", []interface{}{false, true}},
+		{"YEAR(day) * 100 + MONTH(day)", []interface{}{int64(202101), nil}},
+		{"CAST(amount AS INT)", []interface{}{int64(12), nil}},
+		{"CAST(id AS STRING)", []interface{}{"1", "2"}},
+		{"CAST('x' AS BIGINT)", []interface{}{nil, nil}},
+		{"'2' > id", []interface{}{true, false}},
+		{"CAST(id AS STRING) IN (1.0, 3)", []interface{}{true, false}},
+		{"id IN (2, 3)", []interface{}{false, true}},
+		{"id NOT IN (2, 3)", []interface{}{true, false}},
+		{"id IN (2, NULL)", []interface{}{nil, true}},
+		{"name IN ('a', 'b')", []interface{}{true, nil}},
+		{"UPPER(address.city)", []interface{}{"BERLIN", nil}},
+		{"LOWER(name) = 'a'", []interface{}{true, nil}},
+		{"LENGTH(CONCAT(address.city, '-', id))", []interface{}{int64(8), nil}},
+		{"CONCAT('#', id)", []interface{}{"#1", "#2"}},
+		{"SUBSTRING(address.city, 2, 3)", []interface{}{"erl", nil}},
+		{"SUBSTR(address.city, -3)", []interface{}{"lin", nil}},
+		{"SUBSTRING(address.city, 0, 2)", []interface{}{"Be", nil}},
+		{"SUBSTRING(address.city, 10)", []interface{}{"", nil}},
+		{"RTRIM(LTRIM('  x  '))", []interface{}{"x", "x"}},
+	}
+
+	for _, tt := range tests {
+		p, err := Compile(MustParse(tt.s), rec.Schema())
+		if err != nil {
+			t.Errorf("%q: compile error %s", tt.s, err)
+			continue
+		}
+		for row, expected := range tt.expected {
+			v, err := p.Eval(rec, row)
+			if err != nil || v != expected {
+				t.Errorf("%q row %d: expected %v, got %v (%v)", tt.s, row, expected, v, err)
+			}
+		}
+	}
+
+	if _, err := Compile(MustParse("missing > 0"), rec.Schema()); err == nil {
+		t.Errorf("expected error for missing column")
+	}
+	if _, err := Compile(MustParse("id.x > 0"), rec.Schema()); err == nil {
+		t.Errorf("expected error for field of non-struct column")
+	}
+	p, _ := Compile(MustParse("name > 1"), rec.Schema())
+	if _, err := p.Eval(rec, 0); err == nil {
+		t.Errorf("expected error comparing string with number")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	rec := testRecord(t, `[
+		{"id": 1, "amount": "1.00", "name": "a", "day": null, "address": null},
+		{"id": 2, "amount": "2.00", "name": "b", "day": null, "address": null},
+		{"id": 3, "amount": null, "name": "c", "day": null, "address": null},
+		{"id": 4, "amount": "4.00", "name": "d", "day": null, "address": null}
+	]`)
+	defer rec.Release()
+
+	tests := []struct {
+		s     string
+		names []string
+	}{
+		{"amount > 1.5", []string{"b", "d"}},
+		{"id <> 2", []string{"a", "c", "d"}},
+		{"id > 0", []string{"a", "b", "c", "d"}},
+		{"id > 4", nil},
+	}
+	for _, tt := range tests {
+		p, err := Compile(MustParse(tt.s), rec.Schema())
+		if err != nil {
+			t.Fatal(err)
+		}
+		filtered, err := p.Filter(rec, memory.DefaultAllocator)
+		if err != nil {
+			t.Errorf("%q: %s", tt.s, err)
+			continue
+		}
+		var names []string
+		col := filtered.Column(2).(*array.String)
+		for i := 0; i < col.Len(); i++ {
+			names = append(names, col.Value(i))
+		}
+		if int(filtered.NumRows()) != len(tt.names) || strings.Join(names, ",") != strings.Join(tt.names, ",") {
+			t.Errorf("%q: expected rows %v, got %v", tt.s, tt.names, names)
+		}
+		filtered.Release()
+	}
+}
+
+func TestTypedLit(t *testing.T) {
+	l, err := TypedLit("2021-01-02 10:00:00", schema.Date)
+	if err != nil || l.Value != time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC) || l.String() != "DATE '2021-01-02'" {
+		t.Errorf("unexpected date literal %#v (%v)", l, err)
+	}
+	if l, err := TypedLit(int32(7), schema.Long); err != nil || l.Value != int64(7) || l.Type != schema.Long {
+		t.Errorf("unexpected long literal %#v (%v)", l, err)
+	}
+	if l, err := TypedLit(nil, schema.String); err != nil || l.Value != nil || l.Type != schema.String {
+		t.Errorf("unexpected NULL literal %#v (%v)", l, err)
+	}
+	if _, err := TypedLit("x", schema.Integer); err == nil {
+		t.Errorf("expected error for a string that is not an integer")
+	}
+}
+
+func TestCompareTimes(t *testing.T) {
+	day := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	if c, err := Compare("2021-01-01 12:00:00", day); err != nil || c != 1 {
+		t.Errorf("expected timestamp after date, got %d (%v)", c, err)
+	}
+	if _, err := Compare(day, "not a date"); err == nil {
+		t.Errorf("expected error for invalid time string")
+	}
+}
+
+func TestCompareDecimals(t *testing.T) {
+	s := arrow.NewSchema([]arrow.Field{
+		{Name: "amount", Type: &arrow.Decimal128Type{Precision: 38, Scale: 18}},
+	}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, s, strings.NewReader(`[
+		{"amount": "0.009999999999999999"},
+		{"amount": "0.010000000000000000"},
+		{"amount": "12345678901234567.890000000000000001"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+
+	tests := []struct {
+		s        string
+		expected []interface{}
+	}{
+		// the first value rounds to 0.01 as a float64
+		{"amount >= 0.01", []interface{}{false, true, true}},
+		{"amount = 0.01", []interface{}{false, true, false}},
+		{"amount > 12345678901234567.89", []interface{}{false, false, true}},
+		{"amount - 0.01 < 0", []interface{}{true, false, false}},
+		{"CAST(amount AS DECIMAL(4, 2)) = 0.01", []interface{}{true, true, nil}},
+	}
+	for _, tt := range tests {
+		p, err := Compile(MustParse(tt.s), rec.Schema())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for row, expected := range tt.expected {
+			v, err := p.Eval(rec, row)
+			if err != nil || v != expected {
+				t.Errorf("%q row %d: expected %v, got %v (%v)", tt.s, row, expected, v, err)
+			}
+		}
+	}
+
+	if c, err := Compare(MustParse("0.1").(Literal).Value, 0.1); err != nil || c != 0 {
+		t.Errorf("expected the decimal 0.1 to equal the double 0.1, got %d (%v)", c, err)
+	}
+	if _, err := Compare(MustParse("0.1").(Literal).Value, "x"); err == nil {
+		t.Errorf("expected error comparing string with decimal")
+	}
+}
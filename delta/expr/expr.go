@@ -0,0 +1,322 @@
+// Package expr implements the SQL expressions stored in Delta table metadata, such as
+// CHECK constraints and column invariants, and the filters of scans. Expressions are
+// evaluated on the rows of Arrow records with SQL three-valued logic, and on the
+// statistics of data files to tell whether any of their rows may match.
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+// Expr is a node of an expression tree.
+type Expr interface {
+	// String returns the expression as SQL.
+	String() string
+	expr()
+}
+
+// Column references a column by its path. Nested struct fields have paths with more than
+// one element. Names are matched case-insensitively.
+type Column struct {
+	Path []string
+}
+
+// Literal is a constant value. Value is nil, bool, int64, float64, string, []byte or
+// time.Time. Type is the Delta type of the literal if it is known, as for literals made
+// by TypedLit and DATE and TIMESTAMP literals of SQL text; Value is then in the
+// representation of that type, as returned by CastValue.
+type Literal struct {
+	Value interface{}
+	Type  schema.DataType
+}
+
+// Op is a binary operator.
+type Op string
+
+const (
+	OpEq Op = "="
+	OpNe Op = "<>"
+	// OpNullSafeEq is equality that treats two NULLs as equal and is never NULL itself.
+	OpNullSafeEq Op = "<=>"
+	OpLt         Op = "<"
+	OpLe         Op = "<="
+	OpGt         Op = ">"
+	OpGe         Op = ">="
+	OpAdd        Op = "+"
+	OpSub        Op = "-"
+	OpMul        Op = "*"
+	OpDiv        Op = "/"
+	OpMod        Op = "%"
+	OpAnd        Op = "AND"
+	OpOr         Op = "OR"
+)
+
+// Binary applies a binary operator to two expressions.
+type Binary struct {
+	Op    Op
+	Left  Expr
+	Right Expr
+}
+
+// Not negates a boolean expression.
+type Not struct {
+	Expr Expr
+}
+
+// Negate is the arithmetic negation of a numeric expression.
+type Negate struct {
+	Expr Expr
+}
+
+// IsNull tests whether an expression is NULL, or not NULL if Negated is set.
+type IsNull struct {
+	Expr    Expr
+	Negated bool
+}
+
+// In tests whether the value of an expression equals one of a list of values, or none
+// of them if Negated is set. As in SQL, the result is NULL rather than false when the
+// value is NULL or when no value matches and the list contains a NULL.
+type In struct {
+	Expr    Expr
+	List    []Expr
+	Negated bool
+}
+
+// Cast converts the value of an expression to a Delta type. Values that cannot be
+// converted become NULL.
+type Cast struct {
+	Expr Expr
+	Type schema.DataType
+}
+
+// Call calls a built-in function such as YEAR or HOUR.
+type Call struct {
+	// Name is the upper-case name of the function.
+	Name string
+	Args []Expr
+}
+
+func (Column) expr()  {}
+func (Cast) expr()    {}
+func (Call) expr()    {}
+func (Literal) expr() {}
+func (Binary) expr()  {}
+func (Not) expr()     {}
+func (Negate) expr()  {}
+func (IsNull) expr()  {}
+func (In) expr()      {}
+
+// Col returns a reference to the column with the given path.
+func Col(path ...string) Column {
+	return Column{Path: path}
+}
+
+// Lit returns a literal. Go integer and float types are converted to int64 and float64.
+func Lit(v interface{}) Literal {
+	switch n := v.(type) {
+	case int:
+		v = int64(n)
+	case int32:
+		v = int64(n)
+	case float32:
+		v = float64(n)
+	}
+	return Literal{Value: v}
+}
+
+// TypedLit returns a literal of the Delta type dt holding v, which is converted to the
+// representation of dt as by CAST. A nil v is a NULL of type dt. Values that cannot be
+// converted are an error.
+func TypedLit(v interface{}, dt schema.DataType) (Literal, error) {
+	v = Lit(v).Value
+	if v == nil {
+		return Literal{Type: dt}, nil
+	}
+	converted, err := CastValue(v, dt)
+	if err != nil {
+		return Literal{}, err
+	}
+	if converted == nil {
+		return Literal{}, fmt.Errorf("cannot convert %v to %s", v, dt)
+	}
+	return Literal{Value: converted, Type: dt}, nil
+}
+
+func (c Column) String() string {
+	parts := make([]string, len(c.Path))
+	for i, p := range c.Path {
+		parts[i] = quoteIdent(p)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (l Literal) String() string {
+	switch v := l.Value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case []byte:
+		return fmt.Sprintf("X'%X'", v)
+	case float64:
+		// an exponent keeps the literal a double rather than a decimal
+		f := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(f, "eEIN") {
+			f += "E0"
+		}
+		return f
+	case Decimal:
+		if v.Scale == 0 {
+			return v.String() + "."
+		}
+		return v.String()
+	case time.Time:
+		if l.Type == schema.Date {
+			return "DATE '" + v.UTC().Format("2006-01-02") + "'"
+		}
+		return "TIMESTAMP '" + v.UTC().Format("2006-01-02 15:04:05.999999") + "'"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func (b Binary) String() string {
+	return fmt.Sprintf("(%s %s %s)", b.Left, b.Op, b.Right)
+}
+
+func (c Cast) String() string {
+	return fmt.Sprintf("CAST(%s AS %s)", c.Expr, strings.ToUpper(c.Type.Name()))
+}
+
+func (c Call) String() string {
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = a.String()
+	}
+	return fmt.Sprintf("%s(%s)", c.Name, strings.Join(args, ", "))
+}
+
+func (n Not) String() string {
+	return fmt.Sprintf("(NOT %s)", n.Expr)
+}
+
+func (n Negate) String() string {
+	return fmt.Sprintf("(- %s)", n.Expr)
+}
+
+func (n IsNull) String() string {
+	if n.Negated {
+		return fmt.Sprintf("(%s IS NOT NULL)", n.Expr)
+	}
+	return fmt.Sprintf("(%s IS NULL)", n.Expr)
+}
+
+func (n In) String() string {
+	list := make([]string, len(n.List))
+	for i, e := range n.List {
+		list[i] = e.String()
+	}
+	op := "IN"
+	if n.Negated {
+		op = "NOT IN"
+	}
+	return fmt.Sprintf("(%s %s (%s))", n.Expr, op, strings.Join(list, ", "))
+}
+
+func quoteIdent(s string) string {
+	if isPlainIdent(s) && !isKeyword(s) {
+		return s
+	}
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
+
+func isPlainIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Columns returns the columns referenced by e.
+func Columns(e Expr) []Column {
+	var cols []Column
+	Walk(e, func(n Expr) {
+		if c, ok := n.(Column); ok {
+			cols = append(cols, c)
+		}
+	})
+	return cols
+}
+
+// Walk calls fn for e and every expression nested in it, parents before children.
+func Walk(e Expr, fn func(Expr)) {
+	fn(e)
+	switch n := e.(type) {
+	case Binary:
+		Walk(n.Left, fn)
+		Walk(n.Right, fn)
+	case Not:
+		Walk(n.Expr, fn)
+	case Negate:
+		Walk(n.Expr, fn)
+	case Cast:
+		Walk(n.Expr, fn)
+	case Call:
+		for _, a := range n.Args {
+			Walk(a, fn)
+		}
+	case IsNull:
+		Walk(n.Expr, fn)
+	case In:
+		Walk(n.Expr, fn)
+		for _, l := range n.List {
+			Walk(l, fn)
+		}
+	}
+}
+
+// Rewrite returns a copy of e in which every expression, children before parents, is
+// replaced by the result of fn.
+func Rewrite(e Expr, fn func(Expr) Expr) Expr {
+	switch n := e.(type) {
+	case Binary:
+		e = Binary{Op: n.Op, Left: Rewrite(n.Left, fn), Right: Rewrite(n.Right, fn)}
+	case Not:
+		e = Not{Expr: Rewrite(n.Expr, fn)}
+	case Negate:
+		e = Negate{Expr: Rewrite(n.Expr, fn)}
+	case IsNull:
+		e = IsNull{Expr: Rewrite(n.Expr, fn), Negated: n.Negated}
+	case Cast:
+		e = Cast{Expr: Rewrite(n.Expr, fn), Type: n.Type}
+	case Call:
+		args := make([]Expr, len(n.Args))
+		for i, a := range n.Args {
+			args[i] = Rewrite(a, fn)
+		}
+		e = Call{Name: n.Name, Args: args}
+	case In:
+		list := make([]Expr, len(n.List))
+		for i, l := range n.List {
+			list[i] = Rewrite(l, fn)
+		}
+		e = In{Expr: Rewrite(n.Expr, fn), List: list, Negated: n.Negated}
+	}
+	return fn(e)
+}
@@ -0,0 +1,292 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+type function struct {
+	minArgs, maxArgs int
+	// eval is called with non-NULL arguments; a NULL argument makes the result NULL.
+	eval func(args []interface{}) (interface{}, error)
+}
+
+var functions = map[string]function{
+	"YEAR":       {1, 1, timePart(func(t time.Time) int64 { return int64(t.Year()) })},
+	"MONTH":      {1, 1, timePart(func(t time.Time) int64 { return int64(t.Month()) })},
+	"DAY":        {1, 1, timePart(func(t time.Time) int64 { return int64(t.Day()) })},
+	"DAYOFMONTH": {1, 1, timePart(func(t time.Time) int64 { return int64(t.Day()) })},
+	"HOUR":       {1, 1, timePart(func(t time.Time) int64 { return int64(t.Hour()) })},
+	"UPPER":      {1, 1, stringFunc(strings.ToUpper)},
+	"LOWER":      {1, 1, stringFunc(strings.ToLower)},
+	"TRIM":       {1, 1, stringFunc(func(s string) string { return strings.Trim(s, " ") })},
+	"LTRIM":      {1, 1, stringFunc(func(s string) string { return strings.TrimLeft(s, " ") })},
+	"RTRIM":      {1, 1, stringFunc(func(s string) string { return strings.TrimRight(s, " ") })},
+	"LENGTH":     {1, 1, length},
+	"SUBSTRING":  {2, 3, substring},
+	"SUBSTR":     {2, 3, substring},
+	"CONCAT":     {1, -1, concat},
+}
+
+func checkArity(c Call) error {
+	fn, ok := functions[c.Name]
+	if !ok {
+		return fmt.Errorf("unknown function %s", c.Name)
+	}
+	if fn.maxArgs < 0 && len(c.Args) < fn.minArgs {
+		return fmt.Errorf("%s takes at least %d arguments, got %d", c.Name, fn.minArgs, len(c.Args))
+	}
+	if len(c.Args) < fn.minArgs || fn.maxArgs >= 0 && len(c.Args) > fn.maxArgs {
+		return fmt.Errorf("%s takes %d to %d arguments, got %d", c.Name, fn.minArgs, fn.maxArgs, len(c.Args))
+	}
+	return nil
+}
+
+// stringFunc returns a function applying f to a string. Other values are converted to
+// strings as by CAST.
+func stringFunc(f func(string) string) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		return f(castString(args[0])), nil
+	}
+}
+
+// length returns the number of characters of a string.
+func length(args []interface{}) (interface{}, error) {
+	return int64(utf8.RuneCountInString(castString(args[0]))), nil
+}
+
+// substring returns the characters of a string from a 1-based position, counted from the
+// end if negative, up to an optional length, as in Spark.
+func substring(args []interface{}) (interface{}, error) {
+	s := []rune(castString(args[0]))
+	pos, ok := castInt(args[1], schema.Long).(int64)
+	if !ok {
+		return nil, fmt.Errorf("SUBSTRING position must be an integer, got %v", args[1])
+	}
+	n := int64(len(s))
+	if len(args) == 3 {
+		if n, ok = castInt(args[2], schema.Long).(int64); !ok {
+			return nil, fmt.Errorf("SUBSTRING length must be an integer, got %v", args[2])
+		}
+	}
+
+	start := pos - 1
+	switch {
+	case pos < 0:
+		start = int64(len(s)) + pos
+	case pos == 0:
+		start = 0
+	}
+	end := int64(len(s))
+	if n < end-start {
+		end = start + n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start >= end {
+		return "", nil
+	}
+	return string(s[start:end]), nil
+}
+
+// concat concatenates strings.
+func concat(args []interface{}) (interface{}, error) {
+	var b strings.Builder
+	for _, a := range args {
+		b.WriteString(castString(a))
+	}
+	return b.String(), nil
+}
+
+// timePart returns a function extracting a part of a date or timestamp. Strings are
+// parsed as times.
+func timePart(part func(time.Time) int64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		switch v := args[0].(type) {
+		case time.Time:
+			return part(v.UTC()), nil
+		case string:
+			t, err := ParseTime(v)
+			if err != nil {
+				return nil, nil
+			}
+			return part(t), nil
+		}
+		return nil, fmt.Errorf("expected a date or timestamp, got %T", args[0])
+	}
+}
+
+// CastValue converts a non-NULL value to the representation of type dt. Values that cannot
+// be converted, such as strings that are not numbers, become NULL as in Spark.
+func CastValue(v interface{}, dt schema.DataType) (interface{}, error) {
+	switch dt {
+	case schema.String:
+		return castString(v), nil
+	case schema.Long, schema.Integer, schema.Short, schema.Byte:
+		return castInt(v, dt), nil
+	case schema.Float, schema.Double:
+		switch n := v.(type) {
+		case int64:
+			return float64(n), nil
+		case float64:
+			if dt == schema.Float {
+				return float64(float32(n)), nil
+			}
+			return n, nil
+		case Decimal:
+			if dt == schema.Float {
+				return float64(float32(n.Float64())), nil
+			}
+			return n.Float64(), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+			if err != nil {
+				return nil, nil
+			}
+			return f, nil
+		case bool:
+			if n {
+				return 1.0, nil
+			}
+			return 0.0, nil
+		}
+	case schema.Boolean:
+		switch n := v.(type) {
+		case bool:
+			return n, nil
+		case int64:
+			return n != 0, nil
+		case float64:
+			return n != 0, nil
+		case Decimal:
+			return n.Unscaled.Sign() != 0, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(n))
+			if err != nil {
+				return nil, nil
+			}
+			return b, nil
+		}
+	case schema.Binary:
+		switch n := v.(type) {
+		case []byte:
+			return n, nil
+		case string:
+			return []byte(n), nil
+		}
+	case schema.Date, schema.Timestamp, schema.TimestampNtz:
+		var t time.Time
+		switch n := v.(type) {
+		case time.Time:
+			t = n.UTC()
+		case string:
+			var err error
+			if t, err = ParseTime(strings.TrimSpace(n)); err != nil {
+				return nil, nil
+			}
+		default:
+			return nil, fmt.Errorf("cannot cast %T to %s", v, dt)
+		}
+		if dt == schema.Date {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		return t, nil
+	}
+
+	if d, ok := dt.(schema.DecimalType); ok {
+		if b, ok := v.(bool); ok {
+			v = int64(0)
+			if b {
+				v = int64(1)
+			}
+		}
+		n, ok := toDecimal(v)
+		if !ok {
+			if _, isTime := v.(time.Time); isTime {
+				return nil, fmt.Errorf("cannot cast %T to %s", v, dt)
+			}
+			return nil, nil
+		}
+		if n = n.Rescale(d.Scale); !n.fits(d.Precision) {
+			return nil, nil
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("cannot cast %T to %s", v, dt)
+}
+
+func castString(v interface{}) string {
+	switch n := v.(type) {
+	case string:
+		return n
+	case []byte:
+		return string(n)
+	case float64:
+		return strconv.FormatFloat(n, 'g', -1, 64)
+	case Decimal:
+		return n.String()
+	case time.Time:
+		n = n.UTC()
+		if n.Hour() == 0 && n.Minute() == 0 && n.Second() == 0 && n.Nanosecond() == 0 {
+			return n.Format("2006-01-02")
+		}
+		return n.Format("2006-01-02 15:04:05.999999")
+	default:
+		return fmt.Sprint(n)
+	}
+}
+
+var intRanges = map[schema.DataType][2]int64{
+	schema.Long:    {math.MinInt64, math.MaxInt64},
+	schema.Integer: {math.MinInt32, math.MaxInt32},
+	schema.Short:   {math.MinInt16, math.MaxInt16},
+	schema.Byte:    {math.MinInt8, math.MaxInt8},
+}
+
+func castInt(v interface{}, dt schema.DataType) interface{} {
+	var i int64
+	switch n := v.(type) {
+	case int64:
+		i = n
+	case float64:
+		if math.IsNaN(n) || n < math.MinInt64 || n >= math.MaxInt64 {
+			return nil
+		}
+		i = int64(n)
+	case Decimal:
+		q := new(big.Int).Quo(n.Unscaled, pow10(n.Scale))
+		if !q.IsInt64() {
+			return nil
+		}
+		i = q.Int64()
+	case bool:
+		if n {
+			i = 1
+		}
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil {
+			return nil
+		}
+		return castInt(f, dt)
+	case time.Time:
+		// seconds since the epoch, as in Spark
+		i = n.Unix()
+	default:
+		return nil
+	}
+
+	r := intRanges[dt]
+	if i < r[0] || i > r[1] {
+		return nil
+	}
+	return i
+}
@@ -0,0 +1,569 @@
+package expr
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+// SyntaxError is returned when an expression cannot be parsed.
+type SyntaxError struct {
+	Expr   string
+	Pos    int
+	Reason string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at position %d in %q: %s", e.Pos, e.Expr, e.Reason)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokQuotedIdent
+	tokNumber
+	tokString
+	tokSymbol
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var keywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IS": true, "IN": true, "NULL": true, "TRUE": true, "FALSE": true,
+}
+
+func isKeyword(s string) bool {
+	return keywords[strings.ToUpper(s)]
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(s) {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(s) && (s[i] == '_' || unicode.IsLetter(rune(s[i])) || unicode.IsDigit(rune(s[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, s[start:i], start})
+		case unicode.IsDigit(c) || c == '.' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1])):
+			start := i
+			for i < len(s) && (unicode.IsDigit(rune(s[i])) || s[i] == '.') {
+				i++
+			}
+			if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+				i++
+				if i < len(s) && (s[i] == '+' || s[i] == '-') {
+					i++
+				}
+				for i < len(s) && unicode.IsDigit(rune(s[i])) {
+					i++
+				}
+			}
+			tokens = append(tokens, token{tokNumber, s[start:i], start})
+		case c == '\'' || c == '`' || c == '"':
+			start := i
+			text, n, ok := lexQuoted(s[i:], byte(c))
+			if !ok {
+				return nil, &SyntaxError{Expr: s, Pos: start, Reason: "unterminated quote"}
+			}
+			i += n
+			kind := tokString
+			if c == '`' {
+				kind = tokQuotedIdent
+			}
+			tokens = append(tokens, token{kind, text, start})
+		default:
+			start := i
+			sym := s[i : i+1]
+			if i+1 < len(s) {
+				switch two := s[i : i+2]; two {
+				case "<=", ">=", "<>", "!=", "==":
+					sym = two
+				}
+				if i+2 < len(s) && s[i:i+3] == "<=>" {
+					sym = "<=>"
+				}
+			}
+			if !strings.Contains("=<>!+-*/%(),.", sym[:1]) || sym == "!" {
+				return nil, &SyntaxError{Expr: s, Pos: start, Reason: fmt.Sprintf("unexpected character %q", sym)}
+			}
+			i += len(sym)
+			tokens = append(tokens, token{tokSymbol, sym, start})
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(s)}), nil
+}
+
+// lexQuoted reads a string quoted with q, where a doubled quote is an escaped quote. It
+// returns the unquoted text and the number of bytes consumed.
+func lexQuoted(s string, q byte) (string, int, bool) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != q {
+			if s[i] == '\\' && q != '`' && i+1 < len(s) {
+				i++
+			}
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == q {
+			b.WriteByte(q)
+			i++
+			continue
+		}
+		return b.String(), i + 1, true
+	}
+	return "", 0, false
+}
+
+type parser struct {
+	src    string
+	tokens []token
+	pos    int
+}
+
+// Parse parses a SQL expression such as `amount >= 0 AND currency IS NOT NULL`.
+func Parse(s string) (Expr, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{src: s, tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return e, nil
+}
+
+// MustParse is like Parse but panics if the expression cannot be parsed.
+func MustParse(s string) Expr {
+	e, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return &SyntaxError{Expr: p.src, Pos: t.pos, Reason: fmt.Sprintf(format, args...)}
+}
+
+// acceptKeyword consumes the next token if it is the given keyword.
+func (p *parser) acceptKeyword(kw string) bool {
+	t := p.peek()
+	if t.kind == tokIdent && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) acceptSymbol(syms ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokSymbol {
+		return "", false
+	}
+	for _, s := range syms {
+		if t.text == s {
+			p.pos++
+			return s, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expectSymbol(sym string) error {
+	if _, ok := p.acceptSymbol(sym); !ok {
+		t := p.peek()
+		return p.errorf(t, "expected %q, got %q", sym, t.text)
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Binary{Op: OpOr, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.acceptKeyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = Binary{Op: OpAnd, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.acceptKeyword("NOT") {
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: e}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.acceptKeyword("IS") {
+		negated := p.acceptKeyword("NOT")
+		if !p.acceptKeyword("NULL") {
+			return nil, p.errorf(p.peek(), "expected NULL")
+		}
+		return IsNull{Expr: left, Negated: negated}, nil
+	}
+
+	if p.acceptKeyword("NOT") {
+		if !p.acceptKeyword("IN") {
+			return nil, p.errorf(p.peek(), "expected IN")
+		}
+		return p.parseIn(left, true)
+	}
+	if p.acceptKeyword("IN") {
+		return p.parseIn(left, false)
+	}
+
+	if sym, ok := p.acceptSymbol("=", "==", "<>", "!=", "<", "<=", ">", ">=", "<=>"); ok {
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		op := Op(sym)
+		switch sym {
+		case "==":
+			op = OpEq
+		case "!=":
+			op = OpNe
+		}
+		return Binary{Op: op, Left: left, Right: right}, nil
+	}
+	return left, nil
+}
+
+// parseIn parses the parenthesized list of an IN expression.
+func (p *parser) parseIn(left Expr, negated bool) (Expr, error) {
+	if err := p.expectSymbol("("); err != nil {
+		return nil, err
+	}
+	in := In{Expr: left, Negated: negated}
+	for {
+		e, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		in.List = append(in.List, e)
+		if _, ok := p.acceptSymbol(","); !ok {
+			break
+		}
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+func (p *parser) parseAdditive() (Expr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		sym, ok := p.acceptSymbol("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = Binary{Op: Op(sym), Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseMultiplicative() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		sym, ok := p.acceptSymbol("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = Binary{Op: Op(sym), Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if sym, ok := p.acceptSymbol("-", "+"); ok {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if sym == "+" {
+			return e, nil
+		}
+		if l, ok := e.(Literal); ok {
+			switch v := l.Value.(type) {
+			case int64:
+				return Literal{Value: -v}, nil
+			case float64:
+				return Literal{Value: -v}, nil
+			case Decimal:
+				return Literal{Value: NewDecimal(new(big.Int).Neg(v.Unscaled), v.Scale)}, nil
+			}
+		}
+		return Negate{Expr: e}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber:
+		return parseNumber(p, t)
+	case tokString:
+		return Literal{Value: t.text}, nil
+	case tokSymbol:
+		if t.text == "(" {
+			e, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+			return e, nil
+		}
+	case tokIdent:
+		switch strings.ToUpper(t.text) {
+		case "NULL":
+			return Literal{Value: nil}, nil
+		case "TRUE":
+			return Literal{Value: true}, nil
+		case "FALSE":
+			return Literal{Value: false}, nil
+		}
+		if isKeyword(t.text) {
+			return nil, p.errorf(t, "unexpected keyword %s", strings.ToUpper(t.text))
+		}
+		if next := p.peek(); next.kind == tokString {
+			switch strings.ToUpper(t.text) {
+			case "DATE", "TIMESTAMP":
+				p.next()
+				v, err := ParseTime(next.text)
+				if err != nil {
+					return nil, p.errorf(next, "%s", err)
+				}
+				return TypedLit(v, sqlTypes[strings.ToUpper(t.text)])
+			}
+		}
+		if _, ok := p.acceptSymbol("("); ok {
+			return p.parseCall(t)
+		}
+		return p.parseColumn(t.text)
+	case tokQuotedIdent:
+		return p.parseColumn(t.text)
+	case tokEOF:
+		return nil, p.errorf(t, "unexpected end of expression")
+	}
+	return nil, p.errorf(t, "unexpected %q", t.text)
+}
+
+func (p *parser) parseColumn(first string) (Expr, error) {
+	path := []string{first}
+	for {
+		if _, ok := p.acceptSymbol("."); !ok {
+			return Column{Path: path}, nil
+		}
+		t := p.next()
+		if t.kind != tokIdent && t.kind != tokQuotedIdent {
+			return nil, p.errorf(t, "expected field name after '.'")
+		}
+		path = append(path, t.text)
+	}
+}
+
+func parseNumber(p *parser, t token) (Expr, error) {
+	if !strings.ContainsAny(t.text, "eE") {
+		// as in Spark, numbers with a decimal point and integers too large for a long are
+		// exact decimals
+		if v, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return Literal{Value: v}, nil
+		}
+		d, err := ParseDecimal(t.text)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %s", t.text)
+		}
+		return Literal{Value: d}, nil
+	}
+	v, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return nil, p.errorf(t, "invalid number %s", t.text)
+	}
+	return Literal{Value: v}, nil
+}
+
+func (p *parser) parseCall(name token) (Expr, error) {
+	fn := strings.ToUpper(name.text)
+	if fn == "CAST" {
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptKeyword("AS") {
+			return nil, p.errorf(p.peek(), "expected AS")
+		}
+		dt, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return Cast{Expr: e, Type: dt}, nil
+	}
+
+	if _, ok := functions[fn]; !ok {
+		return nil, p.errorf(name, "unknown function %s", fn)
+	}
+
+	var args []Expr
+	if _, ok := p.acceptSymbol(")"); !ok {
+		for {
+			a, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			if _, ok := p.acceptSymbol(","); !ok {
+				break
+			}
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	call := Call{Name: fn, Args: args}
+	if err := checkArity(call); err != nil {
+		return nil, p.errorf(name, "%s", err)
+	}
+	return call, nil
+}
+
+// sqlTypes maps the SQL names of types to Delta types.
+var sqlTypes = map[string]schema.DataType{
+	"STRING":        schema.String,
+	"VARCHAR":       schema.String,
+	"BIGINT":        schema.Long,
+	"LONG":          schema.Long,
+	"INT":           schema.Integer,
+	"INTEGER":       schema.Integer,
+	"SMALLINT":      schema.Short,
+	"SHORT":         schema.Short,
+	"TINYINT":       schema.Byte,
+	"BYTE":          schema.Byte,
+	"FLOAT":         schema.Float,
+	"REAL":          schema.Float,
+	"DOUBLE":        schema.Double,
+	"BOOLEAN":       schema.Boolean,
+	"BINARY":        schema.Binary,
+	"DATE":          schema.Date,
+	"TIMESTAMP":     schema.Timestamp,
+	"TIMESTAMP_NTZ": schema.TimestampNtz,
+}
+
+func (p *parser) parseType() (schema.DataType, error) {
+	t := p.next()
+	if t.kind != tokIdent {
+		return nil, p.errorf(t, "expected type name")
+	}
+
+	name := strings.ToUpper(t.text)
+	if name == "DECIMAL" || name == "NUMERIC" {
+		d := schema.DecimalType{Precision: 10}
+		if _, ok := p.acceptSymbol("("); ok {
+			precision, scale := p.next(), token{text: "0"}
+			if _, ok := p.acceptSymbol(","); ok {
+				scale = p.next()
+			}
+			if err := p.expectSymbol(")"); err != nil {
+				return nil, err
+			}
+
+			var err1, err2 error
+			d.Precision, err1 = strconv.Atoi(precision.text)
+			d.Scale, err2 = strconv.Atoi(scale.text)
+			if err1 != nil || err2 != nil || d.Precision < 1 || d.Precision > schema.MaxDecimalPrecision || d.Scale > d.Precision {
+				return nil, p.errorf(precision, "invalid decimal type")
+			}
+		}
+		return d, nil
+	}
+
+	dt, ok := sqlTypes[name]
+	if !ok {
+		return nil, p.errorf(t, "unsupported type %s", t.text)
+	}
+	return dt, nil
+}
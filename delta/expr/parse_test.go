@@ -0,0 +1,76 @@
+package expr
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected Expr
+	}{
+		{"amount >= 0", Binary{Op: OpGe, Left: Col("amount"), Right: Lit(0)}},
+		{"a = 1 OR b <> 'x' AND NOT c", Binary{Op: OpOr,
+			Left:  Binary{Op: OpEq, Left: Col("a"), Right: Lit(1)},
+			Right: Binary{Op: OpAnd, Left: Binary{Op: OpNe, Left: Col("b"), Right: Lit("x")}, Right: Not{Expr: Col("c")}},
+		}},
+		{"(a + b) * -2.5 != c % 3", Binary{Op: OpNe,
+			Left:  Binary{Op: OpMul, Left: Binary{Op: OpAdd, Left: Col("a"), Right: Col("b")}, Right: Lit(NewDecimal(big.NewInt(-25), 1))},
+			Right: Binary{Op: OpMod, Left: Col("c"), Right: Lit(3)},
+		}},
+		{"address.city IS NOT NULL", IsNull{Expr: Col("address", "city"), Negated: true}},
+		{"`weird col`.`x``y` is null", IsNull{Expr: Col("weird col", "x`y")}},
+		{"flag = TRUE and other == null", Binary{Op: OpAnd,
+			Left:  Binary{Op: OpEq, Left: Col("flag"), Right: Lit(true)},
+			Right: Binary{Op: OpEq, Left: Col("other"), Right: Lit(nil)},
+		}},
+		{"name = 'it''s'", Binary{Op: OpEq, Left: Col("name"), Right: Lit("it's")}},
+		{"CAST(ts AS date) <=> DATE '2021-01-01'", Binary{Op: OpNullSafeEq,
+			Left:  Cast{Expr: Col("ts"), Type: schema.Date},
+			Right: Literal{Value: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Type: schema.Date},
+		}},
+		{"year(ts) = 2021 AND CAST(x AS DECIMAL(10, 2)) > 1", Binary{Op: OpAnd,
+			Left:  Binary{Op: OpEq, Left: Call{Name: "YEAR", Args: []Expr{Col("ts")}}, Right: Lit(2021)},
+			Right: Binary{Op: OpGt, Left: Cast{Expr: Col("x"), Type: schema.DecimalType{Precision: 10, Scale: 2}}, Right: Lit(1)},
+		}},
+		{"year = 2021 AND month IN (4, 12)", Binary{Op: OpAnd,
+			Left:  Binary{Op: OpEq, Left: Col("year"), Right: Lit(2021)},
+			Right: In{Expr: Col("month"), List: []Expr{Lit(4), Lit(12)}},
+		}},
+		{"name not in ('a', NULL)", In{Expr: Col("name"), List: []Expr{Lit("a"), Lit(nil)}, Negated: true}},
+		{"-x < 1e3", Binary{Op: OpLt, Left: Negate{Expr: Col("x")}, Right: Lit(1000.0)}},
+	}
+
+	for _, tt := range tests {
+		e, err := Parse(tt.s)
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", tt.s, err)
+			continue
+		}
+		if !reflect.DeepEqual(e, tt.expected) {
+			t.Errorf("%q: expected %s, got %s", tt.s, tt.expected, e)
+		}
+
+		// the SQL of an expression parses back to the same expression
+		again, err := Parse(e.String())
+		if err != nil || !reflect.DeepEqual(again, e) {
+			t.Errorf("%q: %s does not round trip, got %v (%v)", tt.s, e, again, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{"", "a >", "(a = 1", "a = 1)", "a IS 1", "a ! b", "'open", "a.", "AND", "CAST(a)", "CAST(a AS interval)", "nope(a)", "YEAR(a, b)", "a IN 1", "a NOT 1", "a IN (1,", "SUBSTRING(a)", "CONCAT()"} {
+		_, err := Parse(s)
+		var syntax *SyntaxError
+		if !errors.As(err, &syntax) {
+			t.Errorf("%q: expected SyntaxError, got %v", s, err)
+		}
+	}
+}
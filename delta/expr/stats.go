@@ -0,0 +1,277 @@
+package expr
+
+import (
+	"math"
+
+	"github.com/apache/arrow/go/v8/arrow"
+)
+
+// Stats are statistics of the values of a set of rows, such as the rows of a data file.
+// Bounds may be looser than the actual values but never tighter.
+type Stats interface {
+	// NumRecords returns the number of rows. ok is false if it is unknown.
+	NumRecords() (n int64, ok bool)
+	// Min returns a lower bound of the non-NULL values of a column. ok is false if it is
+	// unknown.
+	Min(c Column) (v interface{}, ok bool)
+	// Max returns an upper bound of the non-NULL values of a column. ok is false if it
+	// is unknown.
+	Max(c Column) (v interface{}, ok bool)
+	// NullCount returns the number of NULL values of a column. ok is false if it is
+	// unknown.
+	NullCount(c Column) (n int64, ok bool)
+}
+
+// outcomes is the set of values a boolean expression may take over a set of rows.
+type outcomes uint8
+
+const (
+	mayBeTrue outcomes = 1 << iota
+	mayBeFalse
+	mayBeNull
+
+	anyOutcome = mayBeTrue | mayBeFalse | mayBeNull
+)
+
+// MayMatch reports whether e may be true for some of the rows described by s. It is false
+// only when the statistics prove that e is false or NULL for every row, so a data file
+// for which it is false can be skipped by a scan filtered by e. Unknown statistics and
+// expressions the statistics say nothing about, such as arithmetic on columns, may
+// match.
+func MayMatch(e Expr, s Stats) bool {
+	if n, ok := s.NumRecords(); ok && n == 0 {
+		return false
+	}
+	return statsOutcomes(e, s)&mayBeTrue != 0
+}
+
+func statsOutcomes(e Expr, s Stats) outcomes {
+	if len(Columns(e)) == 0 {
+		return constantOutcome(e)
+	}
+
+	switch n := e.(type) {
+	case Binary:
+		switch n.Op {
+		case OpAnd, OpOr:
+			return combine(n.Op, statsOutcomes(n.Left, s), statsOutcomes(n.Right, s))
+		case OpEq, OpNe, OpLt, OpLe, OpGt, OpGe, OpNullSafeEq:
+			if c, ok := n.Left.(Column); ok {
+				if l, ok := n.Right.(Literal); ok {
+					return compareOutcomes(n.Op, c, l.Value, s)
+				}
+			}
+			if l, ok := n.Left.(Literal); ok {
+				if c, ok := n.Right.(Column); ok {
+					return compareOutcomes(flip(n.Op), c, l.Value, s)
+				}
+			}
+		}
+	case Not:
+		return negate(statsOutcomes(n.Expr, s))
+	case IsNull:
+		c, ok := n.Expr.(Column)
+		if !ok {
+			break
+		}
+		o := nullOutcomes(c, s)
+		if n.Negated {
+			o = negate(o)
+		}
+		return o
+	case In:
+		c, ok := n.Expr.(Column)
+		if !ok {
+			break
+		}
+		// x IN (a, b) is x = a OR x = b, and the negation is NOT of that
+		o := mayBeFalse
+		for _, item := range n.List {
+			l, ok := item.(Literal)
+			if !ok {
+				return anyOutcome
+			}
+			o = combine(OpOr, o, compareOutcomes(OpEq, c, l.Value, s))
+		}
+		if n.Negated {
+			o = negate(o)
+		}
+		return o
+	case Column:
+		// a boolean column used as a predicate
+		return compareOutcomes(OpEq, n, true, s)
+	}
+	return anyOutcome
+}
+
+// constantOutcome evaluates an expression without columns.
+func constantOutcome(e Expr) outcomes {
+	p, err := Compile(e, arrow.NewSchema(nil, nil))
+	if err != nil {
+		return anyOutcome
+	}
+	v, err := p.Eval(nil, 0)
+	if err != nil {
+		return anyOutcome
+	}
+	switch v {
+	case nil:
+		return mayBeNull
+	case true:
+		return mayBeTrue
+	case false:
+		return mayBeFalse
+	}
+	return anyOutcome
+}
+
+// negate swaps true and false in o.
+func negate(o outcomes) outcomes {
+	return o&mayBeNull | (o&mayBeTrue)<<1 | (o&mayBeFalse)>>1
+}
+
+// nullOutcomes returns the outcomes of testing whether column c is NULL.
+func nullOutcomes(c Column, s Stats) outcomes {
+	var o outcomes
+	nulls, known := s.NullCount(c)
+	if !known || nulls > 0 {
+		o |= mayBeTrue
+	}
+	if rows, ok := s.NumRecords(); !known || !ok || nulls < rows {
+		o |= mayBeFalse
+	}
+	return o
+}
+
+// combine returns the outcomes of AND or OR of operands with outcomes l and r.
+func combine(op Op, l, r outcomes) outcomes {
+	var o outcomes
+	for _, lv := range []interface{}{true, false, nil} {
+		if l&outcomeOf(lv) == 0 {
+			continue
+		}
+		for _, rv := range []interface{}{true, false, nil} {
+			if r&outcomeOf(rv) == 0 {
+				continue
+			}
+			v, _ := logical(op, lv, rv)
+			o |= outcomeOf(v)
+		}
+	}
+	return o
+}
+
+func outcomeOf(v interface{}) outcomes {
+	switch v {
+	case true:
+		return mayBeTrue
+	case false:
+		return mayBeFalse
+	}
+	return mayBeNull
+}
+
+// flip returns the operator op with its operands swapped.
+func flip(op Op) Op {
+	switch op {
+	case OpLt:
+		return OpGt
+	case OpLe:
+		return OpGe
+	case OpGt:
+		return OpLt
+	case OpGe:
+		return OpLe
+	}
+	return op
+}
+
+// compareOutcomes returns the outcomes of comparing column c with the literal v.
+func compareOutcomes(op Op, c Column, v interface{}, s Stats) outcomes {
+	nulls, nullsKnown := s.NullCount(c)
+	rows, rowsKnown := s.NumRecords()
+	allNull := nullsKnown && rowsKnown && nulls == rows
+
+	if v == nil {
+		if op == OpNullSafeEq {
+			return nullOutcomes(c, s)
+		}
+		return mayBeNull
+	}
+
+	var o outcomes
+	if !nullsKnown || nulls > 0 {
+		if op == OpNullSafeEq {
+			o |= mayBeFalse
+		} else {
+			o |= mayBeNull
+		}
+	}
+	if allNull {
+		return o
+	}
+
+	min, minOK := s.Min(c)
+	max, maxOK := s.Max(c)
+	if minOK && !comparableBound(min, v) || maxOK && !comparableBound(max, v) {
+		return anyOutcome
+	}
+	// cmpMin and cmpMax compare the bounds with v; unknown bounds are -2 for the minimum
+	// and 2 for the maximum, below and above every value
+	cmpMin, cmpMax := -2, 2
+	if minOK {
+		var err error
+		if cmpMin, err = Compare(min, v); err != nil {
+			return anyOutcome
+		}
+	}
+	if maxOK {
+		var err error
+		if cmpMax, err = Compare(max, v); err != nil {
+			return anyOutcome
+		}
+	}
+
+	// t and f report whether some value may compare true and false
+	var t, f bool
+	switch op {
+	case OpEq, OpNullSafeEq:
+		t = cmpMin <= 0 && cmpMax >= 0
+		f = !(cmpMin == 0 && cmpMax == 0)
+	case OpNe:
+		t = !(cmpMin == 0 && cmpMax == 0)
+		f = cmpMin <= 0 && cmpMax >= 0
+	case OpLt:
+		t, f = cmpMin < 0, cmpMax >= 0
+	case OpLe:
+		t, f = cmpMin <= 0, cmpMax > 0
+	case OpGt:
+		t, f = cmpMax > 0, cmpMin <= 0
+	case OpGe:
+		t, f = cmpMax >= 0, cmpMin < 0
+	}
+	if t {
+		o |= mayBeTrue
+	}
+	if f {
+		o |= mayBeFalse
+	}
+	return o
+}
+
+// comparableBound reports whether the order of a bound of a column and v is the order of
+// the column values and v. Strings compared with numbers are converted to numbers, which
+// do not sort like the strings, and NaN is not ordered.
+func comparableBound(bound, v interface{}) bool {
+	if _, ok := bound.(string); ok {
+		_, ok := v.(string)
+		return ok
+	}
+	if f, ok := bound.(float64); ok && math.IsNaN(f) {
+		return false
+	}
+	if f, ok := v.(float64); ok && math.IsNaN(f) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,97 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// testStats are statistics keyed by column path.
+type testStats struct {
+	rows     int64
+	min, max map[string]interface{}
+	nulls    map[string]int64
+}
+
+func (s testStats) NumRecords() (int64, bool) { return s.rows, s.rows >= 0 }
+
+func (s testStats) Min(c Column) (interface{}, bool) {
+	v, ok := s.min[strings.Join(c.Path, ".")]
+	return v, ok
+}
+
+func (s testStats) Max(c Column) (interface{}, bool) {
+	v, ok := s.max[strings.Join(c.Path, ".")]
+	return v, ok
+}
+
+func (s testStats) NullCount(c Column) (int64, bool) {
+	n, ok := s.nulls[strings.Join(c.Path, ".")]
+	return n, ok
+}
+
+func TestMayMatch(t *testing.T) {
+	stats := testStats{
+		rows: 10,
+		min:  map[string]interface{}{"id": int64(10), "name": "b", "day": time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), "a.b": 1.5, "const": int64(7)},
+		max:  map[string]interface{}{"id": int64(20), "name": "d", "day": time.Date(2021, 1, 31, 0, 0, 0, 0, time.UTC), "a.b": 2.5, "const": int64(7)},
+		nulls: map[string]int64{
+			"id": 0, "name": 2, "empty": 10, "const": 0,
+		},
+	}
+
+	tests := []struct {
+		s        string
+		expected bool
+	}{
+		{"id = 15", true},
+		{"id = 25", false},
+		{"id < 10", false},
+		{"id <= 10", true},
+		{"10 >= id", true},
+		{"5 > id", false},
+		{"id > 20", false},
+		{"id >= 20", true},
+		{"id <> 15", true},
+		{"const <> 7", false},
+		{"NOT (const = 7)", false},
+		{"NOT (id = 15)", true},
+		{"id = 25 OR name = 'c'", true},
+		{"id = 15 AND name = 'z'", false},
+		{"id IN (1, 2, 30)", false},
+		{"id IN (1, 12)", true},
+		{"const NOT IN (7, 8)", false},
+		{"id NOT IN (1, NULL)", false},
+		{"id IS NULL", false},
+		{"name IS NULL", true},
+		{"empty IS NOT NULL", false},
+		{"empty = 1", false},
+		{"empty <=> NULL", true},
+		{"id <=> NULL", false},
+		{"id = NULL", false},
+		{"unknown = 1", true},
+		{"day > '2021-02-01'", false},
+		{"day = DATE '2021-01-15'", true},
+		{"a.b > 3", false},
+		{"name = 5", true},
+		{"id + 1 = 100", true},
+		{"1 = 2", false},
+		{"1 = 1 AND id = 25", false},
+	}
+	for _, tt := range tests {
+		e, err := Parse(tt.s)
+		if err != nil {
+			t.Fatalf("%q: %s", tt.s, err)
+		}
+		if got := MayMatch(e, stats); got != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.s, tt.expected, got)
+		}
+	}
+
+	if MayMatch(MustParse("id = 15"), testStats{rows: 0}) {
+		t.Errorf("expected no match without rows")
+	}
+	if !MayMatch(MustParse("id = 15"), testStats{rows: -1}) {
+		t.Errorf("expected a match with unknown statistics")
+	}
+}
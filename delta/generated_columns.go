@@ -0,0 +1,324 @@
+package delta
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/delta-golang/delta-go/delta/expr"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+// GenerationExpressionKey is the field metadata key that holds the SQL expression a
+// generated column is computed from.
+const GenerationExpressionKey = "delta.generationExpression"
+
+// GeneratedColumn is a top-level column whose values are computed from other columns.
+type GeneratedColumn struct {
+	Name string
+	Type schema.DataType
+	Expr expr.Expr
+}
+
+// GeneratedColumns returns the generated columns of the table in schema order.
+func (s *Snapshot) GeneratedColumns() ([]GeneratedColumn, error) {
+	sch, err := s.Schema()
+	if err != nil {
+		return nil, err
+	}
+	return ParseGeneratedColumns(sch)
+}
+
+// ParseGeneratedColumns returns the generated columns of a table schema.
+func ParseGeneratedColumns(sch *schema.StructType) ([]GeneratedColumn, error) {
+	var columns []GeneratedColumn
+	for _, f := range sch.Fields {
+		c, ok, err := parseGeneratedColumn(f)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			columns = append(columns, c)
+		}
+	}
+	return columns, nil
+}
+
+// parseGeneratedColumn returns the generated column f, if f has a generation expression.
+func parseGeneratedColumn(f schema.StructField) (GeneratedColumn, bool, error) {
+	raw, ok := f.Metadata[GenerationExpressionKey]
+	if !ok {
+		return GeneratedColumn{}, false, nil
+	}
+
+	s, ok := raw.(string)
+	if !ok {
+		return GeneratedColumn{}, false, fmt.Errorf("generation expression of column %s: expected a string, got %T", f.Name, raw)
+	}
+	e, err := expr.Parse(s)
+	if err != nil {
+		return GeneratedColumn{}, false, fmt.Errorf("generation expression of column %s: %w", f.Name, err)
+	}
+	return GeneratedColumn{Name: f.Name, Type: f.Type, Expr: e}, true, nil
+}
+
+// constraint returns the constraint a written row must satisfy: the column holds the
+// value of its expression, where NULL equals NULL.
+func (c GeneratedColumn) constraint() Constraint {
+	return Constraint{
+		Kind: ConstraintGenerated,
+		Name: c.Name,
+		Expr: expr.Binary{Op: expr.OpNullSafeEq, Left: expr.Col(c.Name), Right: expr.Cast{Expr: c.Expr, Type: c.Type}},
+	}
+}
+
+// FillGeneratedColumns computes the generated columns of the table schema sch that are
+// missing from rec and returns a record with the columns in schema order. Generated
+// columns present in rec are kept; they are verified by the constraints returned by
+// Snapshot.Constraints. Columns of rec that are not in sch follow the others. The
+// returned record must be released by the caller.
+func FillGeneratedColumns(sch *schema.StructType, rec arrow.Record, mem memory.Allocator) (arrow.Record, error) {
+	columns, err := ParseGeneratedColumns(sch)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := rec.Schema().Fields()
+	cols := rec.Columns()
+
+	for _, c := range columns {
+		if fieldIndexFold(fields, c.Name) >= 0 {
+			continue
+		}
+
+		dt, err := schema.ToArrowType(c.Type)
+		if err != nil {
+			return nil, fmt.Errorf("generated column %s: %w", c.Name, err)
+		}
+		p, err := expr.Compile(expr.Cast{Expr: c.Expr, Type: c.Type}, rec.Schema())
+		if err != nil {
+			return nil, fmt.Errorf("generated column %s: %w", c.Name, err)
+		}
+		arr, err := p.EvalArray(rec, dt, mem)
+		if err != nil {
+			return nil, fmt.Errorf("generated column %s: %w", c.Name, err)
+		}
+		defer arr.Release()
+
+		fields = append(fields, arrow.Field{Name: c.Name, Type: dt, Nullable: true})
+		cols = append(cols, arr)
+	}
+
+	return orderColumns(sch, rec.Schema().Metadata(), fields, cols, rec.NumRows()), nil
+}
+
+// orderColumns returns a record of the given columns in the order of the fields of sch,
+// followed by the columns that are not in sch.
+func orderColumns(sch *schema.StructType, md arrow.Metadata, fields []arrow.Field, cols []arrow.Array, rows int64) arrow.Record {
+	orderedFields := make([]arrow.Field, 0, len(fields))
+	orderedCols := make([]arrow.Array, 0, len(cols))
+	used := make([]bool, len(fields))
+	for _, f := range sch.Fields {
+		if i := fieldIndexFold(fields, f.Name); i >= 0 && !used[i] {
+			orderedFields = append(orderedFields, fields[i])
+			orderedCols = append(orderedCols, cols[i])
+			used[i] = true
+		}
+	}
+	for i := range fields {
+		if !used[i] {
+			orderedFields = append(orderedFields, fields[i])
+			orderedCols = append(orderedCols, cols[i])
+		}
+	}
+	return array.NewRecord(arrow.NewSchema(orderedFields, &md), orderedCols, rows)
+}
+
+// GeneratedPartitionFilters derives filters on generated partition columns from a filter
+// on their source columns, so that files can be pruned by partition values. For a
+// partition column generated as CAST(ts AS DATE), the filter ts > '2021-01-01 10:00:00'
+// yields date >= DATE '2021-01-01'.
+//
+// Only top-level conjuncts of filter comparing a column with a literal are used. The
+// generation expression must be the column itself, a CAST of it to a date or timestamp,
+// or YEAR of it; other expressions are not monotonic and yield no filter. The source
+// column must be a date, timestamp or number, whose order the expression preserves,
+// unless the generated column is a copy of it. Spark converts between timestamps and
+// dates in the session time zone, which the table does not record, so such conversions
+// yield no filter either. Generation expressions that cannot be
+// parsed yield no filter either. The derived filters may match more files than the
+// original filter, never fewer.
+func GeneratedPartitionFilters(filter expr.Expr, sch *schema.StructType, partitionColumns []string) []expr.Expr {
+	var generated []GeneratedColumn
+	for _, f := range sch.Fields {
+		for _, p := range partitionColumns {
+			if !strings.EqualFold(f.Name, p) {
+				continue
+			}
+			// the scan does not evaluate the expression, so an unsupported one only
+			// costs pruning
+			if c, ok, err := parseGeneratedColumn(f); err == nil && ok {
+				generated = append(generated, c)
+			}
+		}
+	}
+
+	var filters []expr.Expr
+	for _, conjunct := range splitConjuncts(filter) {
+		for _, c := range generated {
+			source, strict, ok := monotonicSource(c, sch)
+			if !ok {
+				continue
+			}
+			if f, ok := derivePartitionFilter(conjunct, c, source, strict); ok {
+				filters = append(filters, f)
+			}
+		}
+	}
+	return filters
+}
+
+func splitConjuncts(e expr.Expr) []expr.Expr {
+	if b, ok := e.(expr.Binary); ok && b.Op == expr.OpAnd {
+		return append(splitConjuncts(b.Left), splitConjuncts(b.Right)...)
+	}
+	return []expr.Expr{e}
+}
+
+// monotonicSource returns the column of sch the generation expression of c is a
+// non-decreasing function of. strict is set when the expression is the column itself.
+func monotonicSource(c GeneratedColumn, sch *schema.StructType) (source expr.Column, strict bool, ok bool) {
+	// target is the type the source column is cast to, nil for YEAR
+	var target schema.DataType
+	switch n := c.Expr.(type) {
+	case expr.Column:
+		source, strict, ok = n, true, true
+		target = c.Type
+	case expr.Cast:
+		switch n.Type {
+		case schema.Date, schema.Timestamp, schema.TimestampNtz:
+			source, ok = n.Expr.(expr.Column)
+			target = n.Type
+		}
+	case expr.Call:
+		if n.Name == "YEAR" && len(n.Args) == 1 {
+			source, ok = n.Args[0].(expr.Column)
+		}
+	}
+	if !ok {
+		return expr.Column{}, false, false
+	}
+
+	// a string such as '2021-1-2' does not sort like the date it casts to
+	dt, found := columnType(sch, source)
+	if !found || strict && dt == c.Type {
+		return source, strict, found
+	}
+	switch dt {
+	case schema.Timestamp:
+		// the date of a timestamp depends on the session time zone
+		return source, strict, target == schema.Timestamp
+	case schema.Date, schema.TimestampNtz:
+		return source, strict, target != schema.Timestamp
+	case schema.Byte, schema.Short, schema.Integer, schema.Long, schema.Float, schema.Double:
+		return source, strict, true
+	}
+	_, isDecimal := dt.(schema.DecimalType)
+	return source, strict, isDecimal
+}
+
+// columnType returns the type of the possibly nested column c of sch.
+func columnType(sch *schema.StructType, c expr.Column) (schema.DataType, bool) {
+	var dt schema.DataType = sch
+	for _, name := range c.Path {
+		s, ok := dt.(*schema.StructType)
+		if !ok {
+			return nil, false
+		}
+		f, ok := s.Field(name)
+		if !ok {
+			return nil, false
+		}
+		dt = f.Type
+	}
+	return dt, true
+}
+
+var flippedOps = map[expr.Op]expr.Op{
+	expr.OpEq: expr.OpEq, expr.OpLt: expr.OpGt, expr.OpLe: expr.OpGe, expr.OpGt: expr.OpLt, expr.OpGe: expr.OpLe,
+}
+
+// relaxedOps maps comparisons on a source column to comparisons on a non-decreasing,
+// non-injective function of it: ts < x only implies f(ts) <= f(x).
+var relaxedOps = map[expr.Op]expr.Op{
+	expr.OpEq: expr.OpEq, expr.OpLt: expr.OpLe, expr.OpLe: expr.OpLe, expr.OpGt: expr.OpGe, expr.OpGe: expr.OpGe,
+}
+
+func derivePartitionFilter(conjunct expr.Expr, c GeneratedColumn, source expr.Column, strict bool) (expr.Expr, bool) {
+	partition := expr.Col(c.Name)
+
+	if n, ok := conjunct.(expr.IsNull); ok {
+		if col, ok := n.Expr.(expr.Column); ok && sameColumn(col, source) && !n.Negated {
+			return expr.IsNull{Expr: partition}, true
+		}
+		return nil, false
+	}
+
+	b, ok := conjunct.(expr.Binary)
+	if !ok {
+		return nil, false
+	}
+	col, isCol := b.Left.(expr.Column)
+	lit, isLit := b.Right.(expr.Literal)
+	op := b.Op
+	if !isCol || !isLit {
+		col, isCol = b.Right.(expr.Column)
+		lit, isLit = b.Left.(expr.Literal)
+		op = flippedOps[op]
+	}
+	if !isCol || !isLit || lit.Value == nil || !sameColumn(col, source) {
+		return nil, false
+	}
+
+	if !strict {
+		op = relaxedOps[op]
+	}
+	if op == "" {
+		return nil, false
+	}
+
+	// evaluate the generation expression with the literal in place of the source column
+	value := expr.Rewrite(expr.Cast{Expr: c.Expr, Type: c.Type}, func(e expr.Expr) expr.Expr {
+		if col, ok := e.(expr.Column); ok && sameColumn(col, source) {
+			return lit
+		}
+		return e
+	})
+	p, err := expr.Compile(value, arrow.NewSchema(nil, nil))
+	if err != nil {
+		return nil, false
+	}
+	v, err := p.Eval(nil, 0)
+	if err != nil || v == nil {
+		return nil, false
+	}
+	typed, err := expr.TypedLit(v, c.Type)
+	if err != nil {
+		return nil, false
+	}
+	return expr.Binary{Op: op, Left: partition, Right: typed}, true
+}
+
+func sameColumn(a, b expr.Column) bool {
+	if len(a.Path) != len(b.Path) {
+		return false
+	}
+	for i := range a.Path {
+		if !strings.EqualFold(a.Path[i], b.Path[i]) {
+			return false
+		}
+	}
+	return true
+}
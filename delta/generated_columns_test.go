@@ -0,0 +1,201 @@
+package delta
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/delta-golang/delta-go/delta/expr"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+const generatedColumnsSchema = `{"type":"struct","fields":[` +
+	`{"name":"ts","type":"timestamp","nullable":true,"metadata":{}},` +
+	`{"name":"value","type":"long","nullable":true,"metadata":{}},` +
+	`{"name":"date","type":"date","nullable":true,"metadata":{"delta.generationExpression":"CAST(ts AS DATE)"}},` +
+	`{"name":"year","type":"integer","nullable":true,"metadata":{"delta.generationExpression":"YEAR(ts)"}},` +
+	`{"name":"double_value","type":"long","nullable":true,"metadata":{"delta.generationExpression":"value * 2"}}]}`
+
+func TestFillGeneratedColumns(t *testing.T) {
+	sch, err := schema.Parse(generatedColumnsSchema)
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+	columns, err := ParseGeneratedColumns(sch)
+	if err != nil {
+		t.Fatalf("error parsing generated columns: %s", err)
+	}
+	if len(columns) != 3 || columns[0].Expr.String() != "CAST(ts AS DATE)" {
+		t.Fatalf("unexpected generated columns %v", columns)
+	}
+
+	input := arrow.NewSchema([]arrow.Field{
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}, Nullable: true},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "double_value", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, input, strings.NewReader(
+		`[{"ts":"2021-03-04T23:59:00Z","value":1,"double_value":2},{"ts":null,"value":null,"double_value":null}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+
+	filled, err := FillGeneratedColumns(sch, rec, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatalf("error filling generated columns: %s", err)
+	}
+	defer filled.Release()
+
+	// the columns are in schema order
+	var names []string
+	for _, f := range filled.Schema().Fields() {
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, ","); got != "ts,value,date,year,double_value" {
+		t.Fatalf("unexpected columns %s", got)
+	}
+	dates := filled.Column(2).(*array.Date32)
+	if dates.Value(0).ToTime() != time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC) || !dates.IsNull(1) {
+		t.Errorf("unexpected dates %s", dates)
+	}
+	if years := filled.Column(3).(*array.Int32); years.Value(0) != 2021 || !years.IsNull(1) {
+		t.Errorf("unexpected years %s", years)
+	}
+
+	constraints, err := ParseConstraints(nil, sch)
+	if err != nil {
+		t.Fatalf("error parsing constraints: %s", err)
+	}
+	if err := CheckConstraints(constraints, filled); err != nil {
+		t.Errorf("unexpected constraint violation %s", err)
+	}
+
+	// a supplied value that does not match the expression is rejected
+	wrong, _, err := array.RecordFromJSON(memory.DefaultAllocator, input, strings.NewReader(
+		`[{"ts":"2021-03-04T23:59:00Z","value":1,"double_value":3}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wrong.Release()
+	filled, err = FillGeneratedColumns(sch, wrong, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatalf("error filling generated columns: %s", err)
+	}
+	defer filled.Release()
+
+	var violation *ConstraintViolationError
+	err = CheckConstraints(constraints, filled)
+	if !errors.As(err, &violation) || violation.Constraint.Kind != ConstraintGenerated || violation.Constraint.Name != "double_value" {
+		t.Errorf("expected violation of generated column double_value, got %v", err)
+	}
+}
+
+func TestGeneratedPartitionFilters(t *testing.T) {
+	sch, err := schema.Parse(strings.Replace(generatedColumnsSchema, `"type":"timestamp"`, `"type":"timestamp_ntz"`, 1))
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+
+	filter := expr.MustParse("ts > '2021-03-04 10:00:00' AND '2022-01-01' >= ts AND value = 3 AND ts IS NULL")
+	filters := GeneratedPartitionFilters(filter, sch, []string{"date", "year", "double_value"})
+	var got []string
+	for _, f := range filters {
+		got = append(got, f.String())
+	}
+
+	expected := []string{
+		"(date >= DATE '2021-03-04')",
+		"(year >= 2021)",
+		"(date <= DATE '2022-01-01')",
+		"(year <= 2022)",
+		"(date IS NULL)",
+		"(year IS NULL)",
+	}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected filters %v, got %v", expected, got)
+	}
+
+	// a timestamp written from a session in UTC+2 at 2021-03-04 23:00:00 UTC was
+	// partitioned into the date 2021-03-05, so no date or year can be derived from it
+	sch, err = schema.Parse(generatedColumnsSchema)
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+	filter = expr.MustParse("ts = '2021-03-04 23:00:00'")
+	if filters := GeneratedPartitionFilters(filter, sch, []string{"date", "year"}); len(filters) != 0 {
+		t.Errorf("expected no filters for a timestamp source column, got %v", filters)
+	}
+	pruner := newPartitionPruner(filter, sch, []string{"date", "year"})
+	if ok, err := pruner.matches(map[string]string{"date": "2021-03-05", "year": "2021"}); err != nil || !ok {
+		t.Errorf("expected partition date=2021-03-05 to match, got %v (%v)", ok, err)
+	}
+
+	// a string does not sort like the date it casts to: '2021-1-2' < '2021-01-10'
+	sch, err = schema.Parse(`{"type":"struct","fields":[` +
+		`{"name":"s","type":"string","nullable":true,"metadata":{}},` +
+		`{"name":"date","type":"date","nullable":true,"metadata":{"delta.generationExpression":"CAST(s AS DATE)"}}]}`)
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+	filters = GeneratedPartitionFilters(expr.MustParse("s > '2021-01-10'"), sch, []string{"date"})
+	if len(filters) != 0 {
+		t.Errorf("expected no filters for a string source column, got %v", filters)
+	}
+
+	// a generation expression the expr package cannot parse yields no filter, and the
+	// filter on the partition column itself is still used for pruning
+	sch, err = schema.Parse(`{"type":"struct","fields":[` +
+		`{"name":"ts","type":"timestamp_ntz","nullable":true,"metadata":{}},` +
+		`{"name":"m","type":"string","nullable":true,"metadata":{"delta.generationExpression":"date_format(ts, 'yyyy-MM')"}}]}`)
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+	filter = expr.MustParse("m = '2021-01' AND ts > '2021-01-10'")
+	if filters := GeneratedPartitionFilters(filter, sch, []string{"m"}); len(filters) != 0 {
+		t.Errorf("expected no filters for an unsupported generation expression, got %v", filters)
+	}
+	pruner = newPartitionPruner(filter, sch, []string{"m"})
+	if ok, err := pruner.matches(map[string]string{"m": "2021-02"}); err != nil || ok {
+		t.Errorf("expected partition m=2021-02 to be pruned, got %v (%v)", ok, err)
+	}
+}
+
+func TestPrepareRecordGeneratedColumns(t *testing.T) {
+	tbl := createTestTable(t, generatedColumnsSchema, nil)
+	input := arrow.NewSchema([]arrow.Field{
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}, Nullable: true},
+	}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, input, strings.NewReader(`[{"ts":"2021-03-04T23:59:00Z","value":1}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+
+	tx, _ := tbl.NewTransaction()
+	prepared, err := tx.PrepareRecord(rec, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatalf("error preparing record: %s", err)
+	}
+	defer prepared.Release()
+	if prepared.NumCols() != 5 || prepared.Schema().Field(0).Name != "ts" || prepared.Column(4).(*array.Int64).Value(0) != 2 {
+		t.Errorf("unexpected prepared record %v", prepared)
+	}
+
+	// a supplied generated value must match its expression
+	input = arrow.NewSchema(append(input.Fields(), arrow.Field{Name: "double_value", Type: arrow.PrimitiveTypes.Int64, Nullable: true}), nil)
+	wrong, _, err := array.RecordFromJSON(memory.DefaultAllocator, input, strings.NewReader(`[{"value":1,"ts":null,"double_value":3}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wrong.Release()
+	var violation *ConstraintViolationError
+	if _, err := tx.PrepareRecord(wrong, memory.DefaultAllocator); !errors.As(err, &violation) || violation.Constraint.Kind != ConstraintGenerated {
+		t.Errorf("expected violation of a generated column, got %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package delta
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+// Field metadata keys of identity columns.
+const (
+	IdentityStartKey               = "delta.identity.start"
+	IdentityStepKey                = "delta.identity.step"
+	IdentityHighWaterMarkKey       = "delta.identity.highWaterMark"
+	IdentityAllowExplicitInsertKey = "delta.identity.allowExplicitInsert"
+)
+
+// IdentityColumn is a long column whose values are assigned by the writer: start,
+// start+step, start+2*step and so on, continuing after the high-water mark, the last
+// value assigned by a previous commit.
+type IdentityColumn struct {
+	Name                string
+	Start               int64
+	Step                int64
+	HighWaterMark       int64
+	HasHighWaterMark    bool
+	AllowExplicitInsert bool
+}
+
+// IdentityColumns returns the identity columns of the table in schema order.
+func (s *Snapshot) IdentityColumns() ([]IdentityColumn, error) {
+	sch, err := s.Schema()
+	if err != nil {
+		return nil, err
+	}
+	return ParseIdentityColumns(sch)
+}
+
+// ParseIdentityColumns returns the identity columns of a table schema.
+func ParseIdentityColumns(sch *schema.StructType) ([]IdentityColumn, error) {
+	var columns []IdentityColumn
+	for _, f := range sch.Fields {
+		if _, ok := f.Metadata[IdentityStartKey]; !ok {
+			continue
+		}
+
+		c := IdentityColumn{Name: f.Name}
+		var ok bool
+		if f.Type != schema.Long {
+			return nil, fmt.Errorf("identity column %s must be a long, not %s", f.Name, f.Type)
+		}
+		if c.Start, ok = f.MetadataInt(IdentityStartKey); !ok {
+			return nil, fmt.Errorf("identity column %s: invalid %s", f.Name, IdentityStartKey)
+		}
+		if c.Step, ok = f.MetadataInt(IdentityStepKey); !ok || c.Step == 0 {
+			return nil, fmt.Errorf("identity column %s: invalid %s", f.Name, IdentityStepKey)
+		}
+		if _, set := f.Metadata[IdentityHighWaterMarkKey]; set {
+			if c.HighWaterMark, ok = f.MetadataInt(IdentityHighWaterMarkKey); !ok {
+				return nil, fmt.Errorf("identity column %s: invalid %s", f.Name, IdentityHighWaterMarkKey)
+			}
+			c.HasHighWaterMark = true
+		}
+		c.AllowExplicitInsert, _ = f.Metadata[IdentityAllowExplicitInsertKey].(bool)
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
+
+// next returns the first value to assign after the high-water mark, or false if it
+// overflows.
+func (c IdentityColumn) next() (int64, bool) {
+	if !c.HasHighWaterMark {
+		return c.Start, true
+	}
+	return stepValue(c.HighWaterMark, c.Step, 1)
+}
+
+// AssignIdentityValues returns rec with values for the identity columns it omits. The
+// values continue after the high-water marks of the metadata the transaction will
+// commit, and the transaction is updated to commit metadata with the new high-water
+// marks. Identity columns present in rec are kept if the column allows explicit inserts
+// and move the high-water mark past the inserted values. The returned record must be
+// released by the caller.
+//
+// Two transactions assigning values from the same snapshot both change the metadata, so
+// the one that commits second fails with a *ConcurrentModificationError instead of
+// committing duplicate values.
+func (tx *Transaction) AssignIdentityValues(rec arrow.Record, mem memory.Allocator) (arrow.Record, error) {
+	md := tx.Metadata()
+	sch, err := schema.Parse(md.SchemaString)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := ParseIdentityColumns(sch)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		rec.Retain()
+		return rec, nil
+	}
+
+	fields := rec.Schema().Fields()
+	cols := rec.Columns()
+	marks := make(map[string]int64, len(columns))
+	n := rec.NumRows()
+
+	for _, c := range columns {
+		if i := fieldIndexFold(fields, c.Name); i >= 0 {
+			if !c.AllowExplicitInsert {
+				return nil, fmt.Errorf("identity column %s does not allow explicit values", c.Name)
+			}
+			if mark, ok := explicitHighWaterMark(c, cols[i]); ok {
+				marks[c.Name] = mark
+			}
+			continue
+		}
+		if n == 0 {
+			continue
+		}
+
+		first, ok := c.next()
+		last, ok2 := stepValue(first, c.Step, n-1)
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("identity column %s overflows", c.Name)
+		}
+
+		b := array.NewInt64Builder(mem)
+		b.Reserve(int(n))
+		for v, i := first, int64(0); i < n; v, i = v+c.Step, i+1 {
+			b.Append(v)
+		}
+		arr := b.NewArray()
+		b.Release()
+		defer arr.Release()
+
+		fields = append(fields, arrow.Field{Name: c.Name, Type: arrow.PrimitiveTypes.Int64})
+		cols = append(cols, arr)
+		marks[c.Name] = last
+	}
+
+	if len(marks) > 0 {
+		for i, f := range sch.Fields {
+			mark, ok := marks[f.Name]
+			if !ok {
+				continue
+			}
+			fmd := make(map[string]interface{}, len(f.Metadata)+1)
+			for k, v := range f.Metadata {
+				fmd[k] = v
+			}
+			fmd[IdentityHighWaterMarkKey] = json.Number(strconv.FormatInt(mark, 10))
+			sch.Fields[i].Metadata = fmd
+		}
+
+		md.SchemaString, err = schema.Serialize(sch)
+		if err != nil {
+			return nil, err
+		}
+		tx.UpdateMetadata(md)
+	}
+
+	arrowMD := rec.Schema().Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &arrowMD), cols, n), nil
+}
+
+// stepValue returns first + k*step, or false on overflow.
+func stepValue(first, step, k int64) (int64, bool) {
+	d := k * step
+	if k != 0 && (d/k != step || k == -1 && step == math.MinInt64) {
+		return 0, false
+	}
+	v := first + d
+	if d > 0 && v < first || d < 0 && v > first {
+		return 0, false
+	}
+	return v, true
+}
+
+// explicitHighWaterMark returns the high-water mark after explicit values were inserted:
+// the largest value for a positive step, the smallest for a negative one.
+func explicitHighWaterMark(c IdentityColumn, col arrow.Array) (int64, bool) {
+	values, ok := col.(*array.Int64)
+	if !ok {
+		return 0, false
+	}
+
+	mark, found := c.HighWaterMark, c.HasHighWaterMark
+	for i := 0; i < values.Len(); i++ {
+		if values.IsNull(i) {
+			continue
+		}
+		v := values.Value(i)
+		if !found || c.Step > 0 && v > mark || c.Step < 0 && v < mark {
+			mark, found = v, true
+		}
+	}
+	return mark, found && (mark != c.HighWaterMark || !c.HasHighWaterMark)
+}
+
+func fieldIndexFold(fields []arrow.Field, name string) int {
+	for i, f := range fields {
+		if strings.EqualFold(f.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,131 @@
+package delta
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+)
+
+func TestAssignIdentityValues(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[`+
+		`{"name":"id","type":"long","nullable":false,"metadata":{"delta.identity.start":10,"delta.identity.step":5,"delta.identity.allowExplicitInsert":false}},`+
+		`{"name":"name","type":"string","nullable":true,"metadata":{}}]}`, nil)
+	ctx := context.Background()
+
+	input := arrow.NewSchema([]arrow.Field{{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true}}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, input, strings.NewReader(`[{"name":"a"},{"name":"b"},{"name":"c"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+
+	assign := func(tx *Transaction) []int64 {
+		t.Helper()
+		out, err := tx.AssignIdentityValues(rec, memory.DefaultAllocator)
+		if err != nil {
+			t.Fatalf("error assigning identity values: %s", err)
+		}
+		defer out.Release()
+		return out.Column(1).(*array.Int64).Int64Values()
+	}
+
+	tx, _ := tbl.NewTransaction()
+	if got := assign(tx); len(got) != 3 || got[0] != 10 || got[2] != 20 {
+		t.Errorf("expected values 10, 15, 20, got %v", got)
+	}
+	// a second batch in the same transaction continues after the first
+	if got := assign(tx); got[0] != 25 || got[2] != 35 {
+		t.Errorf("expected values 25, 30, 35, got %v", got)
+	}
+	if _, err := tx.Commit(ctx, "WRITE"); err != nil {
+		t.Fatalf("error committing: %s", err)
+	}
+
+	columns, err := tbl.Snapshot().IdentityColumns()
+	if err != nil || len(columns) != 1 || !columns[0].HasHighWaterMark || columns[0].HighWaterMark != 35 {
+		t.Fatalf("expected high-water mark 35, got %+v (%v)", columns, err)
+	}
+
+	// concurrent writers assigning from the same snapshot conflict
+	tx1, _ := tbl.NewTransaction()
+	tx2, _ := tbl.NewTransaction()
+	first, second := assign(tx1), assign(tx2)
+	if first[0] != 40 || second[0] != 40 {
+		t.Errorf("expected both transactions to start at 40, got %v and %v", first, second)
+	}
+	if _, err := tx1.Commit(ctx, "WRITE"); err != nil {
+		t.Fatalf("error committing: %s", err)
+	}
+	_, err = tx2.Commit(ctx, "WRITE")
+	var conflict *ConcurrentModificationError
+	if !errors.As(err, &conflict) {
+		t.Errorf("expected ConcurrentModificationError, got %v", err)
+	}
+
+	explicit := arrow.NewSchema([]arrow.Field{{Name: "id", Type: arrow.PrimitiveTypes.Int64}}, nil)
+	rec2, _, err := array.RecordFromJSON(memory.DefaultAllocator, explicit, strings.NewReader(`[{"id":1}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec2.Release()
+	tx, _ = tbl.NewTransaction()
+	if _, err := tx.AssignIdentityValues(rec2, memory.DefaultAllocator); err == nil {
+		t.Errorf("expected error for explicit identity values")
+	}
+}
+
+func TestStepValue(t *testing.T) {
+	if v, ok := stepValue(1, 2, 3); !ok || v != 7 {
+		t.Errorf("expected 7, got %d (%t)", v, ok)
+	}
+	if _, ok := stepValue(1<<62, 1<<61, 2); ok {
+		t.Errorf("expected overflow")
+	}
+	if _, ok := stepValue(-(1 << 62), -(1 << 62), 2); ok {
+		t.Errorf("expected negative overflow")
+	}
+}
+
+func TestPrepareRecordIdentity(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[`+
+		`{"name":"id","type":"long","nullable":true,"metadata":{"delta.identity.start":1,"delta.identity.step":1,"delta.identity.allowExplicitInsert":false}},`+
+		`{"name":"name","type":"string","nullable":true,"metadata":{}}]}`, nil)
+	ctx := context.Background()
+
+	// identity values must be assigned by PrepareRecord
+	tx, _ := tbl.NewTransaction()
+	tx.AddFiles(AddAction{Action: Action{Path: "part-1.parquet", Size: 1, DataChange: true}})
+	var unprepared *UnpreparedDataError
+	if _, err := tx.Commit(ctx, "WRITE"); !errors.As(err, &unprepared) {
+		t.Fatalf("expected UnpreparedDataError, got %v", err)
+	}
+
+	input := arrow.NewSchema([]arrow.Field{{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true}}, nil)
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, input, strings.NewReader(`[{"name":"a"},{"name":"b"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	tx, _ = tbl.NewTransaction()
+	prepared, err := tx.PrepareRecord(rec, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatalf("error preparing record: %s", err)
+	}
+	defer prepared.Release()
+	if ids, ok := prepared.Column(0).(*array.Int64); !ok || ids.Value(0) != 1 || ids.Value(1) != 2 {
+		t.Fatalf("expected identity values in the first column, got %v", prepared)
+	}
+
+	tx.AddFiles(AddAction{Action: Action{Path: "part-1.parquet", Size: 1, DataChange: true}, Stats: `{"numRecords":2}`})
+	if _, err := tx.Commit(ctx, "WRITE"); err != nil {
+		t.Fatalf("error committing: %s", err)
+	}
+	if columns, _ := tbl.Snapshot().IdentityColumns(); columns[0].HighWaterMark != 2 {
+		t.Errorf("expected high-water mark 2, got %+v", columns)
+	}
+}
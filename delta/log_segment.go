@@ -0,0 +1,124 @@
+package delta
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+var (
+	commitFileRe         = regexp.MustCompile(`^(\d{20})\.json$`)
+	checkpointFileRe     = regexp.MustCompile(`^(\d{20})\.checkpoint\.parquet$`)
+	checkpointPartFileRe = regexp.MustCompile(`^(\d{20})\.checkpoint\.(\d{10})\.(\d{10})\.parquet$`)
+	v2CheckpointFileRe   = regexp.MustCompile(`^(\d{20})\.checkpoint\.[0-9a-fA-F]{8}(-[0-9a-fA-F]{4}){3}-[0-9a-fA-F]{12}\.(json|parquet)$`)
+)
+
+// logListing holds the commits and complete checkpoints found by listing _delta_log.
+type logListing struct {
+	// commits are the versions of every commit file, in ascending order
+	commits []int64
+	// checkpoints are the checkpoints whose files are all present, in ascending
+	// version order
+	checkpoints []Checkpoint
+}
+
+// listLog lists the _delta_log directory and classifies its commit and checkpoint files.
+// Multi-part checkpoints that are missing any part are left out.
+func (t *Table) listLog() (*logListing, error) {
+	infos, err := t.Storage.List(LogDir)
+	if err != nil {
+		return nil, err
+	}
+
+	type partKey struct {
+		version int64
+		parts   uint32
+	}
+
+	l := &logListing{}
+	parts := make(map[partKey]map[uint32]struct{})
+	for _, fi := range infos {
+		name := fi.Name()
+		if m := commitFileRe.FindStringSubmatch(name); m != nil {
+			l.commits = append(l.commits, parseVersion(m[1]))
+		} else if m := checkpointFileRe.FindStringSubmatch(name); m != nil {
+			l.checkpoints = append(l.checkpoints, Checkpoint{Version: parseVersion(m[1])})
+		} else if m := v2CheckpointFileRe.FindStringSubmatch(name); m != nil {
+			l.checkpoints = append(l.checkpoints, Checkpoint{
+				Version:      parseVersion(m[1]),
+				V2Checkpoint: &V2Checkpoint{Path: name, SizeInBytes: fi.Size(), ModificationTime: fi.ModTime().UnixMilli()},
+			})
+		} else if m := checkpointPartFileRe.FindStringSubmatch(name); m != nil {
+			part, _ := strconv.ParseUint(m[2], 10, 32)
+			n, _ := strconv.ParseUint(m[3], 10, 32)
+			k := partKey{version: parseVersion(m[1]), parts: uint32(n)}
+			if parts[k] == nil {
+				parts[k] = make(map[uint32]struct{})
+			}
+			parts[k][uint32(part)] = struct{}{}
+		}
+	}
+
+	for k, found := range parts {
+		complete := true
+		for i := uint32(1); i <= k.parts; i++ {
+			if _, ok := found[i]; !ok {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			l.checkpoints = append(l.checkpoints, Checkpoint{Version: k.version, Parts: k.parts})
+		}
+	}
+
+	sort.Slice(l.commits, func(i, j int) bool { return l.commits[i] < l.commits[j] })
+	sort.SliceStable(l.checkpoints, func(i, j int) bool {
+		return l.checkpoints[i].Version < l.checkpoints[j].Version
+	})
+	return l, nil
+}
+
+// latestCheckpoint returns the newest complete checkpoint at or below maxVersion.
+func (l *logListing) latestCheckpoint(maxVersion int64) (Checkpoint, bool) {
+	for i := len(l.checkpoints) - 1; i >= 0; i-- {
+		if l.checkpoints[i].Version <= maxVersion {
+			return l.checkpoints[i], true
+		}
+	}
+	return Checkpoint{}, false
+}
+
+// checkCommits verifies that the listed commits from start up to maxVersion have no gaps,
+// so that replaying them from start produces a consistent state.
+func (l *logListing) checkCommits(start, maxVersion int64) error {
+	expected := start
+	for _, v := range l.commits {
+		if v < start {
+			continue
+		}
+		if v > maxVersion {
+			break
+		}
+		if v != expected {
+			return fmt.Errorf("commit %d is missing from %s: %w", expected, LogDir, os.ErrNotExist)
+		}
+		expected++
+	}
+	return nil
+}
+
+// earliestCommit returns the version of the oldest commit still present in the log.
+func (l *logListing) earliestCommit() (int64, bool) {
+	if len(l.commits) == 0 {
+		return -1, false
+	}
+	return l.commits[0], true
+}
+
+func parseVersion(s string) int64 {
+	v, _ := strconv.ParseInt(s, 10, 64)
+	return v
+}
@@ -0,0 +1,130 @@
+package delta
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func copyLogFile(t *testing.T, src, dst, name string) {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(src, LogDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dst, LogDir, name), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListLog(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, LogDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{
+		"00000000000000000000.json",
+		"00000000000000000001.json",
+		"00000000000000000002.json",
+		"00000000000000000002.checkpoint.parquet",
+		"00000000000000000003.json",
+		"00000000000000000004.checkpoint.0000000001.0000000002.parquet",
+		"00000000000000000004.checkpoint.0000000002.0000000002.parquet",
+		"00000000000000000004.json",
+		"00000000000000000005.checkpoint.0000000001.0000000003.parquet",
+		"00000000000000000005.checkpoint.0000000003.0000000003.parquet",
+		"00000000000000000005.json",
+		"00000000000000000006.checkpoint.3a0d65cd-4056-49b8-937b-95f9e3ee90e5.json",
+		"00000000000000000006.crc",
+		"_last_checkpoint",
+	}
+	for _, n := range names {
+		if err := os.WriteFile(filepath.Join(dir, LogDir, n), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	l, err := NewTable(dir).listLog()
+	if err != nil {
+		t.Fatalf("error listing log: %s", err)
+	}
+
+	if len(l.commits) != 6 || l.commits[5] != 5 {
+		t.Errorf("unexpected commits %v", l.commits)
+	}
+
+	// the 3 part checkpoint at version 5 is incomplete
+	tests := []struct {
+		max     int64
+		version int64
+		parts   uint32
+		v2      bool
+	}{
+		{max: 100, version: 6, v2: true},
+		{max: 5, version: 4, parts: 2},
+		{max: 3, version: 2},
+	}
+	for _, tt := range tests {
+		cp, ok := l.latestCheckpoint(tt.max)
+		if !ok || cp.Version != tt.version || cp.Parts != tt.parts || (cp.V2Checkpoint != nil) != tt.v2 {
+			t.Errorf("max %d: unexpected checkpoint %+v", tt.max, cp)
+		}
+	}
+	if _, ok := l.latestCheckpoint(1); ok {
+		t.Errorf("expected no checkpoint at or below version 1")
+	}
+
+	if err := l.checkCommits(3, 100); err != nil {
+		t.Errorf("unexpected gap: %s", err)
+	}
+	if err := os.Remove(filepath.Join(dir, LogDir, "00000000000000000003.json")); err != nil {
+		t.Fatal(err)
+	}
+	l, _ = NewTable(dir).listLog()
+	if err := l.checkCommits(0, 100); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected missing commit error, got %v", err)
+	}
+	if err := l.checkCommits(4, 100); err != nil {
+		t.Errorf("unexpected gap after checkpoint: %s", err)
+	}
+}
+
+func TestLoadTableWithoutLastCheckpoint(t *testing.T) {
+	src := "../tests/data/simple_table_with_checkpoint"
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, LogDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// the commits before the checkpoint have been cleaned up and _last_checkpoint is lost
+	copyLogFile(t, src, dir, "00000000000000000010.checkpoint.parquet")
+	copyLogFile(t, src, dir, "00000000000000000010.json")
+
+	tbl, err := LoadTable(dir)
+	if err != nil {
+		t.Fatalf("error loading table: %s", err)
+	}
+	if tbl.Version() != 10 || len(tbl.Snapshot().Files()) != 11 {
+		t.Errorf("expected version 10 with 11 files, got version %d with %d files", tbl.Version(), len(tbl.Snapshot().Files()))
+	}
+
+	// a stale _last_checkpoint is ignored in favour of the newest checkpoint
+	stale := `{"version":5,"size":6}`
+	if err := os.WriteFile(filepath.Join(dir, LogDir, LastCheckPointFile), []byte(stale), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tbl, err = LoadTable(dir)
+	if err != nil {
+		t.Fatalf("error loading table: %s", err)
+	}
+	l, err := tbl.listLog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	candidates := tbl.checkpointCandidates(l, math.MaxInt64)
+	if len(candidates) == 0 || candidates[0].Version != 10 || len(tbl.Snapshot().Files()) != 11 {
+		t.Errorf("expected checkpoint 10 with 11 files, got checkpoints %v with %d files", candidates, len(tbl.Snapshot().Files()))
+	}
+}
@@ -0,0 +1,233 @@
+package delta
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/decimal128"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/delta-golang/delta-go/delta/expr"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+// HiveDefaultPartition is the partition directory name Hive-style writers use for NULL
+// partition values. Some writers store it as the partition value itself.
+const HiveDefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// ParsePartitionValue parses the serialized value of a partition column of type dt, as
+// stored in the partitionValues of an add action. Values are stored unescaped; only the
+// data file path escapes them. An empty value or HiveDefaultPartition is NULL and
+// returns nil.
+//
+// Values are returned in the representation of the expr package: int64 for integral
+// types, float64 for floating point types, an exact expr.Decimal for decimal types, bool,
+// string, []byte for binary and time.Time in UTC for dates and timestamps. Timestamps are written as
+// "2006-01-02 15:04:05[.ffffff]" in UTC or in ISO 8601 with a zone.
+func ParsePartitionValue(value string, dt schema.DataType) (interface{}, error) {
+	if value == "" || value == HiveDefaultPartition {
+		return nil, nil
+	}
+
+	switch dt {
+	case schema.String:
+		return value, nil
+	case schema.Binary:
+		return []byte(value), nil
+	case schema.Byte, schema.Short, schema.Integer, schema.Long:
+		bits := map[schema.DataType]int{schema.Byte: 8, schema.Short: 16, schema.Integer: 32, schema.Long: 64}[dt]
+		i, err := strconv.ParseInt(value, 10, bits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s partition value %q", dt, value)
+		}
+		return i, nil
+	case schema.Float, schema.Double:
+		bits := 64
+		if dt == schema.Float {
+			bits = 32
+		}
+		f, err := strconv.ParseFloat(value, bits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s partition value %q", dt, value)
+		}
+		return f, nil
+	case schema.Boolean:
+		switch strings.ToLower(value) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("invalid %s partition value %q", dt, value)
+	case schema.Date:
+		t, err := time.ParseInLocation("2006-01-02", value, time.UTC)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s partition value %q", dt, value)
+		}
+		return t, nil
+	case schema.Timestamp, schema.TimestampNtz:
+		t, err := expr.ParseTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s partition value %q", dt, value)
+		}
+		return t.UTC(), nil
+	}
+
+	if d, ok := dt.(schema.DecimalType); ok {
+		n, err := parseDecimal(value, d)
+		if err != nil {
+			return nil, err
+		}
+		return expr.NewDecimal(n.BigInt(), int(d.Scale)), nil
+	}
+	return nil, fmt.Errorf("partition columns of type %s are not supported", dt)
+}
+
+// parseDecimal parses a decimal partition value exactly to its unscaled value at the
+// scale of d. Values with more fractional digits than the scale or more digits than the
+// precision are an error.
+func parseDecimal(value string, d schema.DecimalType) (decimal128.Num, error) {
+	r, ok := new(big.Rat).SetString(value)
+	if !ok || strings.ContainsAny(value, "/") {
+		return decimal128.Num{}, fmt.Errorf("invalid %s partition value %q", d, value)
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.Scale)), nil)))
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.Precision)), nil)
+	if !r.IsInt() || new(big.Int).Abs(r.Num()).Cmp(limit) >= 0 {
+		return decimal128.Num{}, fmt.Errorf("partition value %q does not fit %s", value, d)
+	}
+	return decimal128.FromBigInt(r.Num()), nil
+}
+
+// partitionValue returns the serialized value of the partition column name among the
+// partition values of a file. Partition column names are case-insensitive.
+func partitionValue(values map[string]string, name string) string {
+	if v, ok := values[name]; ok {
+		return v
+	}
+	for k, v := range values {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// partitionColumn returns n copies of the value of the partition column f, parsed from
+// the partition values of a file, as an array of the Arrow type target.
+func partitionColumn(f schema.StructField, target arrow.DataType, values map[string]string, n int, mem memory.Allocator) (arrow.Array, error) {
+	s := partitionValue(values, f.Name)
+
+	var v interface{}
+	var err error
+	if d, ok := f.Type.(schema.DecimalType); ok && s != "" && s != HiveDefaultPartition {
+		// decimals are built from the exact unscaled value rather than a float64
+		v, err = parseDecimal(s, d)
+	} else {
+		v, err = ParsePartitionValue(s, f.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("partition column %s: %w", f.Name, err)
+	}
+	return expr.Repeat(v, target, n, mem)
+}
+
+// partitionPruner decides from the partition values of a data file whether the file can
+// hold rows matching a filter. Only the conjuncts of the filter that reference nothing
+// but partition columns are used, since they have the same value for every row of a
+// file, along with the filters GeneratedPartitionFilters derives for generated
+// partition columns.
+type partitionPruner struct {
+	columns map[string]schema.StructField
+	// names are the keys of columns in sorted order.
+	names     []string
+	conjuncts []expr.Expr
+	// results caches the decision for each distinct set of partition values.
+	results map[string]bool
+}
+
+func newPartitionPruner(filter expr.Expr, sch *schema.StructType, partitionColumns []string) *partitionPruner {
+	p := &partitionPruner{columns: make(map[string]schema.StructField), results: make(map[string]bool)}
+	for _, f := range sch.Fields {
+		for _, c := range partitionColumns {
+			if strings.EqualFold(f.Name, c) {
+				p.columns[strings.ToLower(f.Name)] = f
+				p.names = append(p.names, strings.ToLower(f.Name))
+			}
+		}
+	}
+	sort.Strings(p.names)
+
+	candidates := append(splitConjuncts(filter), GeneratedPartitionFilters(filter, sch, partitionColumns)...)
+	for _, c := range candidates {
+		if p.partitionOnly(c) {
+			p.conjuncts = append(p.conjuncts, c)
+		}
+	}
+	return p
+}
+
+func (p *partitionPruner) partitionOnly(e expr.Expr) bool {
+	for _, c := range expr.Columns(e) {
+		if len(c.Path) != 1 {
+			return false
+		}
+		if _, ok := p.columns[strings.ToLower(c.Path[0])]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// matches reports whether a file with the given partition values, keyed by logical
+// column name, can hold matching rows. A conjunct that evaluates to NULL excludes the
+// file like one that evaluates to false.
+func (p *partitionPruner) matches(values map[string]string) (bool, error) {
+	if len(p.conjuncts) == 0 {
+		return true, nil
+	}
+
+	var key strings.Builder
+	for _, name := range p.names {
+		key.WriteString(strconv.Quote(partitionValue(values, p.columns[name].Name)))
+	}
+	if ok, found := p.results[key.String()]; found {
+		return ok, nil
+	}
+
+	literals := make(map[string]interface{}, len(p.columns))
+	for name, f := range p.columns {
+		v, err := ParsePartitionValue(partitionValue(values, f.Name), f.Type)
+		if err != nil {
+			return false, fmt.Errorf("partition column %s: %w", f.Name, err)
+		}
+		literals[name] = v
+	}
+
+	ok := true
+	for _, c := range p.conjuncts {
+		bound := expr.Rewrite(c, func(e expr.Expr) expr.Expr {
+			if col, isCol := e.(expr.Column); isCol {
+				return expr.Lit(literals[strings.ToLower(col.Path[0])])
+			}
+			return e
+		})
+		prog, err := expr.Compile(bound, arrow.NewSchema(nil, nil))
+		if err != nil {
+			return false, err
+		}
+		// a conjunct that cannot be evaluated, such as a comparison of a string that is
+		// not a number with a number, does not exclude the file
+		v, err := prog.Eval(nil, 0)
+		if err == nil && !expr.IsTrue(v) {
+			ok = false
+			break
+		}
+	}
+	p.results[key.String()] = ok
+	return ok, nil
+}
@@ -0,0 +1,81 @@
+package delta
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/delta-golang/delta-go/delta/expr"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+func TestParsePartitionValue(t *testing.T) {
+	tests := []struct {
+		value string
+		typ   schema.DataType
+		want  interface{}
+	}{
+		{"", schema.Integer, nil},
+		{HiveDefaultPartition, schema.String, nil},
+		{"A/A", schema.String, "A/A"},
+		{"10", schema.Byte, int64(10)},
+		{"-9", schema.Long, int64(-9)},
+		{"10.0", schema.Double, 10.0},
+		{"True", schema.Boolean, true},
+		{"2021-01-02", schema.Date, time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"2021-01-02 03:04:05.123456", schema.Timestamp, time.Date(2021, 1, 2, 3, 4, 5, 123456000, time.UTC)},
+		{"2021-01-02T03:04:05+01:00", schema.Timestamp, time.Date(2021, 1, 2, 2, 4, 5, 0, time.UTC)},
+		{"2021-01-02 03:04:05", schema.TimestampNtz, time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"12.30", schema.DecimalType{Precision: 5, Scale: 2}, expr.NewDecimal(big.NewInt(1230), 2)},
+		{"ab", schema.Binary, []byte("ab")},
+	}
+	for _, tt := range tests {
+		got, err := ParsePartitionValue(tt.value, tt.typ)
+		if err != nil {
+			t.Errorf("%q as %s: %s", tt.value, tt.typ, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q as %s: expected %#v, got %#v", tt.value, tt.typ, tt.want, got)
+		}
+	}
+	if got, err := ParsePartitionValue("NaN", schema.Double); err != nil || !math.IsNaN(got.(float64)) {
+		t.Errorf("expected NaN, got %v, %v", got, err)
+	}
+
+	for _, tt := range []struct {
+		value string
+		typ   schema.DataType
+	}{
+		{"300", schema.Byte},
+		{"1.5", schema.Integer},
+		{"yes", schema.Boolean},
+		{"2021-13-01", schema.Date},
+		{"123.456", schema.DecimalType{Precision: 5, Scale: 2}},
+		{"1234.5", schema.DecimalType{Precision: 5, Scale: 2}},
+	} {
+		if _, err := ParsePartitionValue(tt.value, tt.typ); err == nil {
+			t.Errorf("%q as %s: expected error", tt.value, tt.typ)
+		}
+	}
+}
+
+func TestPartitionColumnDecimal(t *testing.T) {
+	f := schema.StructField{Name: "d", Type: schema.DecimalType{Precision: 38, Scale: 10}}
+	target, err := schema.ToArrowType(f.Type)
+	if err != nil {
+		t.Fatal(err)
+	}
+	arr, err := partitionColumn(f, target, map[string]string{"D": "1234567890123456789.0123456789"}, 2, memory.DefaultAllocator)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer arr.Release()
+	if got := arr.(*array.Decimal128).Value(1).BigInt().String(); got != "12345678901234567890123456789" {
+		t.Errorf("expected the exact unscaled value, got %s", got)
+	}
+}
@@ -0,0 +1,209 @@
+package delta
+
+import (
+	"sort"
+)
+
+// Names of the table features listed in the readerFeatures and writerFeatures of a
+// protocol with reader version 3 or writer version 7.
+const (
+	FeatureAppendOnly       = "appendOnly"
+	FeatureInvariants       = "invariants"
+	FeatureCheckConstraints = "checkConstraints"
+	FeatureChangeDataFeed   = "changeDataFeed"
+	FeatureGeneratedColumns = "generatedColumns"
+	FeatureColumnMapping    = "columnMapping"
+	FeatureIdentityColumns  = "identityColumns"
+	FeatureTypeWidening     = "typeWidening"
+	FeatureTimestampNtz     = "timestampNtz"
+	FeatureVariantType      = "variantType"
+	FeatureV2Checkpoint     = "v2Checkpoint"
+)
+
+// maxReaderVersion is the highest reader protocol version this package can read.
+const maxReaderVersion = 3
+
+// maxWriterVersion is the highest writer protocol version this package can write.
+const maxWriterVersion = 7
+
+// legacyFeatures maps the features that predate table features to the protocol versions
+// that introduced them.
+var legacyFeatures = map[string]Protocol{
+	FeatureAppendOnly:       {MinReaderVersion: 1, MinWriterVersion: 2},
+	FeatureInvariants:       {MinReaderVersion: 1, MinWriterVersion: 2},
+	FeatureCheckConstraints: {MinReaderVersion: 1, MinWriterVersion: 3},
+	FeatureChangeDataFeed:   {MinReaderVersion: 1, MinWriterVersion: 4},
+	FeatureGeneratedColumns: {MinReaderVersion: 1, MinWriterVersion: 4},
+	FeatureColumnMapping:    {MinReaderVersion: 2, MinWriterVersion: 5},
+	FeatureIdentityColumns:  {MinReaderVersion: 1, MinWriterVersion: 6},
+}
+
+// readerWriterFeatures are the features readers must support as well as writers.
+var readerWriterFeatures = map[string]bool{
+	FeatureColumnMapping: true,
+	FeatureTypeWidening:  true,
+	FeatureTimestampNtz:  true,
+	FeatureVariantType:   true,
+	FeatureV2Checkpoint:  true,
+}
+
+// supportedReaderFeatures are the reader features this package implements.
+var supportedReaderFeatures = map[string]bool{
+	FeatureColumnMapping:   true,
+	FeatureTypeWidening:    true,
+	FeatureTimestampNtz:    true,
+	FeatureVariantType:     true,
+	FeatureV2Checkpoint:    true,
+	"typeWidening-preview": true,
+	"variantType-preview":  true,
+	"vacuumProtocolCheck":  true,
+}
+
+// supportedWriterFeatures are the writer features Transaction enforces. Commits that
+// remove and add data files to a table with the change data feed enabled are rejected,
+// since they would need change data files. The checkpoint and vacuum features only
+// constrain operations this package does not perform.
+var supportedWriterFeatures = map[string]bool{
+	FeatureAppendOnly:       true,
+	FeatureInvariants:       true,
+	FeatureCheckConstraints: true,
+	FeatureChangeDataFeed:   true,
+	FeatureGeneratedColumns: true,
+	FeatureColumnMapping:    true,
+	FeatureIdentityColumns:  true,
+	FeatureTypeWidening:     true,
+	FeatureTimestampNtz:     true,
+	FeatureVariantType:      true,
+	FeatureV2Checkpoint:     true,
+	"typeWidening-preview":  true,
+	"variantType-preview":   true,
+	"vacuumProtocolCheck":   true,
+}
+
+// SupportsFeature reports whether the protocol enables a table feature, either by listing
+// it or, for features that predate table features, by its writer version.
+func (p Protocol) SupportsFeature(name string) bool {
+	if v, ok := legacyFeatures[name]; ok && p.MinWriterVersion < 7 {
+		return p.MinWriterVersion >= v.MinWriterVersion
+	}
+	for _, f := range p.WriterFeatures {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckReadSupport returns an *UnsupportedProtocolError if reading the table requires a
+// reader version or reader features this package does not implement.
+func (p Protocol) CheckReadSupport() error {
+	if p.MinReaderVersion > maxReaderVersion {
+		return &UnsupportedProtocolError{ReaderVersion: p.MinReaderVersion}
+	}
+	if p.MinReaderVersion < 3 {
+		return nil
+	}
+
+	var unsupported []string
+	for _, f := range p.ReaderFeatures {
+		if !supportedReaderFeatures[f] {
+			unsupported = append(unsupported, f)
+		}
+	}
+	if len(unsupported) > 0 {
+		return &UnsupportedProtocolError{ReaderVersion: p.MinReaderVersion, Features: unsupported}
+	}
+	return nil
+}
+
+// CheckWriteSupport returns an *UnsupportedProtocolError if writing to the table requires
+// a writer version or writer features this package does not implement.
+func (p Protocol) CheckWriteSupport() error {
+	if p.MinWriterVersion > maxWriterVersion {
+		return &UnsupportedProtocolError{WriterVersion: p.MinWriterVersion}
+	}
+	if p.MinWriterVersion < 7 {
+		return nil
+	}
+
+	var unsupported []string
+	for _, f := range p.WriterFeatures {
+		if !supportedWriterFeatures[f] {
+			unsupported = append(unsupported, f)
+		}
+	}
+	if len(unsupported) > 0 {
+		return &UnsupportedProtocolError{WriterVersion: p.MinWriterVersion, Features: unsupported}
+	}
+	return nil
+}
+
+// withFeatures returns the protocol upgraded to support features. While only features
+// that predate table features are needed, the versions are raised; otherwise the protocol
+// moves to writer version 7, and to reader version 3 if a reader feature is needed,
+// listing the features the old versions supported implicitly.
+func (p Protocol) withFeatures(features ...string) Protocol {
+	var missing []string
+	legacy := true
+	for _, f := range features {
+		if !p.SupportsFeature(f) {
+			missing = append(missing, f)
+			_, ok := legacyFeatures[f]
+			legacy = legacy && ok
+		}
+	}
+	if len(missing) == 0 {
+		return p
+	}
+
+	if legacy && p.MinWriterVersion < 7 {
+		for _, f := range missing {
+			v := legacyFeatures[f]
+			if v.MinReaderVersion > p.MinReaderVersion {
+				p.MinReaderVersion = v.MinReaderVersion
+			}
+			if v.MinWriterVersion > p.MinWriterVersion {
+				p.MinWriterVersion = v.MinWriterVersion
+			}
+		}
+		return p
+	}
+
+	supported := make(map[string]bool)
+	for name := range legacyFeatures {
+		if p.SupportsFeature(name) {
+			supported[name] = true
+		}
+	}
+	for _, f := range p.WriterFeatures {
+		supported[f] = true
+	}
+	for _, f := range missing {
+		supported[f] = true
+	}
+
+	readers := make(map[string]bool)
+	for _, f := range p.ReaderFeatures {
+		readers[f] = true
+	}
+
+	upgraded := Protocol{MinReaderVersion: p.MinReaderVersion, MinWriterVersion: 7}
+	for f := range supported {
+		upgraded.WriterFeatures = append(upgraded.WriterFeatures, f)
+		if readerWriterFeatures[f] {
+			readers[f] = true
+		}
+	}
+	for f := range readers {
+		upgraded.ReaderFeatures = append(upgraded.ReaderFeatures, f)
+	}
+	sort.Strings(upgraded.WriterFeatures)
+	sort.Strings(upgraded.ReaderFeatures)
+
+	if len(upgraded.ReaderFeatures) > 0 {
+		upgraded.MinReaderVersion = 3
+	} else if upgraded.MinReaderVersion < 1 {
+		upgraded.MinReaderVersion = 1
+	}
+	return upgraded
+}
@@ -0,0 +1,77 @@
+package delta
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+func TestProtocolWithFeatures(t *testing.T) {
+	p := Protocol{MinReaderVersion: 1, MinWriterVersion: 2}
+	if got := p.withFeatures(FeatureInvariants); !reflect.DeepEqual(got, p) {
+		t.Errorf("expected no upgrade, got %+v", got)
+	}
+	if got := p.withFeatures(FeatureColumnMapping); got.MinReaderVersion != 2 || got.MinWriterVersion != 5 {
+		t.Errorf("expected upgrade to 2/5, got %+v", got)
+	}
+
+	p = Protocol{MinReaderVersion: 3, MinWriterVersion: 7, ReaderFeatures: []string{"deletionVectors"}, WriterFeatures: []string{"deletionVectors"}}
+	expected := Protocol{
+		MinReaderVersion: 3,
+		MinWriterVersion: 7,
+		ReaderFeatures:   []string{"deletionVectors"},
+		WriterFeatures:   []string{FeatureCheckConstraints, "deletionVectors"},
+	}
+	if got := p.withFeatures(FeatureCheckConstraints); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+	if !expected.SupportsFeature(FeatureCheckConstraints) || expected.SupportsFeature(FeatureInvariants) {
+		t.Errorf("expected only listed features to be supported by %+v", expected)
+	}
+}
+
+func TestCheckReadSupport(t *testing.T) {
+	tests := []struct {
+		p   Protocol
+		err bool
+	}{
+		{Protocol{MinReaderVersion: 1, MinWriterVersion: 2}, false},
+		{Protocol{MinReaderVersion: 3, MinWriterVersion: 7, ReaderFeatures: []string{FeatureTimestampNtz, FeatureVariantType}}, false},
+		{Protocol{MinReaderVersion: 3, MinWriterVersion: 7, ReaderFeatures: []string{"deletionVectors", FeatureColumnMapping}}, true},
+		{Protocol{MinReaderVersion: 4, MinWriterVersion: 7}, true},
+	}
+	for _, tt := range tests {
+		err := tt.p.CheckReadSupport()
+		var unsupported *UnsupportedProtocolError
+		if tt.err != errors.As(err, &unsupported) {
+			t.Errorf("%+v: expected error %t, got %v", tt.p, tt.err, err)
+		}
+	}
+}
+
+func TestAddColumnUpgradesProtocol(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[{"name":"a","type":"long","nullable":true,"metadata":{}}]}`, nil)
+	_, err := tbl.AlterTable(context.Background(),
+		AddColumn{Field: schema.StructField{Name: "ts", Type: schema.TimestampNtz, Nullable: true}},
+		AddColumn{Field: schema.StructField{Name: "v", Type: &schema.ArrayType{ElementType: schema.Variant, ContainsNull: true}, Nullable: true}},
+	)
+	if err != nil {
+		t.Fatalf("error adding columns: %s", err)
+	}
+
+	expected := Protocol{
+		MinReaderVersion: 3,
+		MinWriterVersion: 7,
+		ReaderFeatures:   []string{FeatureTimestampNtz, FeatureVariantType},
+		WriterFeatures:   []string{FeatureAppendOnly, FeatureInvariants, FeatureTimestampNtz, FeatureVariantType},
+	}
+	if p := tbl.Snapshot().Protocol(); !reflect.DeepEqual(p, expected) {
+		t.Errorf("expected protocol %+v, got %+v", expected, p)
+	}
+	if err := tbl.Snapshot().Protocol().CheckReadSupport(); err != nil {
+		t.Errorf("expected table to be readable, got %s", err)
+	}
+}
@@ -0,0 +1,589 @@
+package delta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/apache/arrow/go/v8/parquet/file"
+	"github.com/apache/arrow/go/v8/parquet/pqarrow"
+	"github.com/delta-golang/delta-go/delta/expr"
+	"github.com/delta-golang/delta-go/delta/schema"
+	"github.com/delta-golang/delta-go/delta/storage"
+)
+
+// defaultScanBatchSize is the number of rows of the records returned by a scan when
+// ScanOptions.BatchSize is not set.
+const defaultScanBatchSize = 64 * 1024
+
+// parquetFieldIDKey is the Arrow field metadata key the parquet reader stores field ids in.
+const parquetFieldIDKey = "PARQUET:field_id"
+
+// ScanOptions configures Snapshot.Scan.
+type ScanOptions struct {
+	// BatchSize is the maximum number of rows of each record. Zero uses a default of
+	// 64Ki rows.
+	BatchSize int64
+	// Allocator allocates the memory of the records. Nil uses memory.DefaultAllocator.
+	Allocator memory.Allocator
+	// Filter, if set, skips the data files whose partition values or statistics show
+	// that none of their rows can match it, before any file is opened. Rows of the
+	// remaining files are returned whether they match or not.
+	Filter expr.Expr
+}
+
+// ScanReader reads the rows of the data files of a snapshot as records with the table
+// schema. It implements array.RecordReader; Err returns the error that ended the scan.
+// A ScanReader is not safe for concurrent use.
+type ScanReader struct {
+	refCount int64
+
+	storage    storage.Backend
+	opts       ScanOptions
+	logical    *schema.StructType
+	schema     *arrow.Schema
+	mapping    *ColumnMapping
+	partitions map[string]bool
+	files      []AddAction
+	pruned     int
+	skipped    int
+
+	next    int
+	current *scanFile
+	rec     arrow.Record
+	err     error
+}
+
+var _ array.RecordReader = (*ScanReader)(nil)
+
+// scanFile is the data file a scan is reading.
+type scanFile struct {
+	path            string
+	partitionValues map[string]string
+	reader          *file.Reader
+	closer          io.Closer
+	// records is nil when no column is read from the file, in which case remaining rows
+	// are returned without columns.
+	records   pqarrow.RecordReader
+	remaining int64
+	batch     arrow.Record
+}
+
+// Scan returns a reader of the rows of every data file of the snapshot. Records have the
+// table schema converted by schema.ToArrow: the values of partition columns are taken
+// from the partition values of each file, columns a file does not have, such as columns
+// added after it was written, are NULL, and narrower types of files written before a
+// type change are widened. Files are opened through the storage backend of the table,
+// one at a time as the reader advances. The reader must be released.
+func (s *Snapshot) Scan(opts ScanOptions) (*ScanReader, error) {
+	if err := s.Protocol().CheckReadSupport(); err != nil {
+		return nil, err
+	}
+	if s.storage == nil {
+		return nil, errors.New("snapshot is not backed by a table")
+	}
+	sch, err := s.Schema()
+	if err != nil {
+		return nil, err
+	}
+	mapping, err := s.ColumnMapping()
+	if err != nil {
+		return nil, err
+	}
+	as, err := schema.ToArrow(sch)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultScanBatchSize
+	}
+	if opts.Allocator == nil {
+		opts.Allocator = memory.DefaultAllocator
+	}
+
+	partitionColumns := s.state.CurrentMetadata.PartitionColumns
+	partitions := make(map[string]bool, len(partitionColumns))
+	for _, p := range partitionColumns {
+		partitions[strings.ToLower(p)] = true
+	}
+
+	r := &ScanReader{
+		refCount:   1,
+		storage:    s.storage,
+		opts:       opts,
+		logical:    sch,
+		schema:     as,
+		mapping:    mapping,
+		partitions: partitions,
+		files:      s.Files(),
+	}
+	if opts.Filter == nil {
+		return r, nil
+	}
+
+	// reject filters that do not apply to the table before pruning
+	if _, err := expr.Compile(opts.Filter, as); err != nil {
+		return nil, fmt.Errorf("invalid scan filter: %w", err)
+	}
+	pruner := newPartitionPruner(opts.Filter, sch, partitionColumns)
+	files := r.files[:0:0]
+	for _, add := range r.files {
+		values := mapping.LogicalPartitionValues(add.PartitionValues)
+		ok, err := pruner.matches(values)
+		if err != nil {
+			return nil, fmt.Errorf("pruning data file %s: %w", add.Path, err)
+		}
+		if !ok {
+			r.pruned++
+			continue
+		}
+
+		// files without statistics, or with statistics that cannot be parsed, are read
+		if add.Stats != "" {
+			stats, err := parseFileStats(add.Stats, sch, mapping, partitions, values)
+			if err == nil && !expr.MayMatch(opts.Filter, stats) {
+				r.skipped++
+				continue
+			}
+		}
+		files = append(files, add)
+	}
+	r.files = files
+	return r, nil
+}
+
+// NumFiles returns the number of data files the scan reads.
+func (r *ScanReader) NumFiles() int {
+	return len(r.files)
+}
+
+// PrunedFiles returns the number of data files skipped by the partition values of the
+// scan filter.
+func (r *ScanReader) PrunedFiles() int {
+	return r.pruned
+}
+
+// SkippedFiles returns the number of data files skipped by the statistics of the scan
+// filter, not counting pruned files.
+func (r *ScanReader) SkippedFiles() int {
+	return r.skipped
+}
+
+// Retain increases the reference count of the reader.
+func (r *ScanReader) Retain() {
+	atomic.AddInt64(&r.refCount, 1)
+}
+
+// Release decreases the reference count of the reader. When it reaches zero the current
+// record is released and the open data file is closed.
+func (r *ScanReader) Release() {
+	if atomic.AddInt64(&r.refCount, -1) != 0 {
+		return
+	}
+	if r.rec != nil {
+		r.rec.Release()
+		r.rec = nil
+	}
+	r.closeFile()
+}
+
+// Schema returns the Arrow schema of the table.
+func (r *ScanReader) Schema() *arrow.Schema {
+	return r.schema
+}
+
+// Record returns the current record. It is valid until the next call to Next.
+func (r *ScanReader) Record() arrow.Record {
+	return r.rec
+}
+
+// Err returns the error that stopped the scan, if any.
+func (r *ScanReader) Err() error {
+	return r.err
+}
+
+// Next advances to the next record and reports whether there is one.
+func (r *ScanReader) Next() bool {
+	if r.rec != nil {
+		r.rec.Release()
+		r.rec = nil
+	}
+
+	for r.err == nil {
+		if r.current == nil {
+			if r.next >= len(r.files) {
+				return false
+			}
+			add := r.files[r.next]
+			r.next++
+			if r.current, r.err = r.openFile(add); r.err != nil {
+				r.err = fmt.Errorf("opening data file %s: %w", add.Path, r.err)
+			}
+			continue
+		}
+
+		rec, err := r.current.read(r.opts.BatchSize)
+		if errors.Is(err, io.EOF) {
+			r.closeFile()
+			continue
+		}
+		if err == nil {
+			r.rec, err = r.conform(rec)
+		}
+		if err != nil {
+			r.err = fmt.Errorf("reading data file %s: %w", r.current.path, err)
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+func (r *ScanReader) closeFile() {
+	if r.current == nil {
+		return
+	}
+	if r.current.records != nil {
+		r.current.records.Release()
+	}
+	if r.current.batch != nil {
+		r.current.batch.Release()
+	}
+	r.current.reader.Close()
+	r.current.closer.Close()
+	r.current = nil
+}
+
+// openFile opens a data file and prepares to read the columns of the table it contains.
+func (r *ScanReader) openFile(add AddAction) (*scanFile, error) {
+	path, err := url.PathUnescape(add.Path)
+	if err != nil {
+		return nil, err
+	}
+	if u, err := url.Parse(add.Path); err == nil && u.Scheme != "" {
+		return nil, fmt.Errorf("absolute data file paths are not supported")
+	}
+
+	obj, err := r.storage.OpenObject(path)
+	if err != nil {
+		return nil, err
+	}
+	pf, err := file.NewParquetReader(storage.NewReadSeekerAt(obj))
+	if err != nil {
+		obj.Close()
+		return nil, err
+	}
+	f := &scanFile{
+		path:            add.Path,
+		partitionValues: r.mapping.LogicalPartitionValues(add.PartitionValues),
+		reader:          pf,
+		closer:          obj,
+		remaining:       pf.NumRows(),
+	}
+
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{BatchSize: r.opts.BatchSize}, r.opts.Allocator)
+	if err != nil {
+		f.reader.Close()
+		obj.Close()
+		return nil, err
+	}
+
+	// only read the columns of the table, not those of dropped columns
+	var cols []int
+	fileFields := make([]arrow.Field, len(fr.Manifest.Fields))
+	for i, sf := range fr.Manifest.Fields {
+		fileFields[i] = *sf.Field
+	}
+	for _, field := range r.logical.Fields {
+		if r.partitions[strings.ToLower(field.Name)] {
+			continue
+		}
+		if i := r.findField(fileFields, field); i >= 0 {
+			cols = append(cols, leafColumns(fr.Manifest.Fields[i])...)
+		}
+	}
+	if len(cols) == 0 {
+		return f, nil
+	}
+
+	f.records, err = fr.GetRecordReader(context.Background(), cols, nil)
+	if err != nil {
+		f.reader.Close()
+		obj.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// leafColumns returns the parquet column indexes of the leaves of sf. Groups are told
+// apart by their children: pqarrow leaves ColIndex at zero for groups, so IsLeaf is
+// true for them.
+func leafColumns(sf pqarrow.SchemaField) []int {
+	if len(sf.Children) == 0 {
+		return []int{sf.ColIndex}
+	}
+	var cols []int
+	for _, c := range sf.Children {
+		cols = append(cols, leafColumns(c)...)
+	}
+	return cols
+}
+
+// read returns the next batch of the file. The record is owned by the file.
+func (f *scanFile) read(batchSize int64) (arrow.Record, error) {
+	if f.records != nil {
+		return f.records.Read()
+	}
+	if f.batch != nil {
+		f.batch.Release()
+		f.batch = nil
+	}
+	if f.remaining <= 0 {
+		return nil, io.EOF
+	}
+	n := batchSize
+	if f.remaining < n {
+		n = f.remaining
+	}
+	f.remaining -= n
+	f.batch = array.NewRecord(arrow.NewSchema(nil, nil), nil, n)
+	return f.batch, nil
+}
+
+// findField returns the index of the field of a data file that holds the values of the
+// table field f: by column mapping id in id mode, by physical name in name mode, and by
+// name otherwise.
+func (r *ScanReader) findField(fields []arrow.Field, f schema.StructField) int {
+	switch r.mapping.Mode() {
+	case ColumnMappingID:
+		id, _ := f.ColumnMappingID()
+		for i, ff := range fields {
+			if k := ff.Metadata.FindKey(parquetFieldIDKey); k >= 0 && ff.Metadata.Values()[k] == strconv.FormatInt(id, 10) {
+				return i
+			}
+		}
+		return -1
+	case ColumnMappingName:
+		physical, _ := f.PhysicalName()
+		for i, ff := range fields {
+			if ff.Name == physical {
+				return i
+			}
+		}
+		return -1
+	}
+
+	for i, ff := range fields {
+		if ff.Name == f.Name {
+			return i
+		}
+	}
+	for i, ff := range fields {
+		if strings.EqualFold(ff.Name, f.Name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// conform converts a batch read from the current file to a record with the table schema.
+func (r *ScanReader) conform(batch arrow.Record) (arrow.Record, error) {
+	n := int(batch.NumRows())
+	fields := batch.Schema().Fields()
+	cols := make([]arrow.Array, len(r.logical.Fields))
+	defer func() {
+		for _, c := range cols {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+
+	for i, f := range r.logical.Fields {
+		target := r.schema.Field(i).Type
+
+		var err error
+		switch {
+		case r.partitions[strings.ToLower(f.Name)]:
+			cols[i], err = partitionColumn(f, target, r.current.partitionValues, n, r.opts.Allocator)
+		default:
+			j := r.findField(fields, f)
+			if j < 0 {
+				cols[i], err = expr.Repeat(nil, target, n, r.opts.Allocator)
+			} else {
+				cols[i], err = r.conformArray(batch.Column(j), f, target)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return array.NewRecord(r.schema, cols, int64(n)), nil
+}
+
+// conformArray converts arr, the values of field f in a data file, to the Arrow type
+// target of f in the table schema. The fields of nested structs are matched like
+// top-level columns. The parquet reader never returns sliced arrays, so the offset of
+// arr is not taken into account when nested arrays are rebuilt.
+func (r *ScanReader) conformArray(arr arrow.Array, f schema.StructField, target arrow.DataType) (arrow.Array, error) {
+	changes, err := ParseTypeChanges(f)
+	if err != nil {
+		return nil, err
+	}
+	return r.conformValue(arr, f.Name, f.Type, changes, "", target)
+}
+
+// conformValue converts arr, the values at fieldPath of the field name, to target, the
+// Arrow type of dt. changes are the type changes recorded on the field; the fieldPath of
+// the field itself is empty, and the elements of arrays and the keys and values of maps
+// append "element", "key" and "value".
+func (r *ScanReader) conformValue(arr arrow.Array, name string, dt schema.DataType, changes []TypeChange, fieldPath string, target arrow.DataType) (arrow.Array, error) {
+	mem := r.opts.Allocator
+	data := arr.Data()
+
+	switch t := dt.(type) {
+	case *schema.StructType:
+		st, ok := arr.(*array.Struct)
+		if !ok {
+			break
+		}
+		srcFields := st.DataType().(*arrow.StructType).Fields()
+		targetFields := target.(*arrow.StructType).Fields()
+		children := make([]arrow.ArrayData, len(t.Fields))
+		for i, cf := range t.Fields {
+			var child arrow.Array
+			var err error
+			if j := r.findField(srcFields, cf); j >= 0 {
+				child, err = r.conformArray(st.Field(j), cf, targetFields[i].Type)
+			} else {
+				child, err = expr.Repeat(nil, targetFields[i].Type, arr.Len(), mem)
+			}
+			if err != nil {
+				return nil, err
+			}
+			defer child.Release()
+			children[i] = child.Data()
+		}
+		return rebuild(target, data, data.Buffers()[:1], children), nil
+
+	case *schema.ArrayType:
+		list, ok := arr.(*array.List)
+		if !ok {
+			break
+		}
+		values, err := r.conformValue(list.ListValues(), name, t.ElementType, changes, joinFieldPath(fieldPath, "element"), target.(*arrow.ListType).Elem())
+		if err != nil {
+			return nil, err
+		}
+		defer values.Release()
+		return rebuild(target, data, data.Buffers()[:2], []arrow.ArrayData{values.Data()}), nil
+
+	case *schema.MapType:
+		m, ok := arr.(*array.Map)
+		if !ok {
+			break
+		}
+		mt := target.(*arrow.MapType)
+		keys, err := r.conformValue(m.Keys(), name, t.KeyType, changes, joinFieldPath(fieldPath, "key"), mt.KeyType())
+		if err != nil {
+			return nil, err
+		}
+		defer keys.Release()
+		items, err := r.conformValue(m.Items(), name, t.ValueType, changes, joinFieldPath(fieldPath, "value"), mt.ItemType())
+		if err != nil {
+			return nil, err
+		}
+		defer items.Release()
+
+		entries := array.NewData(mt.ValueType(), keys.Len(), []*memory.Buffer{nil}, []arrow.ArrayData{keys.Data(), items.Data()}, 0, 0)
+		defer entries.Release()
+		return rebuild(target, data, data.Buffers()[:2], []arrow.ArrayData{entries}), nil
+
+	default:
+		if arrow.TypeEqual(arr.DataType(), target) {
+			arr.Retain()
+			return arr, nil
+		}
+		converted, ok, err := convertPhysical(arr, target, mem)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", name, err)
+		}
+		if ok {
+			return converted, nil
+		}
+		widened, ok, err := widenLeaf(dt, changes, fieldPath, arr, target, mem)
+		if err != nil || ok {
+			return widened, err
+		}
+	}
+	return nil, fmt.Errorf("column %s is a %s in the data file, expected %s", name, arr.DataType().Name(), dt)
+}
+
+// rebuild returns an array of type dt with the buffers and children given, and the length
+// and nulls of data.
+func rebuild(dt arrow.DataType, data arrow.ArrayData, buffers []*memory.Buffer, children []arrow.ArrayData) arrow.Array {
+	d := array.NewData(dt, data.Len(), buffers, children, data.NullN(), data.Offset())
+	defer d.Release()
+	return array.MakeFromData(d)
+}
+
+// convertPhysical converts the representations parquet writers use for a Delta type to
+// the one of schema.ToArrowType: binary columns without a string annotation and
+// timestamps stored with another unit, such as INT96 nanoseconds. Timestamps are
+// converted between units directly, so that coarser units keep their full range; an
+// error is returned for values the target unit cannot hold.
+func convertPhysical(arr arrow.Array, target arrow.DataType, mem memory.Allocator) (arrow.Array, bool, error) {
+	data := arr.Data()
+	switch src := arr.DataType().(type) {
+	case *arrow.BinaryType:
+		if target.ID() != arrow.STRING {
+			return nil, false, nil
+		}
+		return rebuild(target, data, data.Buffers(), nil), true, nil
+	case *arrow.TimestampType:
+		tt, ok := target.(*arrow.TimestampType)
+		if !ok {
+			return nil, false, nil
+		}
+		from, to := int64(src.Unit.Multiplier()), int64(tt.Unit.Multiplier())
+		ts := arr.(*array.Timestamp)
+		b := array.NewTimestampBuilder(mem, tt)
+		defer b.Release()
+		b.Reserve(ts.Len())
+		for i := 0; i < ts.Len(); i++ {
+			if ts.IsNull(i) {
+				b.AppendNull()
+				continue
+			}
+			v := int64(ts.Value(i))
+			if from >= to {
+				f := from / to
+				if v > math.MaxInt64/f || v < math.MinInt64/f {
+					return nil, false, fmt.Errorf("timestamp %d in %s is out of range in %s", v, src.Unit, tt.Unit)
+				}
+				v *= f
+			} else {
+				// round towards the earlier instant, also before the epoch
+				d := to / from
+				q := v / d
+				if v%d < 0 {
+					q--
+				}
+				v = q
+			}
+			b.Append(arrow.Timestamp(v))
+		}
+		return b.NewArray(), true, nil
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,333 @@
+package delta
+
+import (
+	"context"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/apache/arrow/go/v8/parquet"
+	"github.com/apache/arrow/go/v8/parquet/pqarrow"
+	"github.com/delta-golang/delta-go/delta/expr"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+// scanAll scans the current snapshot of tbl and returns the number of records read and
+// the values of each column by name.
+func scanAll(t *testing.T, tbl *Table, opts ScanOptions) (int, map[string][]interface{}) {
+	t.Helper()
+	r, err := tbl.Snapshot().Scan(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Release()
+
+	records := 0
+	values := make(map[string][]interface{})
+	for r.Next() {
+		records++
+		rec := r.Record()
+		if opts.BatchSize > 0 && rec.NumRows() > opts.BatchSize {
+			t.Errorf("record has %d rows, expected at most %d", rec.NumRows(), opts.BatchSize)
+		}
+		for i, f := range rec.Schema().Fields() {
+			col := rec.Column(i)
+			for j := 0; j < col.Len(); j++ {
+				var v interface{}
+				if col.IsValid(j) {
+					switch c := col.(type) {
+					case *array.Int32:
+						v = c.Value(j)
+					case *array.Int64:
+						v = c.Value(j)
+					case *array.Float64:
+						v = c.Value(j)
+					case *array.String:
+						v = c.Value(j)
+					case *array.Date32:
+						v = c.Value(j).ToTime().Format("2006-01-02")
+					default:
+						t.Fatalf("unexpected column type %s", col.DataType())
+					}
+				}
+				values[f.Name] = append(values[f.Name], v)
+			}
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return records, values
+}
+
+func TestScan(t *testing.T) {
+	tbl, err := LoadTable("../tests/data/delta-0.8.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, values := scanAll(t, tbl, ScanOptions{})
+	got := values["value"]
+	sort.Slice(got, func(i, j int) bool { return got[i].(int32) < got[j].(int32) })
+	if want := []interface{}{int32(0), int32(1), int32(2), int32(4)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected values %v, got %v", want, got)
+	}
+
+	records, values := scanAll(t, tbl, ScanOptions{BatchSize: 1})
+	if records != 4 || len(values["value"]) != 4 {
+		t.Errorf("expected 4 records of 1 row, got %d records of %d rows", records, len(values["value"]))
+	}
+}
+
+func TestScanPartitionValues(t *testing.T) {
+	tbl, err := LoadTable("../tests/data/delta-0.8.0-null-partition")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, values := scanAll(t, tbl, ScanOptions{})
+	got := map[interface{}]interface{}{}
+	for i, k := range values["k"] {
+		got[k] = values["v"][i]
+	}
+	if want := map[interface{}]interface{}{"A": int64(1), nil: int64(2)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected rows %v, got %v", want, got)
+	}
+
+	tbl, err = LoadTable("../tests/data/delta-0.8.0-numeric-partition")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, values = scanAll(t, tbl, ScanOptions{})
+	if len(values["x"]) != 2 || len(values["y"]) != 2 || len(values["z"]) != 2 {
+		t.Fatalf("expected 2 rows, got %v", values)
+	}
+	for i, x := range values["x"] {
+		if x == int64(9) && values["y"][i] != 9.9 {
+			t.Errorf("expected y 9.9 for x 9, got %v", values["y"][i])
+		}
+	}
+}
+
+func TestScanSpecialPartitionValues(t *testing.T) {
+	tbl, err := LoadTable("../tests/data/delta-0.8.0-special-partition")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, values := scanAll(t, tbl, ScanOptions{})
+	got := values["x"]
+	sort.Slice(got, func(i, j int) bool { return got[i].(string) < got[j].(string) })
+	if want := []interface{}{"A/A", "B B"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected partition values %v, got %v", want, got)
+	}
+}
+
+func TestScanDates(t *testing.T) {
+	tbl, err := LoadTable("../tests/data/delta-0.8.0-date")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, values := scanAll(t, tbl, ScanOptions{})
+	if len(values["date"]) != 5 || values["date"][0] != "2021-01-01" || values["dayOfYear"][0] != int32(1) {
+		t.Errorf("unexpected rows %v", values)
+	}
+}
+
+func TestScanPartitionPruning(t *testing.T) {
+	tbl, err := LoadTable("../tests/data/delta-0.8.0-partitioned")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter string
+		files  int
+		pruned int
+	}{
+		{"year = 2021 AND month IN (4, 12)", 3, 3},
+		{"year = '2020' AND day > 2 OR month = '1'", 3, 3},
+		{"month = 2 AND value = 'x'", 2, 4},
+		{"value = 'x'", 6, 0},
+		{"day IS NULL", 0, 6},
+	}
+	for _, tt := range tests {
+		r, err := tbl.Snapshot().Scan(ScanOptions{Filter: expr.MustParse(tt.filter)})
+		if err != nil {
+			t.Errorf("%s: %s", tt.filter, err)
+			continue
+		}
+		if r.NumFiles() != tt.files || r.PrunedFiles() != tt.pruned {
+			t.Errorf("%s: expected %d files and %d pruned, got %d and %d", tt.filter, tt.files, tt.pruned, r.NumFiles(), r.PrunedFiles())
+		}
+		r.Release()
+	}
+
+	_, values := scanAll(t, tbl, ScanOptions{Filter: expr.MustParse("year = 2021 AND month IN (4, 12)")})
+	for i, y := range values["year"] {
+		if y != "2021" || values["month"][i] != "4" && values["month"][i] != "12" {
+			t.Errorf("unexpected row from partition year=%v/month=%v", y, values["month"][i])
+		}
+	}
+
+	if _, err := tbl.Snapshot().Scan(ScanOptions{Filter: expr.MustParse("nope = 1")}); err == nil {
+		t.Errorf("expected error for a filter on an unknown column")
+	}
+}
+
+func TestScanAddedColumn(t *testing.T) {
+	dir := t.TempDir()
+	copyDir(t, "../tests/data/delta-0.8.0", dir)
+	tbl, err := LoadTable(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tbl.AlterTable(context.Background(), AddColumn{Field: schema.StructField{Name: "extra", Type: schema.String, Nullable: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, values := scanAll(t, tbl, ScanOptions{})
+	if len(values["extra"]) != 4 {
+		t.Fatalf("expected 4 values of the added column, got %v", values["extra"])
+	}
+	for _, v := range values["extra"] {
+		if v != nil {
+			t.Errorf("expected NULL in the added column, got %v", v)
+		}
+	}
+}
+
+func TestScanNestedColumns(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[`+
+		`{"name":"a","type":"integer","nullable":true,"metadata":{}},`+
+		`{"name":"s","type":{"type":"struct","fields":[{"name":"f","type":"float","nullable":true,"metadata":{}}]},"nullable":true,"metadata":{}},`+
+		`{"name":"xs","type":{"type":"array","elementType":"integer","containsNull":true},"nullable":true,"metadata":{}},`+
+		`{"name":"m","type":{"type":"map","keyType":"string","valueType":"integer","valueContainsNull":true},"nullable":true,"metadata":{}}]}`, nil)
+	file := arrow.NewSchema([]arrow.Field{
+		{Name: "a", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "s", Type: arrow.StructOf(arrow.Field{Name: "f", Type: arrow.PrimitiveTypes.Float32, Nullable: true}), Nullable: true},
+		{Name: "xs", Type: arrow.ListOf(arrow.PrimitiveTypes.Int32), Nullable: true},
+		{Name: "m", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.PrimitiveTypes.Int32), Nullable: true},
+	}, nil)
+	data, _, err := array.RecordFromJSON(memory.DefaultAllocator, file, strings.NewReader(`[
+		{"a":1,"s":{"f":1.5},"xs":[2,3],"m":[{"key":"k","value":4}]},
+		{"a":2,"s":null,"xs":null,"m":null}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Release()
+	addTestDataFile(t, tbl, "part-0.parquet", data)
+
+	r, err := tbl.Snapshot().Scan(ScanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Release()
+	if !r.Next() {
+		t.Fatalf("expected a record, got %v", r.Err())
+	}
+	rec := r.Record()
+	if s := rec.Column(1).(*array.Struct); s.Field(0).(*array.Float32).Value(0) != 1.5 || !s.IsNull(1) {
+		t.Errorf("unexpected struct column %s", s)
+	}
+	if xs := rec.Column(2).(*array.List); !reflect.DeepEqual(xs.ListValues().(*array.Int32).Int32Values(), []int32{2, 3}) || !xs.IsNull(1) {
+		t.Errorf("unexpected array column %s", xs)
+	}
+	if m := rec.Column(3).(*array.Map); m.Items().(*array.Int32).Value(0) != 4 || !m.IsNull(1) {
+		t.Errorf("unexpected map column %s", m)
+	}
+}
+
+func TestConvertPhysicalTimestamps(t *testing.T) {
+	target := &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+	convert := func(unit arrow.TimeUnit, v int64) (int64, error) {
+		b := array.NewTimestampBuilder(memory.DefaultAllocator, &arrow.TimestampType{Unit: unit, TimeZone: "UTC"})
+		defer b.Release()
+		b.Append(arrow.Timestamp(v))
+		arr := b.NewArray()
+		defer arr.Release()
+
+		converted, ok, err := convertPhysical(arr, target, memory.DefaultAllocator)
+		if err != nil || !ok {
+			return 0, err
+		}
+		defer converted.Release()
+		return int64(converted.(*array.Timestamp).Value(0)), nil
+	}
+
+	// 3000-01-01 does not fit in nanoseconds, but does in microseconds
+	if v, err := convert(arrow.Millisecond, 32503680000000); err != nil || v != 32503680000000000 {
+		t.Errorf("expected 3000-01-01 in microseconds, got %d (%v)", v, err)
+	}
+	if v, err := convert(arrow.Nanosecond, -1500); err != nil || v != -2 {
+		t.Errorf("expected -1500ns to round down to -2us, got %d (%v)", v, err)
+	}
+	if _, err := convert(arrow.Second, math.MaxInt64/1000); err == nil {
+		t.Errorf("expected error for a timestamp out of range")
+	}
+}
+
+// addTestDataFile writes rec as the parquet data file name of tbl and commits it.
+func addTestDataFile(t *testing.T, tbl *Table, name string, rec arrow.Record) {
+	t.Helper()
+	path := filepath.Join(tbl.URI, name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	data := array.NewTableFromRecords(rec.Schema(), []arrow.Record{rec})
+	defer data.Release()
+	if err := pqarrow.WriteTable(data, f, rec.NumRows(), parquet.NewWriterProperties(), pqarrow.DefaultWriterProps()); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx, _ := tbl.NewTransaction()
+	tx.AddFiles(AddAction{Action: Action{Path: name, Size: info.Size(), DataChange: true}})
+	if _, err := tx.Commit(context.Background(), "WRITE"); err != nil {
+		t.Fatalf("error adding data file: %s", err)
+	}
+}
+
+func copyDir(t *testing.T, src, dst string) {
+	t.Helper()
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+		_, err = io.Copy(out, in)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
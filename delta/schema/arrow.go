@@ -0,0 +1,229 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/apache/arrow/go/v8/arrow"
+)
+
+// Arrow names the elements of lists and the entries of maps itself. Delta has no
+// names for them, so the names used by the Parquet and Arrow specifications are used.
+const (
+	arrowListElementName = "element"
+	arrowMapKeyName      = "key"
+	arrowMapValueName    = "value"
+)
+
+// VariantArrowType is the Arrow type of variant columns: the metadata and value binaries
+// of the variant encoding. Variant columns read back from Arrow are structs of this type.
+var VariantArrowType = arrow.StructOf(
+	arrow.Field{Name: "metadata", Type: arrow.BinaryTypes.Binary},
+	arrow.Field{Name: "value", Type: arrow.BinaryTypes.Binary},
+)
+
+// ToArrow converts a Delta schema to an Arrow schema.
+//
+// Timestamps are converted to microsecond timestamps, in UTC for timestamp columns and
+// without a time zone for timestamp_ntz columns. Field metadata is kept as Arrow field
+// metadata with every value encoded as JSON, so that a string such as "42" is not
+// mistaken for a number when converted back.
+func ToArrow(s *StructType) (*arrow.Schema, error) {
+	fields, err := toArrowFields(s)
+	if err != nil {
+		return nil, err
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// FromArrow converts an Arrow schema to a Delta schema. It is the inverse of ToArrow.
+//
+// Arrow field metadata values are decoded from JSON. Values that are not valid JSON, such
+// as metadata set by other Arrow producers, are kept as strings.
+func FromArrow(s *arrow.Schema) (*StructType, error) {
+	return fromArrowFields(s.Fields())
+}
+
+// ToArrowType converts a Delta data type to the Arrow data type used to represent it.
+func ToArrowType(t DataType) (arrow.DataType, error) {
+	switch t := t.(type) {
+	case PrimitiveType:
+		switch t {
+		case String:
+			return arrow.BinaryTypes.String, nil
+		case Long:
+			return arrow.PrimitiveTypes.Int64, nil
+		case Integer:
+			return arrow.PrimitiveTypes.Int32, nil
+		case Short:
+			return arrow.PrimitiveTypes.Int16, nil
+		case Byte:
+			return arrow.PrimitiveTypes.Int8, nil
+		case Float:
+			return arrow.PrimitiveTypes.Float32, nil
+		case Double:
+			return arrow.PrimitiveTypes.Float64, nil
+		case Boolean:
+			return arrow.FixedWidthTypes.Boolean, nil
+		case Binary:
+			return arrow.BinaryTypes.Binary, nil
+		case Date:
+			return arrow.FixedWidthTypes.Date32, nil
+		case Timestamp:
+			return &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}, nil
+		case TimestampNtz:
+			return &arrow.TimestampType{Unit: arrow.Microsecond}, nil
+		case Variant:
+			return VariantArrowType, nil
+		}
+	case DecimalType:
+		return &arrow.Decimal128Type{Precision: int32(t.Precision), Scale: int32(t.Scale)}, nil
+	case *ArrayType:
+		et, err := ToArrowType(t.ElementType)
+		if err != nil {
+			return nil, err
+		}
+		return arrow.ListOfField(arrow.Field{Name: arrowListElementName, Type: et, Nullable: t.ContainsNull}), nil
+	case *MapType:
+		kt, err := ToArrowType(t.KeyType)
+		if err != nil {
+			return nil, err
+		}
+		vt, err := ToArrowType(t.ValueType)
+		if err != nil {
+			return nil, err
+		}
+		m := arrow.MapOf(kt, vt)
+		m.SetItemNullable(t.ValueContainsNull)
+		return m, nil
+	case *StructType:
+		fields, err := toArrowFields(t)
+		if err != nil {
+			return nil, err
+		}
+		return arrow.StructOf(fields...), nil
+	}
+	return nil, &UnsupportedTypeError{Type: t.Name()}
+}
+
+// FromArrowType converts an Arrow data type to a Delta data type. Arrow types without an
+// exact Delta equivalent, such as unsigned integers, are rejected rather than widened.
+func FromArrowType(t arrow.DataType) (DataType, error) {
+	switch t := t.(type) {
+	case *arrow.StringType:
+		return String, nil
+	case *arrow.Int64Type:
+		return Long, nil
+	case *arrow.Int32Type:
+		return Integer, nil
+	case *arrow.Int16Type:
+		return Short, nil
+	case *arrow.Int8Type:
+		return Byte, nil
+	case *arrow.Float32Type:
+		return Float, nil
+	case *arrow.Float64Type:
+		return Double, nil
+	case *arrow.BooleanType:
+		return Boolean, nil
+	case *arrow.BinaryType, *arrow.FixedSizeBinaryType:
+		return Binary, nil
+	case *arrow.Date32Type, *arrow.Date64Type:
+		return Date, nil
+	case *arrow.TimestampType:
+		if t.TimeZone == "" {
+			return TimestampNtz, nil
+		}
+		return Timestamp, nil
+	case *arrow.Decimal128Type:
+		if t.Precision < 1 || t.Precision > MaxDecimalPrecision {
+			return nil, fmt.Errorf("invalid decimal precision %d", t.Precision)
+		}
+		return DecimalType{Precision: int(t.Precision), Scale: int(t.Scale)}, nil
+	case *arrow.ListType:
+		et, err := FromArrowType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &ArrayType{ElementType: et, ContainsNull: t.ElemField().Nullable}, nil
+	case *arrow.FixedSizeListType:
+		et, err := FromArrowType(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &ArrayType{ElementType: et, ContainsNull: t.ElemField().Nullable}, nil
+	case *arrow.MapType:
+		kt, err := FromArrowType(t.KeyType())
+		if err != nil {
+			return nil, err
+		}
+		vt, err := FromArrowType(t.ItemType())
+		if err != nil {
+			return nil, err
+		}
+		return &MapType{KeyType: kt, ValueType: vt, ValueContainsNull: t.ItemField().Nullable}, nil
+	case *arrow.StructType:
+		return fromArrowFields(t.Fields())
+	}
+	return nil, &UnsupportedTypeError{Type: t.Name()}
+}
+
+func toArrowFields(s *StructType) ([]arrow.Field, error) {
+	fields := make([]arrow.Field, len(s.Fields))
+	for i, f := range s.Fields {
+		t, err := ToArrowType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+
+		md, err := toArrowMetadata(f.Metadata)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		fields[i] = arrow.Field{Name: f.Name, Type: t, Nullable: f.Nullable, Metadata: md}
+	}
+	return fields, nil
+}
+
+func fromArrowFields(fields []arrow.Field) (*StructType, error) {
+	s := &StructType{Fields: make([]StructField, len(fields))}
+	for i, f := range fields {
+		t, err := FromArrowType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		s.Fields[i] = StructField{Name: f.Name, Type: t, Nullable: f.Nullable, Metadata: fromArrowMetadata(f.Metadata)}
+	}
+	return s, nil
+}
+
+func toArrowMetadata(md map[string]interface{}) (arrow.Metadata, error) {
+	if len(md) == 0 {
+		return arrow.Metadata{}, nil
+	}
+
+	kv := make(map[string]string, len(md))
+	for k, v := range md {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return arrow.Metadata{}, fmt.Errorf("metadata %s: %w", k, err)
+		}
+		kv[k] = string(b)
+	}
+	return arrow.MetadataFrom(kv), nil
+}
+
+func fromArrowMetadata(md arrow.Metadata) map[string]interface{} {
+	kv := make(map[string]interface{}, md.Len())
+	for i, k := range md.Keys() {
+		v := md.Values()[i]
+
+		var decoded interface{}
+		if json.Valid([]byte(v)) && unmarshalUseNumber([]byte(v), &decoded) == nil {
+			kv[k] = decoded
+			continue
+		}
+		kv[k] = v
+	}
+	return kv
+}
@@ -0,0 +1,103 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow/go/v8/arrow"
+)
+
+func TestArrowRoundTrip(t *testing.T) {
+	s := NewStructType(
+		StructField{Name: "id", Type: Long, Metadata: map[string]interface{}{
+			"delta.columnMapping.id":           json.Number("1"),
+			"delta.columnMapping.physicalName": "col-5f422f40",
+			"comment":                          "primary key",
+		}},
+		StructField{Name: "price", Type: DecimalType{Precision: 38, Scale: 18}, Nullable: true},
+		StructField{Name: "ts", Type: Timestamp, Nullable: true},
+		StructField{Name: "local_ts", Type: TimestampNtz, Nullable: true},
+		StructField{Name: "day", Type: Date, Nullable: true},
+		StructField{Name: "payload", Type: Binary, Nullable: true},
+		StructField{Name: "tags", Type: &ArrayType{ElementType: String}, Nullable: true},
+		StructField{Name: "attrs", Type: &MapType{
+			KeyType:           String,
+			ValueType:         NewStructType(StructField{Name: "n", Type: Integer, Nullable: true}),
+			ValueContainsNull: true,
+		}},
+		// string values that are valid JSON stay strings
+		StructField{Name: "code", Type: String, Nullable: true, Metadata: map[string]interface{}{
+			"comment": "42",
+			"flag":    "true",
+			"raw":     `{"a":1}`,
+		}},
+	)
+
+	as, err := ToArrow(s)
+	if err != nil {
+		t.Fatalf("error converting to arrow: %s", err)
+	}
+
+	expectedTypes := []arrow.DataType{
+		arrow.PrimitiveTypes.Int64,
+		&arrow.Decimal128Type{Precision: 38, Scale: 18},
+		&arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"},
+		&arrow.TimestampType{Unit: arrow.Microsecond},
+		arrow.FixedWidthTypes.Date32,
+		arrow.BinaryTypes.Binary,
+	}
+	for i, et := range expectedTypes {
+		if !arrow.TypeEqual(as.Field(i).Type, et) {
+			t.Errorf("field %s: expected %s, got %s", as.Field(i).Name, et, as.Field(i).Type)
+		}
+	}
+	if as.Field(6).Type.(*arrow.ListType).ElemField().Nullable {
+		t.Errorf("expected non-nullable list elements")
+	}
+	if !as.Field(7).Type.(*arrow.MapType).ItemField().Nullable {
+		t.Errorf("expected nullable map values")
+	}
+	md := as.Field(0).Metadata
+	if i := md.FindKey("delta.columnMapping.id"); i < 0 || md.Values()[i] != "1" {
+		t.Errorf("expected column mapping id metadata, got %s", md)
+	}
+
+	back, err := FromArrow(as)
+	if err != nil {
+		t.Fatalf("error converting from arrow: %s", err)
+	}
+
+	// fields without metadata come back with empty metadata
+	for i := range s.Fields {
+		if s.Fields[i].Metadata == nil {
+			s.Fields[i].Metadata = map[string]interface{}{}
+		}
+	}
+	s.Fields[7].Type.(*MapType).ValueType.(*StructType).Fields[0].Metadata = map[string]interface{}{}
+	if !reflect.DeepEqual(s, back) {
+		t.Errorf("expected %s, got %s", s, back)
+	}
+}
+
+func TestFromArrowUnsupported(t *testing.T) {
+	as := arrow.NewSchema([]arrow.Field{{Name: "u", Type: arrow.PrimitiveTypes.Uint32}}, nil)
+	if _, err := FromArrow(as); err == nil {
+		t.Errorf("expected error for unsigned integer column")
+	}
+}
+
+func TestVariantType(t *testing.T) {
+	s, err := Parse(`{"type":"struct","fields":[{"name":"v","type":"variant","nullable":true,"metadata":{}}]}`)
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+	if s.Fields[0].Type != Variant {
+		t.Fatalf("expected variant, got %s", s.Fields[0].Type)
+	}
+
+	at, err := ToArrowType(Variant)
+	if err != nil || !arrow.TypeEqual(at, VariantArrowType) {
+		t.Errorf("expected %s, got %s (%v)", VariantArrowType, at, err)
+	}
+}
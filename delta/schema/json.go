@@ -0,0 +1,218 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var decimalRe = regexp.MustCompile(`^decimal\(\s*(\d+)\s*,\s*(\d+)\s*\)$`)
+
+// UnsupportedTypeError is returned when the schema contains a type this package does not
+// know how to represent.
+type UnsupportedTypeError struct {
+	Type string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("unsupported data type %s", e.Type)
+}
+
+// Parse parses the schema JSON stored in Metadata.SchemaString.
+func Parse(schemaString string) (*StructType, error) {
+	var s StructType
+	err := json.Unmarshal([]byte(schemaString), &s)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Serialize returns the schema JSON suitable for Metadata.SchemaString.
+func Serialize(s *StructType) (string, error) {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+type jsonField struct {
+	Name     string                 `json:"name"`
+	Type     json.RawMessage        `json:"type"`
+	Nullable bool                   `json:"nullable"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+type jsonStruct struct {
+	Type   string      `json:"type"`
+	Fields []jsonField `json:"fields"`
+}
+
+type jsonArray struct {
+	Type         string          `json:"type"`
+	ElementType  json.RawMessage `json:"elementType"`
+	ContainsNull bool            `json:"containsNull"`
+}
+
+type jsonMap struct {
+	Type              string          `json:"type"`
+	KeyType           json.RawMessage `json:"keyType"`
+	ValueType         json.RawMessage `json:"valueType"`
+	ValueContainsNull bool            `json:"valueContainsNull"`
+}
+
+func (p PrimitiveType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(p))
+}
+
+func (d DecimalType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Name())
+}
+
+func (a *ArrayType) MarshalJSON() ([]byte, error) {
+	et, err := json.Marshal(a.ElementType)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonArray{Type: "array", ElementType: et, ContainsNull: a.ContainsNull})
+}
+
+func (m *MapType) MarshalJSON() ([]byte, error) {
+	kt, err := json.Marshal(m.KeyType)
+	if err != nil {
+		return nil, err
+	}
+	vt, err := json.Marshal(m.ValueType)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonMap{Type: "map", KeyType: kt, ValueType: vt, ValueContainsNull: m.ValueContainsNull})
+}
+
+func (s *StructType) MarshalJSON() ([]byte, error) {
+	fields := make([]jsonField, len(s.Fields))
+	for i, f := range s.Fields {
+		t, err := json.Marshal(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+
+		md := f.Metadata
+		if md == nil {
+			md = map[string]interface{}{}
+		}
+		fields[i] = jsonField{Name: f.Name, Type: t, Nullable: f.Nullable, Metadata: md}
+	}
+	return json.Marshal(jsonStruct{Type: "struct", Fields: fields})
+}
+
+func (s *StructType) UnmarshalJSON(b []byte) error {
+	dt, err := unmarshalType(b)
+	if err != nil {
+		return err
+	}
+
+	st, ok := dt.(*StructType)
+	if !ok {
+		return fmt.Errorf("expected struct type, got %s", dt.Name())
+	}
+	*s = *st
+	return nil
+}
+
+func unmarshalType(b []byte) (DataType, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '"' {
+		var name string
+		if err := json.Unmarshal(b, &name); err != nil {
+			return nil, err
+		}
+		return ParseTypeName(name)
+	}
+
+	var head struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(b, &head); err != nil {
+		return nil, err
+	}
+
+	switch head.Type {
+	case "struct":
+		var js jsonStruct
+		if err := unmarshalUseNumber(b, &js); err != nil {
+			return nil, err
+		}
+
+		st := &StructType{Fields: make([]StructField, len(js.Fields))}
+		for i, f := range js.Fields {
+			t, err := unmarshalType(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", f.Name, err)
+			}
+			st.Fields[i] = StructField{Name: f.Name, Type: t, Nullable: f.Nullable, Metadata: f.Metadata}
+		}
+		return st, nil
+	case "array":
+		var ja jsonArray
+		if err := json.Unmarshal(b, &ja); err != nil {
+			return nil, err
+		}
+
+		et, err := unmarshalType(ja.ElementType)
+		if err != nil {
+			return nil, err
+		}
+		return &ArrayType{ElementType: et, ContainsNull: ja.ContainsNull}, nil
+	case "map":
+		var jm jsonMap
+		if err := json.Unmarshal(b, &jm); err != nil {
+			return nil, err
+		}
+
+		kt, err := unmarshalType(jm.KeyType)
+		if err != nil {
+			return nil, err
+		}
+		vt, err := unmarshalType(jm.ValueType)
+		if err != nil {
+			return nil, err
+		}
+		return &MapType{KeyType: kt, ValueType: vt, ValueContainsNull: jm.ValueContainsNull}, nil
+	default:
+		return nil, &UnsupportedTypeError{Type: head.Type}
+	}
+}
+
+// ParseTypeName parses the name of a primitive or decimal type, such as long or
+// decimal(10,2).
+func ParseTypeName(name string) (DataType, error) {
+	if p, ok := primitiveTypes[name]; ok {
+		return p, nil
+	}
+
+	if name == "decimal" {
+		// Spark's default decimal
+		return DecimalType{Precision: 10, Scale: 0}, nil
+	}
+
+	if m := decimalRe.FindStringSubmatch(name); m != nil {
+		precision, _ := strconv.Atoi(m[1])
+		scale, _ := strconv.Atoi(m[2])
+		if precision < 1 || precision > MaxDecimalPrecision || scale > precision {
+			return nil, fmt.Errorf("invalid decimal type %s", name)
+		}
+		return DecimalType{Precision: precision, Scale: scale}, nil
+	}
+
+	return nil, &UnsupportedTypeError{Type: name}
+}
+
+func unmarshalUseNumber(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,117 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Merge merges the schema of data being written into the current table schema.
+//
+// Fields of update that are missing from current are appended, at any depth of nested
+// structs, including structs inside arrays and maps. New fields are always nullable since
+// existing data files do not contain them. Fields that exist in both schemas keep the name,
+// nullability and metadata of current, and must have the same type. Fields are matched
+// case-insensitively. Neither argument is modified.
+func Merge(current, update *StructType) (*StructType, error) {
+	if err := checkDuplicateNames(update); err != nil {
+		return nil, err
+	}
+	return mergeStruct(current, update, "")
+}
+
+func mergeStruct(current, update *StructType, prefix string) (*StructType, error) {
+	merged := &StructType{Fields: make([]StructField, len(current.Fields), len(current.Fields)+len(update.Fields))}
+	copy(merged.Fields, current.Fields)
+
+	for _, uf := range update.Fields {
+		path := prefix + uf.Name
+		i := current.FieldIndex(uf.Name)
+		if i < 0 {
+			merged.Fields = append(merged.Fields, asNullable(uf))
+			continue
+		}
+
+		t, err := mergeType(current.Fields[i].Type, uf.Type, path)
+		if err != nil {
+			return nil, err
+		}
+		merged.Fields[i].Type = t
+	}
+	return merged, nil
+}
+
+func mergeType(current, update DataType, path string) (DataType, error) {
+	switch c := current.(type) {
+	case *StructType:
+		if u, ok := update.(*StructType); ok {
+			return mergeStruct(c, u, path+".")
+		}
+	case *ArrayType:
+		if u, ok := update.(*ArrayType); ok {
+			et, err := mergeType(c.ElementType, u.ElementType, path+".element")
+			if err != nil {
+				return nil, err
+			}
+			return &ArrayType{ElementType: et, ContainsNull: c.ContainsNull}, nil
+		}
+	case *MapType:
+		if u, ok := update.(*MapType); ok {
+			kt, err := mergeType(c.KeyType, u.KeyType, path+".key")
+			if err != nil {
+				return nil, err
+			}
+			vt, err := mergeType(c.ValueType, u.ValueType, path+".value")
+			if err != nil {
+				return nil, err
+			}
+			return &MapType{KeyType: kt, ValueType: vt, ValueContainsNull: c.ValueContainsNull}, nil
+		}
+	default:
+		if current == update {
+			return current, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot merge field %s: type %s is incompatible with %s", path, update, current)
+}
+
+// asNullable returns a copy of f where the field and all nested fields are nullable.
+func asNullable(f StructField) StructField {
+	f.Nullable = true
+	f.Type = typeAsNullable(f.Type)
+	return f
+}
+
+func typeAsNullable(t DataType) DataType {
+	switch t := t.(type) {
+	case *StructType:
+		s := &StructType{Fields: make([]StructField, len(t.Fields))}
+		for i, f := range t.Fields {
+			s.Fields[i] = asNullable(f)
+		}
+		return s
+	case *ArrayType:
+		return &ArrayType{ElementType: typeAsNullable(t.ElementType), ContainsNull: true}
+	case *MapType:
+		return &MapType{KeyType: typeAsNullable(t.KeyType), ValueType: typeAsNullable(t.ValueType), ValueContainsNull: true}
+	default:
+		return t
+	}
+}
+
+func checkDuplicateNames(s *StructType) error {
+	seen := make(map[string]bool, len(s.Fields))
+	for _, f := range s.Fields {
+		name := strings.ToLower(f.Name)
+		if seen[name] {
+			return fmt.Errorf("duplicate field %s", f.Name)
+		}
+		seen[name] = true
+
+		if nested, ok := f.Type.(*StructType); ok {
+			if err := checkDuplicateNames(nested); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	comment := map[string]interface{}{"comment": "event id"}
+	current := NewStructType(
+		StructField{Name: "id", Type: Long, Metadata: comment},
+		StructField{Name: "payload", Type: NewStructType(
+			StructField{Name: "a", Type: String, Nullable: true},
+		), Nullable: true},
+		StructField{Name: "items", Type: &ArrayType{ElementType: NewStructType(
+			StructField{Name: "x", Type: Integer, Nullable: true},
+		)}, Nullable: true},
+	)
+	update := NewStructType(
+		StructField{Name: "ID", Type: Long, Nullable: true},
+		StructField{Name: "payload", Type: NewStructType(
+			StructField{Name: "b", Type: &MapType{KeyType: String, ValueType: Double}},
+		)},
+		StructField{Name: "items", Type: &ArrayType{ElementType: NewStructType(
+			StructField{Name: "y", Type: Date},
+		)}},
+		StructField{Name: "source", Type: String},
+	)
+
+	merged, err := Merge(current, update)
+	if err != nil {
+		t.Fatalf("error merging schemas: %s", err)
+	}
+
+	expected := NewStructType(
+		StructField{Name: "id", Type: Long, Metadata: comment},
+		StructField{Name: "payload", Type: NewStructType(
+			StructField{Name: "a", Type: String, Nullable: true},
+			StructField{Name: "b", Type: &MapType{KeyType: String, ValueType: Double, ValueContainsNull: true}, Nullable: true},
+		), Nullable: true},
+		StructField{Name: "items", Type: &ArrayType{ElementType: NewStructType(
+			StructField{Name: "x", Type: Integer, Nullable: true},
+			StructField{Name: "y", Type: Date, Nullable: true},
+		)}, Nullable: true},
+		StructField{Name: "source", Type: String, Nullable: true},
+	)
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected %s, got %s", expected, merged)
+	}
+	if len(current.Fields) != 3 || len(current.Fields[1].Type.(*StructType).Fields) != 1 {
+		t.Errorf("merge modified the current schema")
+	}
+}
+
+func TestMergeIncompatible(t *testing.T) {
+	current := NewStructType(StructField{Name: "n", Type: Integer})
+	tests := []*StructType{
+		NewStructType(StructField{Name: "n", Type: String}),
+		NewStructType(StructField{Name: "n", Type: NewStructType()}),
+		NewStructType(StructField{Name: "m", Type: Long}, StructField{Name: "M", Type: Long}),
+	}
+
+	for _, tt := range tests {
+		if _, err := Merge(current, tt); err == nil {
+			t.Errorf("%s: expected error", tt)
+		}
+	}
+}
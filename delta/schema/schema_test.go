@@ -0,0 +1,84 @@
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const nestedSchema = `{"type":"struct","fields":[` +
+	`{"name":"id","type":"long","nullable":false,"metadata":{"delta.columnMapping.id":1,"comment":"primary key"}},` +
+	`{"name":"price","type":"decimal(10,2)","nullable":true,"metadata":{}},` +
+	`{"name":"tags","type":{"type":"array","elementType":"string","containsNull":true},"nullable":true,"metadata":{}},` +
+	`{"name":"attrs","type":{"type":"map","keyType":"string","valueType":{"type":"struct","fields":[` +
+	`{"name":"d","type":"date","nullable":true,"metadata":{}}]},"valueContainsNull":false},"nullable":true,"metadata":{}}]}`
+
+func TestParse(t *testing.T) {
+	s, err := Parse(nestedSchema)
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+
+	expected := NewStructType(
+		StructField{Name: "id", Type: Long, Metadata: map[string]interface{}{
+			"delta.columnMapping.id": json.Number("1"),
+			"comment":                "primary key",
+		}},
+		StructField{Name: "price", Type: DecimalType{Precision: 10, Scale: 2}, Nullable: true, Metadata: map[string]interface{}{}},
+		StructField{Name: "tags", Type: &ArrayType{ElementType: String, ContainsNull: true}, Nullable: true, Metadata: map[string]interface{}{}},
+		StructField{Name: "attrs", Type: &MapType{
+			KeyType: String,
+			ValueType: NewStructType(
+				StructField{Name: "d", Type: Date, Nullable: true, Metadata: map[string]interface{}{}},
+			),
+		}, Nullable: true, Metadata: map[string]interface{}{}},
+	)
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("expected %s, got %s", expected, s)
+	}
+
+	if f, ok := s.Field("PRICE"); !ok || f.Name != "price" {
+		t.Errorf("expected case-insensitive field lookup, got %v (%t)", f, ok)
+	}
+}
+
+func TestSerializeRoundTrip(t *testing.T) {
+	s, err := Parse(nestedSchema)
+	if err != nil {
+		t.Fatalf("error parsing schema: %s", err)
+	}
+
+	out, err := Serialize(s)
+	if err != nil {
+		t.Fatalf("error serializing schema: %s", err)
+	}
+
+	var expected, actual interface{}
+	_ = json.Unmarshal([]byte(nestedSchema), &expected)
+	_ = json.Unmarshal([]byte(out), &actual)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %s, got %s", nestedSchema, out)
+	}
+}
+
+func TestParseInvalidTypes(t *testing.T) {
+	tests := []string{
+		`{"type":"struct","fields":[{"name":"a","type":"interval","nullable":true,"metadata":{}}]}`,
+		`{"type":"struct","fields":[{"name":"a","type":"decimal(39,2)","nullable":true,"metadata":{}}]}`,
+		`{"type":"struct","fields":[{"name":"a","type":"decimal(2,3)","nullable":true,"metadata":{}}]}`,
+		`{"type":"array","elementType":"string","containsNull":true}`,
+	}
+
+	for _, tt := range tests {
+		if _, err := Parse(tt); err == nil {
+			t.Errorf("%s: expected error", tt)
+		}
+	}
+
+	_, err := Parse(tests[0])
+	var unsupported *UnsupportedTypeError
+	if !errors.As(err, &unsupported) || unsupported.Type != "interval" {
+		t.Errorf("expected UnsupportedTypeError, got %v", err)
+	}
+}
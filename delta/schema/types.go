@@ -0,0 +1,185 @@
+// Package schema models the Delta table schema that is stored as JSON in
+// Metadata.SchemaString.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DataType is implemented by every Delta data type.
+type DataType interface {
+	// Name returns the name of the type as written in the schema JSON, such as "long"
+	// or "decimal(10,2)". Nested types return "struct", "array" or "map".
+	Name() string
+	String() string
+}
+
+// PrimitiveType is a type without type parameters.
+type PrimitiveType string
+
+const (
+	String    PrimitiveType = "string"
+	Long      PrimitiveType = "long"
+	Integer   PrimitiveType = "integer"
+	Short     PrimitiveType = "short"
+	Byte      PrimitiveType = "byte"
+	Float     PrimitiveType = "float"
+	Double    PrimitiveType = "double"
+	Boolean   PrimitiveType = "boolean"
+	Binary    PrimitiveType = "binary"
+	Date      PrimitiveType = "date"
+	Timestamp PrimitiveType = "timestamp"
+	// TimestampNtz is a timestamp without a time zone.
+	TimestampNtz PrimitiveType = "timestamp_ntz"
+	// Variant holds semi-structured values in the variant binary encoding.
+	Variant PrimitiveType = "variant"
+)
+
+var primitiveTypes = map[string]PrimitiveType{
+	string(String):       String,
+	string(Long):         Long,
+	string(Integer):      Integer,
+	string(Short):        Short,
+	string(Byte):         Byte,
+	string(Float):        Float,
+	string(Double):       Double,
+	string(Boolean):      Boolean,
+	string(Binary):       Binary,
+	string(Date):         Date,
+	string(Timestamp):    Timestamp,
+	string(TimestampNtz): TimestampNtz,
+	string(Variant):      Variant,
+}
+
+func (p PrimitiveType) Name() string   { return string(p) }
+func (p PrimitiveType) String() string { return string(p) }
+
+// DecimalType is a fixed precision decimal number.
+type DecimalType struct {
+	Precision int
+	Scale     int
+}
+
+// MaxDecimalPrecision is the largest precision a decimal column can have.
+const MaxDecimalPrecision = 38
+
+func (d DecimalType) Name() string   { return fmt.Sprintf("decimal(%d,%d)", d.Precision, d.Scale) }
+func (d DecimalType) String() string { return d.Name() }
+
+// ArrayType is a list of elements of the same type.
+type ArrayType struct {
+	ElementType  DataType
+	ContainsNull bool
+}
+
+func (a *ArrayType) Name() string { return "array" }
+func (a *ArrayType) String() string {
+	return fmt.Sprintf("array<%s>", a.ElementType)
+}
+
+// MapType maps keys of one type to values of another. Keys are never null.
+type MapType struct {
+	KeyType           DataType
+	ValueType         DataType
+	ValueContainsNull bool
+}
+
+func (m *MapType) Name() string { return "map" }
+func (m *MapType) String() string {
+	return fmt.Sprintf("map<%s,%s>", m.KeyType, m.ValueType)
+}
+
+// StructField is a named field of a struct.
+type StructField struct {
+	Name     string
+	Type     DataType
+	Nullable bool
+	// Metadata holds the field metadata, such as comments or column mapping information.
+	// Numbers are kept as json.Number so they are written back unchanged.
+	Metadata map[string]interface{}
+}
+
+// StructType is an ordered list of fields. The schema of a table is a StructType.
+type StructType struct {
+	Fields []StructField
+}
+
+// NewStructType returns a struct with the given fields.
+func NewStructType(fields ...StructField) *StructType {
+	return &StructType{Fields: fields}
+}
+
+func (s *StructType) Name() string { return "struct" }
+func (s *StructType) String() string {
+	fields := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		fields[i] = fmt.Sprintf("%s:%s", f.Name, f.Type)
+	}
+	return fmt.Sprintf("struct<%s>", strings.Join(fields, ","))
+}
+
+// Field returns the field with the given name. Names are matched case-insensitively, as
+// Delta column names are case-insensitive.
+func (s *StructType) Field(name string) (StructField, bool) {
+	i := s.FieldIndex(name)
+	if i < 0 {
+		return StructField{}, false
+	}
+	return s.Fields[i], true
+}
+
+// FieldIndex returns the position of the field with the given name, or -1.
+func (s *StructType) FieldIndex(name string) int {
+	for i, f := range s.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// FieldNames returns the names of the top-level fields in order.
+func (s *StructType) FieldNames() []string {
+	names := make([]string, len(s.Fields))
+	for i, f := range s.Fields {
+		names[i] = f.Name
+	}
+	return names
+}
+
+// Field metadata keys used by column mapping.
+const (
+	ColumnMappingIDKey           = "delta.columnMapping.id"
+	ColumnMappingPhysicalNameKey = "delta.columnMapping.physicalName"
+)
+
+// ColumnMappingID returns the column mapping id of the field, if it has one.
+func (f StructField) ColumnMappingID() (int64, bool) {
+	return f.MetadataInt(ColumnMappingIDKey)
+}
+
+// PhysicalName returns the name the field has in data files when column mapping is
+// enabled, if the field has one.
+func (f StructField) PhysicalName() (string, bool) {
+	name, ok := f.Metadata[ColumnMappingPhysicalNameKey].(string)
+	return name, ok && name != ""
+}
+
+// MetadataInt returns the metadata value for key as an integer, if it is one.
+func (f StructField) MetadataInt(key string) (int64, bool) {
+	switch v := f.Metadata[key].(type) {
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	case float64:
+		return int64(v), v == float64(int64(v))
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,119 @@
+package delta
+
+import (
+	"fmt"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+// SchemaMode controls how a write handles data whose schema differs from the table schema.
+type SchemaMode int
+
+const (
+	// SchemaModeStrict rejects data with columns the table does not have.
+	SchemaModeStrict SchemaMode = iota
+	// MergeSchema adds new columns of the data to the table schema, including new fields
+	// of nested structs. New columns are nullable.
+	MergeSchema
+	// OverwriteSchema replaces the table schema with the schema of the data. It is only
+	// valid for writes that replace all the data of the table.
+	OverwriteSchema
+)
+
+// EvolveSchema stages the metaData action a write of data with the given schema must
+// commit under mode. Nothing is staged if the table schema does not change. A
+// *SchemaMismatchError is returned if the data cannot be written under mode.
+//
+// With column mapping enabled, new columns are assigned column mapping ids and physical
+// names, and the data files have to be written with the physical schema of the staged
+// metadata. Under OverwriteSchema every column is assigned a new id and physical name,
+// and Commit fails unless the transaction removes every file of its snapshot.
+func (tx *Transaction) EvolveSchema(data *schema.StructType, mode SchemaMode) error {
+	a, err := tx.newAlteration()
+	if err != nil {
+		return err
+	}
+	current := a.schema
+
+	// column mapping metadata of the data does not belong to this table
+	data = cloneType(data).(*schema.StructType)
+	stripColumnMapping(data)
+
+	var next, compared *schema.StructType
+	switch mode {
+	case SchemaModeStrict, MergeSchema:
+		next, err = schema.Merge(current, data)
+		if err != nil {
+			return &SchemaMismatchError{Reason: err.Error()}
+		}
+		compared = current
+	case OverwriteSchema:
+		next = data
+		for _, c := range a.md.PartitionColumns {
+			if _, ok := data.Field(c); !ok {
+				return &SchemaMismatchError{Reason: fmt.Sprintf("partition column %s is missing", c)}
+			}
+		}
+		compared = cloneType(current).(*schema.StructType)
+		stripColumnMapping(compared)
+	default:
+		return fmt.Errorf("unknown schema mode %d", mode)
+	}
+
+	comparedString, err := schema.Serialize(compared)
+	if err != nil {
+		return err
+	}
+	nextString, err := schema.Serialize(next)
+	if err != nil {
+		return err
+	}
+	if nextString == comparedString {
+		return nil
+	}
+	if mode == SchemaModeStrict {
+		return &SchemaMismatchError{Reason: "data has columns that are not in the table schema, use MergeSchema to add them"}
+	}
+
+	if a.mode != ColumnMappingNone {
+		a.assignMissingColumnMapping(next)
+	}
+	a.schema = next
+	if err := tx.stageAlteration(a); err != nil {
+		return err
+	}
+	tx.overwriteSchema = tx.overwriteSchema || mode == OverwriteSchema
+	return nil
+}
+
+// assignMissingColumnMapping assigns column mapping ids and physical names to the fields
+// of s, at any depth, that do not have one yet.
+func (a *alteration) assignMissingColumnMapping(s *schema.StructType) {
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		if _, ok := f.ColumnMappingID(); !ok {
+			a.assignColumnMapping(f, false)
+			continue
+		}
+		for _, nested := range nestedStructs(f.Type) {
+			a.assignMissingColumnMapping(nested)
+		}
+	}
+}
+
+// stripColumnMapping removes the column mapping metadata of the fields of s, at any depth.
+func stripColumnMapping(s *schema.StructType) {
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		_, hasID := f.Metadata[schema.ColumnMappingIDKey]
+		_, hasName := f.Metadata[schema.ColumnMappingPhysicalNameKey]
+		if hasID || hasName {
+			f.Metadata = cloneFieldMetadata(f.Metadata)
+			delete(f.Metadata, schema.ColumnMappingIDKey)
+			delete(f.Metadata, schema.ColumnMappingPhysicalNameKey)
+		}
+		for _, nested := range nestedStructs(f.Type) {
+			stripColumnMapping(nested)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package delta
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+func TestEvolveSchema(t *testing.T) {
+	tbl, err := LoadTable("../tests/data/delta-0.8.0-partitioned")
+	if err != nil {
+		t.Fatalf("error loading table: %s", err)
+	}
+	s := tbl.Snapshot()
+	current, _ := s.Schema()
+
+	// same columns in a different order do not change the schema
+	data := schema.NewStructType(current.Fields[3], current.Fields[0])
+	for _, mode := range []SchemaMode{SchemaModeStrict, MergeSchema} {
+		tx, _ := tbl.NewTransaction()
+		if err := tx.EvolveSchema(data, mode); err != nil || tx.metadata != nil {
+			t.Errorf("mode %d: expected no schema change, got %v (%v)", mode, tx.metadata, err)
+		}
+	}
+
+	data = schema.NewStructType(append(current.Fields, schema.StructField{Name: "source", Type: schema.String})...)
+	tx, _ := tbl.NewTransaction()
+	err = tx.EvolveSchema(data, SchemaModeStrict)
+	var mismatch *SchemaMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("expected SchemaMismatchError, got %v", err)
+	}
+
+	if err := tx.EvolveSchema(data, MergeSchema); err != nil || tx.metadata == nil {
+		t.Fatalf("expected new metadata, got %v (%v)", tx.metadata, err)
+	}
+	md := tx.Metadata()
+	merged, err := schema.Parse(md.SchemaString)
+	if err != nil {
+		t.Fatalf("error parsing merged schema: %s", err)
+	}
+	if f, ok := merged.Field("source"); !ok || !f.Nullable {
+		t.Errorf("expected nullable source column, got %v (%t)", f, ok)
+	}
+	if md.ID != s.Metadata().ID || len(md.PartitionColumns) != 3 {
+		t.Errorf("expected metadata to keep id and partition columns, got %v", md)
+	}
+	if s.Metadata().SchemaString == md.SchemaString {
+		t.Errorf("snapshot metadata was modified")
+	}
+
+	tx, _ = tbl.NewTransaction()
+	if err := tx.EvolveSchema(schema.NewStructType(current.Fields[1:]...), OverwriteSchema); err != nil || tx.metadata == nil {
+		t.Fatalf("expected new metadata, got %v (%v)", tx.metadata, err)
+	}
+	if err := tx.validate(); !errors.As(err, &mismatch) {
+		t.Errorf("expected SchemaMismatchError for an overwrite that keeps files, got %v", err)
+	}
+	for _, f := range s.Files() {
+		tx.RemoveFiles(RemoveAction{Action: Action{Path: f.Path, DataChange: true}})
+	}
+	if err := tx.validate(); err != nil {
+		t.Errorf("expected an overwrite that removes every file to be valid, got %v", err)
+	}
+
+	tx, _ = tbl.NewTransaction()
+	err = tx.EvolveSchema(schema.NewStructType(current.Fields[0]), OverwriteSchema)
+	if !errors.As(err, &mismatch) {
+		t.Errorf("expected SchemaMismatchError for missing partition column, got %v", err)
+	}
+}
+
+func TestEvolveSchemaColumnMapping(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[`+
+		`{"name":"a","type":"long","nullable":true,"metadata":{"delta.columnMapping.id":1,"delta.columnMapping.physicalName":"col-a"}},`+
+		`{"name":"s","type":{"type":"struct","fields":[{"name":"x","type":"integer","nullable":true,"metadata":{"delta.columnMapping.id":2,"delta.columnMapping.physicalName":"col-x"}}]},"nullable":true,"metadata":{"delta.columnMapping.id":3,"delta.columnMapping.physicalName":"col-s"}}]}`,
+		map[string]string{ColumnMappingModeKey: "name", ColumnMappingMaxIDKey: "5"})
+	ctx := context.Background()
+
+	// the data does not carry column mapping metadata
+	data := schema.NewStructType(
+		schema.StructField{Name: "a", Type: schema.Long, Nullable: true},
+		schema.StructField{Name: "s", Type: schema.NewStructType(
+			schema.StructField{Name: "x", Type: schema.Integer, Nullable: true},
+			schema.StructField{Name: "y", Type: schema.String, Nullable: true},
+		), Nullable: true},
+		schema.StructField{Name: "b", Type: schema.String, Nullable: true},
+	)
+	tx, _ := tbl.NewTransaction()
+	if err := tx.EvolveSchema(data, MergeSchema); err != nil {
+		t.Fatalf("error merging schema: %s", err)
+	}
+	if _, err := tx.Commit(ctx, "WRITE"); err != nil {
+		t.Fatalf("error committing: %s", err)
+	}
+
+	m, err := tbl.Snapshot().ColumnMapping()
+	if err != nil {
+		t.Fatalf("error reading column mapping: %s", err)
+	}
+	if path, _ := m.PhysicalPath("s", "x"); strings.Join(path, ".") != "col-s.col-x" {
+		t.Errorf("expected existing columns to keep their physical names, got %v", path)
+	}
+	sch, _ := tbl.Snapshot().Schema()
+	b, _ := sch.Field("b")
+	if id, _ := b.ColumnMappingID(); id != 7 {
+		t.Errorf("expected new column to get id 7, got %d", id)
+	}
+	s, _ := sch.Field("s")
+	y, _ := s.Type.(*schema.StructType).Field("y")
+	if id, _ := y.ColumnMappingID(); id != 6 {
+		t.Errorf("expected new nested field to get id 6, got %d", id)
+	}
+	if got := tbl.Snapshot().Metadata().Configuration[ColumnMappingMaxIDKey]; got != "7" {
+		t.Errorf("expected max column id 7, got %s", got)
+	}
+
+	// an overwrite assigns new ids above the previous maximum
+	tx, _ = tbl.NewTransaction()
+	data = schema.NewStructType(schema.StructField{Name: "c", Type: schema.Double, Nullable: true})
+	if err := tx.EvolveSchema(data, OverwriteSchema); err != nil {
+		t.Fatalf("error overwriting schema: %s", err)
+	}
+	if _, err := tx.Commit(ctx, "WRITE"); err != nil {
+		t.Fatalf("error committing: %s", err)
+	}
+	sch, _ = tbl.Snapshot().Schema()
+	if id, _ := sch.Fields[0].ColumnMappingID(); len(sch.Fields) != 1 || id != 8 {
+		t.Errorf("expected a single column with id 8, got %s", sch)
+	}
+	if _, err := tbl.Snapshot().ColumnMapping(); err != nil {
+		t.Errorf("error reading column mapping: %s", err)
+	}
+}
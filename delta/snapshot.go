@@ -0,0 +1,105 @@
+package delta
+
+import (
+	"errors"
+
+	"github.com/delta-golang/delta-go/delta/schema"
+	"github.com/delta-golang/delta-go/delta/storage"
+)
+
+// Snapshot is an immutable view of a table at a single version. A snapshot is never
+// modified once it has been built, so it can be shared between goroutines without
+// synchronization. Slices and maps returned by its accessors are shared with the
+// snapshot and must not be modified by callers.
+type Snapshot struct {
+	// storage is the backend of the table, used to read data files
+	storage storage.Backend
+
+	version   int64
+	timestamp int64
+	state     TableState
+
+	config    TableConfig
+	configErr error
+
+	schema    *schema.StructType
+	schemaErr error
+}
+
+// applyMetadata parses the schema and configuration of the replayed metadata and fills in
+// the retention settings of the state. It is called once, before the snapshot is published.
+func (s *Snapshot) applyMetadata() {
+	if s.state.CurrentMetadata.SchemaString == "" {
+		s.schema, s.schemaErr = nil, errors.New("table metadata has no schema")
+	} else {
+		s.schema, s.schemaErr = schema.Parse(s.state.CurrentMetadata.SchemaString)
+	}
+
+	s.config, s.configErr = ParseTableConfig(s.state.CurrentMetadata.Configuration)
+	s.state.TombstoneRetentionMillis = s.config.DeletedFileRetention.Milliseconds()
+	s.state.LogRetentionMillis = s.config.LogRetention.Milliseconds()
+	s.state.EnableExpiredLogCleanup = s.config.EnableExpiredLogCleanup
+}
+
+// Version returns the table version the snapshot was built at.
+func (s *Snapshot) Version() int64 {
+	return s.version
+}
+
+// VersionTimestamp returns the commit timestamp of the snapshot version in milliseconds
+// since the epoch.
+func (s *Snapshot) VersionTimestamp() int64 {
+	return s.timestamp
+}
+
+// Metadata returns the table metadata in effect at the snapshot version.
+func (s *Snapshot) Metadata() Metadata {
+	return s.state.CurrentMetadata
+}
+
+// Protocol returns the reader and writer protocol versions and the table features
+// required by the table.
+func (s *Snapshot) Protocol() Protocol {
+	return Protocol{
+		MinReaderVersion: s.state.MinReaderVersion,
+		MinWriterVersion: s.state.MinWriterVersion,
+		ReaderFeatures:   s.state.ReaderFeatures,
+		WriterFeatures:   s.state.WriterFeatures,
+	}
+}
+
+// Config returns the typed configuration of the table. If a property has an invalid
+// value, an *InvalidConfigError is returned along with the configuration parsed so far.
+func (s *Snapshot) Config() (TableConfig, error) {
+	return s.config, s.configErr
+}
+
+// Schema returns the schema of the table at the snapshot version. The returned schema
+// is shared with the snapshot and must not be modified.
+func (s *Snapshot) Schema() (*schema.StructType, error) {
+	return s.schema, s.schemaErr
+}
+
+// Files returns the data files that are part of the table at the snapshot version.
+func (s *Snapshot) Files() []AddAction {
+	return s.state.Files
+}
+
+// Tombstones returns the files removed from the table that have not been added back,
+// keyed by path.
+func (s *Snapshot) Tombstones() map[string]RemoveAction {
+	return s.state.Tombstones
+}
+
+// CommitInfos returns the commit infos of the commits replayed to build the snapshot.
+func (s *Snapshot) CommitInfos() []CommitInfo {
+	return s.state.CommitInfos
+}
+
+// AppTransactionVersion returns the latest version committed by the application with the
+// given id through a txn action. Streaming writers use it to skip batches that were
+// already committed.
+func (s *Snapshot) AppTransactionVersion(appID string) (int64, bool) {
+	v, ok := s.state.AppTransactionVersion[appID]
+	return v, ok
+}
@@ -0,0 +1,225 @@
+package delta
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/delta-golang/delta-go/delta/expr"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+// statsStringPrefixLength is the number of characters writers truncate the string
+// minimum and maximum values of file statistics to.
+const statsStringPrefixLength = 32
+
+// fileStats are the statistics of a data file, parsed from the stats of its add action.
+// It implements expr.Stats for the columns of the table schema. Values are converted to
+// the types of their columns when they are looked up, and bounds that writers may have
+// truncated are widened:
+//   - a string maximum of statsStringPrefixLength characters or more may be the prefix
+//     of the actual maximum,
+//   - timestamps are written with millisecond precision.
+//
+// Partition columns have their partition value as the minimum and maximum.
+type fileStats struct {
+	schema *schema.StructType
+
+	numRecords    int64
+	hasNumRecords bool
+	minValues     map[string]interface{}
+	maxValues     map[string]interface{}
+	nullCount     map[string]interface{}
+
+	partitions      map[string]bool
+	partitionValues map[string]string
+}
+
+var _ expr.Stats = (*fileStats)(nil)
+
+// parseFileStats parses the stats of a data file. partitionValues are the partition
+// values of the file keyed by logical column name.
+func parseFileStats(raw string, sch *schema.StructType, mapping *ColumnMapping, partitions map[string]bool, partitionValues map[string]string) (*fileStats, error) {
+	d := json.NewDecoder(strings.NewReader(raw))
+	d.UseNumber()
+	var parsed map[string]interface{}
+	if err := d.Decode(&parsed); err != nil {
+		return nil, err
+	}
+	parsed = mapping.LogicalStats(parsed)
+
+	s := &fileStats{schema: sch, partitions: partitions, partitionValues: partitionValues}
+	if n, ok := parsed["numRecords"].(json.Number); ok {
+		var err error
+		if s.numRecords, err = n.Int64(); err == nil {
+			s.hasNumRecords = true
+		}
+	}
+	s.minValues, _ = parsed["minValues"].(map[string]interface{})
+	s.maxValues, _ = parsed["maxValues"].(map[string]interface{})
+	s.nullCount, _ = parsed["nullCount"].(map[string]interface{})
+	return s, nil
+}
+
+// NumRecords returns the number of rows of the file.
+func (s *fileStats) NumRecords() (int64, bool) {
+	return s.numRecords, s.hasNumRecords
+}
+
+// Min returns the minimum value of a column.
+func (s *fileStats) Min(c expr.Column) (interface{}, bool) {
+	if v, ok, isPartition := s.partitionValue(c); isPartition {
+		return v, ok && v != nil
+	}
+	f, ok := s.field(c)
+	if !ok {
+		return nil, false
+	}
+	return statsValue(lookupStats(s.minValues, c.Path), f.Type)
+}
+
+// Max returns an upper bound of the values of a column.
+func (s *fileStats) Max(c expr.Column) (interface{}, bool) {
+	if v, ok, isPartition := s.partitionValue(c); isPartition {
+		return v, ok && v != nil
+	}
+	f, ok := s.field(c)
+	if !ok {
+		return nil, false
+	}
+	v, ok := statsValue(lookupStats(s.maxValues, c.Path), f.Type)
+	if !ok {
+		return nil, false
+	}
+
+	switch max := v.(type) {
+	case string:
+		// every valid UTF-8 string starting with a truncated maximum sorts before the
+		// maximum followed by the byte 0xff
+		if utf8.RuneCountInString(max) >= statsStringPrefixLength {
+			return max + "\xff", true
+		}
+	case time.Time:
+		if f.Type != schema.Date {
+			return max.Add(time.Millisecond - time.Nanosecond), true
+		}
+	}
+	return v, true
+}
+
+// NullCount returns the number of NULL values of a column.
+func (s *fileStats) NullCount(c expr.Column) (int64, bool) {
+	if v, ok, isPartition := s.partitionValue(c); isPartition {
+		if !ok || !s.hasNumRecords {
+			return 0, false
+		}
+		if v == nil {
+			return s.numRecords, true
+		}
+		return 0, true
+	}
+	n, ok := lookupStats(s.nullCount, c.Path).(json.Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := n.Int64()
+	return i, err == nil
+}
+
+// partitionValue returns the value of c if it is a partition column. ok is false if the
+// value cannot be parsed.
+func (s *fileStats) partitionValue(c expr.Column) (v interface{}, ok bool, isPartition bool) {
+	if len(c.Path) != 1 || !s.partitions[strings.ToLower(c.Path[0])] {
+		return nil, false, false
+	}
+	f, found := s.schema.Field(c.Path[0])
+	if !found {
+		return nil, false, true
+	}
+	v, err := ParsePartitionValue(partitionValue(s.partitionValues, f.Name), f.Type)
+	return v, err == nil, true
+}
+
+// field returns the field of the table schema at the path of c.
+func (s *fileStats) field(c expr.Column) (schema.StructField, bool) {
+	st := s.schema
+	var f schema.StructField
+	for i, name := range c.Path {
+		found := false
+		for _, sf := range st.Fields {
+			if strings.EqualFold(sf.Name, name) {
+				f, found = sf, true
+				break
+			}
+		}
+		if !found {
+			return f, false
+		}
+		if i < len(c.Path)-1 {
+			if st = structOf(f.Type); st == nil {
+				return f, false
+			}
+		}
+	}
+	return f, true
+}
+
+// lookupStats returns the value at path in a nested section of file statistics, or nil.
+func lookupStats(values map[string]interface{}, path []string) interface{} {
+	var v interface{} = values
+	for _, name := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		if v, ok = m[name]; ok {
+			continue
+		}
+		v = nil
+		for k, kv := range m {
+			if strings.EqualFold(k, name) {
+				v = kv
+				break
+			}
+		}
+	}
+	return v
+}
+
+// statsValue converts a value of file statistics to the expr representation of type dt.
+// ok is false for missing values and values of types without usable statistics.
+func statsValue(v interface{}, dt schema.DataType) (interface{}, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		switch dt {
+		case schema.Byte, schema.Short, schema.Integer, schema.Long:
+			i, err := n.Int64()
+			return i, err == nil
+		case schema.Float, schema.Double:
+			f, err := n.Float64()
+			return f, err == nil
+		}
+		if _, ok := dt.(schema.DecimalType); ok {
+			// compared exactly, so that bounds that round to the same float64 stay apart
+			d, err := expr.ParseDecimal(n.String())
+			return d, err == nil
+		}
+	case string:
+		switch dt {
+		case schema.String:
+			return n, true
+		case schema.Date:
+			t, err := time.ParseInLocation("2006-01-02", n, time.UTC)
+			return t, err == nil
+		case schema.Timestamp, schema.TimestampNtz:
+			t, err := expr.ParseTime(n)
+			return t.UTC(), err == nil
+		}
+	case bool:
+		if dt == schema.Boolean {
+			return n, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,102 @@
+package delta
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/delta-golang/delta-go/delta/expr"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+func TestFileStatsSkipping(t *testing.T) {
+	sch, err := schema.Parse(`{"type":"struct","fields":[
+		{"name":"id","type":"long","nullable":true,"metadata":{}},
+		{"name":"name","type":"string","nullable":true,"metadata":{}},
+		{"name":"ts","type":"timestamp","nullable":true,"metadata":{}},
+		{"name":"address","type":{"type":"struct","fields":[
+			{"name":"zip","type":"integer","nullable":true,"metadata":{}}
+		]},"nullable":true,"metadata":{}},
+		{"name":"p","type":"integer","nullable":true,"metadata":{}},
+		{"name":"amount","type":"decimal(38,18)","nullable":true,"metadata":{}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapping, err := NewColumnMapping(ColumnMappingNone, sch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	long := strings.Repeat("m", statsStringPrefixLength)
+	raw := `{"numRecords":10,
+		"minValues":{"id":1,"name":"a","ts":"2021-01-01T00:00:00.000Z","address":{"zip":10000},"amount":0.010000000000000000},
+		"maxValues":{"id":100,"name":"` + long + `","ts":"2021-01-01T10:00:00.123Z","address":{"zip":20000},"amount":0.010000000000000001},
+		"nullCount":{"id":0,"name":0,"ts":0,"address":{"zip":10}}}`
+	stats, err := parseFileStats(raw, sch, mapping, map[string]bool{"p": true}, map[string]string{"p": "3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter   string
+		expected bool
+	}{
+		{"id > 100", false},
+		{"ID = 50", true},
+		// the maximum may be truncated
+		{"name = '" + long + "zzz'", true},
+		{"name > 'n'", false},
+		// timestamps are written with millisecond precision
+		{"ts > TIMESTAMP '2021-01-01 10:00:00.123'", true},
+		{"ts > TIMESTAMP '2021-01-01 10:00:00.124'", false},
+		{"address.zip = 15000", false},
+		{"address.zip IS NULL", true},
+		{"p = 3 AND id = 1", true},
+		{"p = 4 OR id > 100", false},
+		{"p IS NULL", false},
+		// decimal bounds are compared exactly, though they round to the same float64
+		{"amount > 0.009999999999999999", true},
+		{"amount = 0.010000000000000001", true},
+		{"amount < 0.01", false},
+		{"amount > 0.010000000000000001", false},
+	}
+	for _, tt := range tests {
+		if got := expr.MayMatch(expr.MustParse(tt.filter), stats); got != tt.expected {
+			t.Errorf("%q: expected %v, got %v", tt.filter, tt.expected, got)
+		}
+	}
+}
+
+func TestScanDataSkipping(t *testing.T) {
+	tbl, err := LoadTable("../tests/data/delta-0.8.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter  string
+		files   int
+		skipped int
+	}{
+		{"value > 2", 1, 1},
+		{"value = 2", 2, 0},
+		{"value < 0 OR value > 4", 0, 2},
+		{"value IS NULL", 0, 2},
+	}
+	for _, tt := range tests {
+		r, err := tbl.Snapshot().Scan(ScanOptions{Filter: expr.MustParse(tt.filter)})
+		if err != nil {
+			t.Errorf("%s: %s", tt.filter, err)
+			continue
+		}
+		if r.NumFiles() != tt.files || r.SkippedFiles() != tt.skipped || r.PrunedFiles() != 0 {
+			t.Errorf("%s: expected %d files and %d skipped, got %d and %d", tt.filter, tt.files, tt.skipped, r.NumFiles(), r.SkippedFiles())
+		}
+		r.Release()
+	}
+
+	_, values := scanAll(t, tbl, ScanOptions{Filter: expr.MustParse("value > 2")})
+	if len(values["value"]) != 2 {
+		t.Errorf("expected the 2 rows of the file with values above 2, got %v", values["value"])
+	}
+}
@@ -2,6 +2,9 @@ package file
 
 import (
 	"bufio"
+	"errors"
+	"io"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
@@ -13,14 +16,16 @@ type Store struct {
 
 func New(uri string) *Store {
 
-	wd, err := os.Getwd()
-	if err != nil {
-		log.Fatalf("unable to get working directory: %s", err)
-		return nil
+	path := uri
+	if !filepath.IsAbs(uri) {
+		wd, err := os.Getwd()
+		if err != nil {
+			log.Fatalf("unable to get working directory: %s", err)
+			return nil
+		}
+		path = filepath.Join(wd, uri)
 	}
 
-	path := filepath.Join(wd, uri)
-
 	s := Store{
 		path: path,
 	}
@@ -42,3 +47,68 @@ func (s *Store) GetObject(relativePath string) (*bufio.Scanner, func() error, er
 	scanner := bufio.NewScanner(file)
 	return scanner, c, nil
 }
+
+func (s *Store) OpenObject(relativePath string) (io.ReadSeekCloser, error) {
+
+	p := filepath.Join(s.path, relativePath)
+	return os.Open(p)
+}
+
+func (s *Store) Stat(relativePath string) (fs.FileInfo, error) {
+
+	p := filepath.Join(s.path, relativePath)
+	return os.Stat(p)
+}
+
+func (s *Store) List(relativePath string) ([]fs.FileInfo, error) {
+
+	p := filepath.Join(s.path, relativePath)
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]fs.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		fi, err := e.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			// removed since the directory was read
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, fi)
+	}
+	return infos, nil
+}
+
+// PutObjectIfAbsent writes the data to a temporary file and hard links it to the target
+// path. Creating the link fails if the target exists, which makes the write atomic.
+func (s *Store) PutObjectIfAbsent(relativePath string, data []byte) error {
+
+	p := filepath.Join(s.path, relativePath)
+	dir := filepath.Dir(p)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Link(tmp.Name(), p)
+}
@@ -2,6 +2,8 @@ package s3
 
 import (
 	"bufio"
+	"io"
+	"io/fs"
 )
 
 type Store struct {
@@ -19,3 +21,23 @@ func (s *Store) GetObject(uri string) (*bufio.Scanner, func() error, error) {
 	//TODO implement me
 	panic("implement me")
 }
+
+func (s *Store) OpenObject(uri string) (io.ReadSeekCloser, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (s *Store) Stat(uri string) (fs.FileInfo, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (s *Store) List(uri string) ([]fs.FileInfo, error) {
+	//TODO implement me
+	panic("implement me")
+}
+
+func (s *Store) PutObjectIfAbsent(uri string, data []byte) error {
+	//TODO implement me
+	panic("implement me")
+}
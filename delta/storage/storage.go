@@ -5,11 +5,57 @@ import (
 	"errors"
 	"github.com/delta-golang/delta-go/delta/storage/file"
 	"github.com/delta-golang/delta-go/delta/storage/s3"
+	"io"
+	"io/fs"
 	"strings"
+	"sync"
 )
 
 type Backend interface {
 	GetObject(uri string) (*bufio.Scanner, func() error, error)
+	OpenObject(uri string) (io.ReadSeekCloser, error)
+	Stat(uri string) (fs.FileInfo, error)
+	// List returns the objects directly below the given directory. Subdirectories are
+	// not included.
+	List(uri string) ([]fs.FileInfo, error)
+	// PutObjectIfAbsent atomically creates the object with the given contents. If the
+	// object already exists it is left unchanged and an error matching fs.ErrExist is
+	// returned. Commits rely on this to detect concurrent writers.
+	PutObjectIfAbsent(uri string, data []byte) error
+}
+
+// ReadSeekerAt is the random access reader needed to read parquet files.
+type ReadSeekerAt interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+type readerAt struct {
+	mu sync.Mutex
+	io.ReadSeeker
+}
+
+// NewReadSeekerAt returns r itself if it already implements io.ReaderAt, otherwise it
+// wraps r so that ReadAt is served by seeking and reading under a lock.
+func NewReadSeekerAt(r io.ReadSeeker) ReadSeekerAt {
+	if ra, ok := r.(ReadSeekerAt); ok {
+		return ra
+	}
+	return &readerAt{ReadSeeker: r}
+}
+
+func (r *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r.ReadSeeker, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
 }
 
 var (
@@ -1,20 +1,25 @@
 package delta
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
+	"time"
 
-	"github.com/apache/arrow/go/v8/parquet/file"
-	"github.com/apache/arrow/go/v8/parquet/schema"
 	"github.com/delta-golang/delta-go/delta/storage"
-	viewer "github.com/delta-golang/delta-go/delta/utils/parquet"
 	"github.com/delta-golang/delta-go/delta/utils/slices"
+	"github.com/google/uuid"
 )
 
 const (
 	LogDir             = "_delta_log"
+	SidecarDir         = "_sidecars"
 	LastCheckPointFile = "_last_checkpoint"
 	AddPath            = "add.path"
 	RemovePath         = "remove.path"
@@ -27,42 +32,64 @@ const (
 var ActionPaths = []string{AddPath, RemovePath, MetadataPath, CDCPath, TxnPath, ProtocolPath}
 
 type (
+	// Table is a handle to a Delta table. It holds the most recently loaded Snapshot,
+	// which can be refreshed with Update and shared freely between goroutines.
 	Table struct {
-		Storage          storage.Backend
-		Options          TableOptions
-		Version          int64
-		VersionTimestamp int64
-		URI              string
-		State            TableState
-		lastCheckPoint   Checkpoint
+		Storage storage.Backend
+		Options TableOptions
+		URI     string
+
+		updateMu sync.Mutex
+		mu       sync.RWMutex
+		snapshot *Snapshot
 	}
 
 	TableOptions struct {
 		requiresTombstones bool
+
+		// CheckpointWorkers bounds the number of checkpoint parts read concurrently.
+		// Zero uses a default of 8.
+		CheckpointWorkers int
 	}
 
+	// TableState accumulates the actions read while replaying the log. It is mutable and
+	// only used to build a Snapshot.
 	TableState struct {
 		Tombstones               map[string]RemoveAction
 		Files                    []AddAction
 		CommitInfos              []CommitInfo
 		AppTransactionVersion    map[string]int64
+		DomainMetadata           map[string]DomainMetadata
 		MinReaderVersion         int32
 		MinWriterVersion         int32
+		ReaderFeatures           []string
+		WriterFeatures           []string
 		CurrentMetadata          Metadata
 		TombstoneRetentionMillis int64
 		LogRetentionMillis       int64
 		EnableExpiredLogCleanup  bool
+
+		// sidecars referenced by a V2 checkpoint that still have to be read
+		sidecars []Sidecar
 	}
 
 	Checkpoint struct {
 		Version int64 //20 digit decimals
 		Size    int64
 		Parts   uint32 //10 digit decimals
+
+		// V2Checkpoint is set when the checkpoint follows the V2 checkpoint spec
+		V2Checkpoint *V2Checkpoint
 	}
 
-	index struct {
-		in   int
-		path string
+	// V2Checkpoint describes the top-level file of a V2 checkpoint. When NonFileActions
+	// and SidecarFiles are both present, the top-level file does not need to be read.
+	V2Checkpoint struct {
+		Path             string
+		SizeInBytes      int64
+		ModificationTime int64
+		NonFileActions   []json.RawMessage
+		SidecarFiles     []Sidecar
 	}
 )
 
@@ -76,7 +103,6 @@ func NewTable(uri string) *Table {
 	t := Table{
 		Storage: s,
 		URI:     uri,
-		Version: -1,
 	}
 
 	return &t
@@ -104,76 +130,402 @@ func (t *Table) getLastCheckpoint() (Checkpoint, error) {
 	return cp, nil
 }
 
-func (t *Table) restoreCheckpoint() error {
+// Snapshot returns the most recently loaded snapshot of the table, or nil if the table
+// has not been loaded yet.
+func (t *Table) Snapshot() *Snapshot {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.snapshot
+}
+
+// Version returns the version of the current snapshot, or -1 if the table has not been
+// loaded yet.
+func (t *Table) Version() int64 {
+	s := t.Snapshot()
+	if s == nil {
+		return -1
+	}
+	return s.version
+}
+
+func (t *Table) setSnapshot(s *Snapshot) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.snapshot = s
+}
+
+func newTableState() TableState {
+	return TableState{
+		Tombstones:            make(map[string]RemoveAction),
+		AppTransactionVersion: make(map[string]int64),
+		DomainMetadata:        make(map[string]DomainMetadata),
+	}
+}
+
+// LoadVersion discards the current snapshot of the table and rebuilds it as of the given
+// version, starting from the last checkpoint at or below that version.
+func (t *Table) LoadVersion(version int64) error {
+	if version < 0 {
+		return &VersionNotFoundError{Version: version}
+	}
+
+	t.updateMu.Lock()
+	defer t.updateMu.Unlock()
+
+	s, err := t.load(context.Background(), version)
+	if err != nil {
+		return err
+	}
+
+	if s.version != version {
+		return &VersionNotFoundError{Version: version}
+	}
+	t.setSnapshot(s)
+	return nil
+}
+
+// Update brings the table up to date with the latest commit in the log. Only the commits
+// after the current snapshot are read; the previous snapshot stays valid and unchanged
+// for anyone still holding it.
+func (t *Table) Update(ctx context.Context) error {
+	t.updateMu.Lock()
+	defer t.updateMu.Unlock()
 
-	cp := Checkpoint{}
-	if t.lastCheckPoint == cp {
-		// no checkpoint
+	cur := t.Snapshot()
+	if cur == nil {
+		s, err := t.load(ctx, math.MaxInt64)
+		if err != nil {
+			return err
+		}
+		if s.version < 0 {
+			return fmt.Errorf("no commits found in %s: %w", t.logURI(), os.ErrNotExist)
+		}
+		t.setSnapshot(s)
+		return nil
+	}
+
+	// avoid copying the state when there is nothing new to apply
+	_, err := t.Storage.Stat(commitPathForVersion(cur.version + 1))
+	if errors.Is(err, os.ErrNotExist) {
 		return nil
 	}
+	if err != nil {
+		return err
+	}
+
+	next := &Snapshot{
+		storage:   t.Storage,
+		version:   cur.version,
+		timestamp: cur.timestamp,
+		state:     cur.state.clone(),
+	}
+	err = t.updateIncrements(ctx, next, math.MaxInt64)
+	if err != nil {
+		return err
+	}
+	next.applyMetadata()
+	t.setSnapshot(next)
+	return nil
+}
+
+// load builds a snapshot from the last checkpoint and the commits that follow it,
+// stopping at maxVersion. The returned snapshot has version -1 when no commit was found.
+func (t *Table) load(ctx context.Context, maxVersion int64) (*Snapshot, error) {
+	s := &Snapshot{
+		storage: t.Storage,
+		version: -1,
+		state:   newTableState(),
+	}
+
+	listing, err := t.listLog()
+	if err != nil {
+		return nil, err
+	}
 
-	paths := checkpointPathsForCheckpoint(t.lastCheckPoint)
+	// the newest checkpoint is used, older ones are only tried when it cannot be read
+	var restoreErr *CheckpointRestoreError
+	candidates := t.checkpointCandidates(listing, maxVersion)
+	for _, cp := range candidates {
+		err = t.restoreCheckpoint(cp, &s.state)
+		if err == nil {
+			s.version = cp.Version
+			break
+		}
 
-	for _, v := range paths {
-		p := filepath.Join(t.URI, v)
-		rdr, err := file.OpenParquetFile(p, true)
+		// we don't want to abandon if a checkpoint is corrupted for any reason
+		// we can still process the individual commits, and only report the
+		// checkpoint error if that fails as well
+		if restoreErr == nil {
+			restoreErr = &CheckpointRestoreError{Version: cp.Version, Err: err}
+		}
+		s.state = newTableState()
+	}
+
+	err = listing.checkCommits(s.version+1, maxVersion)
+	if err == nil {
+		err = t.updateIncrements(ctx, s, maxVersion)
+	}
+	if err != nil {
+		if restoreErr != nil {
+			restoreErr.ReplayErr = err
+			return nil, restoreErr
+		}
+		return nil, err
+	}
+	s.applyMetadata()
+	return s, nil
+}
+
+// checkpointCandidates returns the complete checkpoints at or below maxVersion, newest
+// first. The log listing decides which checkpoints exist, so a stale or missing
+// _last_checkpoint file does not matter; it is only used for the hints it carries when it
+// describes the same checkpoint.
+func (t *Table) checkpointCandidates(l *logListing, maxVersion int64) []Checkpoint {
+	last, err := t.getLastCheckpoint()
+	hint := err == nil
+
+	var candidates []Checkpoint
+	for i := len(l.checkpoints) - 1; i >= 0; i-- {
+		cp := l.checkpoints[i]
+		if cp.Version > maxVersion {
+			continue
+		}
+
+		if hint && last.Version == cp.Version && last.Parts == cp.Parts && (last.V2Checkpoint == nil) == (cp.V2Checkpoint == nil) {
+			cp = last
+			hint = false
+		}
+		candidates = append(candidates, cp)
+	}
+	return candidates
+}
+
+// updateIncrements replays every commit after the snapshot version into the snapshot, in
+// order, until maxVersion is reached or no further commit file can be found in the log.
+func (t *Table) updateIncrements(ctx context.Context, s *Snapshot, maxVersion int64) error {
+	conf, _ := ParseTableConfig(s.state.CurrentMetadata.Configuration)
+	for s.version < maxVersion {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		version := s.version + 1
+		state, err := t.incrementalState(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("replaying commit %d: %w", version, err)
+		}
+
+		s.state.merge(state)
+		if state.CurrentMetadata.ID != uuid.Nil {
+			conf, _ = ParseTableConfig(s.state.CurrentMetadata.Configuration)
+		}
+
+		ts, err := t.commitTimestamp(version, state.CommitInfos, conf.InCommitTimestampsAt(version))
 		if err != nil {
 			return err
 		}
+		s.version = version
+		s.timestamp = ts
+	}
+	return nil
+}
 
-		seekers := make(map[index]*viewer.Seeker)
+// VersionAtTimestamp returns the latest version committed at or before ts. Commit
+// timestamps are adjusted to be strictly increasing across the log: a commit whose
+// timestamp is not after the adjusted timestamp of its predecessor is treated as one
+// millisecond later.
+//
+// In-commit timestamps increase by protocol, so the commits that carry them are binary
+// searched. The commits before in-commit timestamps were enabled are read in order, as
+// their adjustment depends on every earlier commit.
+func (t *Table) VersionAtTimestamp(ts time.Time) (int64, error) {
+	target := ts.UnixMilli()
+
+	l, err := t.listLog()
+	if err != nil {
+		return -1, err
+	}
+	if _, ok := l.earliestCommit(); !ok {
+		return -1, &TimestampNotFoundError{Timestamp: ts}
+	}
 
-		for i := 0; i < rdr.NumRowGroups(); i++ {
-			reader := rdr.RowGroup(i)
-			idx := index{
-				in: i,
-			}
-			createSeekers(rdr.MetaData().Schema, idx, reader, seekers)
+	// only the commits after the last gap in the log can be replayed
+	commits := l.commits
+	for i := len(commits) - 1; i > 0; i-- {
+		if commits[i-1] != commits[i]-1 {
+			commits = commits[i:]
+			break
 		}
+	}
+
+	conf, err := t.timestampConfig(l, commits[0], commits[len(commits)-1])
+	if err != nil {
+		return -1, err
+	}
 
-		rg := 0
-		for i := 0; i < int(rdr.NumRows()); i++ {
-			reader := rdr.RowGroup(rg)
-			a, ok := findActionType(reader, index{in: rg}, seekers)
-			if !ok {
-				rg++
-				continue
+	// the enablement commit's in-commit timestamp is after every earlier commit
+	split := sort.Search(len(commits), func(i int) bool { return conf.InCommitTimestampsAt(commits[i]) })
+	if split < len(commits) {
+		first, err := t.inCommitTimestamp(commits[split])
+		if err != nil {
+			return -1, err
+		}
+		if first <= target {
+			// the first commit whose in-commit timestamp is after target ends the search
+			ict := commits[split:]
+			lo, hi := 1, len(ict)
+			for lo < hi {
+				mid := int(uint(lo+hi) >> 1)
+				commitTs, err := t.inCommitTimestamp(ict[mid])
+				if err != nil {
+					return -1, err
+				}
+				if commitTs > target {
+					hi = mid
+				} else {
+					lo = mid + 1
+				}
 			}
-			fmt.Println(a)
+			return ict[lo-1], nil
 		}
+	}
 
-		viewer.View(p)
+	found, prev := int64(-1), int64(math.MinInt64)
+	for _, version := range commits[:split] {
+		infos, _, err := t.readCommit(version)
+		if err != nil {
+			return -1, err
+		}
+		commitTs, err := t.commitTimestamp(version, infos, false)
+		if err != nil {
+			return -1, err
+		}
+		if commitTs <= prev {
+			commitTs = prev + 1
+		}
+		if commitTs > target {
+			break
+		}
+		found, prev = version, commitTs
+	}
+	if found < 0 {
+		return -1, &TimestampNotFoundError{Timestamp: ts}
 	}
-	t.Version = cp.Version
+	return found, nil
+}
 
-	return nil
+// timestampConfig returns the table configuration that decides which of the commits from
+// start to end carry in-commit timestamps, without loading the table: the configuration
+// of the newest metaData action among the commits after the current snapshot, or else the
+// snapshot's. Without a snapshot that reaches start, the checkpoint the commits follow is
+// read when none of them changes the metadata.
+func (t *Table) timestampConfig(l *logListing, start, end int64) (TableConfig, error) {
+	s := t.Snapshot()
+	if s != nil && s.version < start-1 {
+		s = nil
+	}
+
+	from := start
+	if s != nil {
+		from = s.version + 1
+	}
+	for version := end; version >= from; version-- {
+		_, md, err := t.readCommit(version)
+		if err != nil {
+			return TableConfig{}, err
+		}
+		if md != nil {
+			return ParseTableConfig(md.Configuration)
+		}
+	}
+	if s != nil {
+		return s.Config()
+	}
+
+	for _, cp := range t.checkpointCandidates(l, start-1) {
+		if cp.Version != start-1 {
+			break
+		}
+		state := newTableState()
+		if err := t.restoreCheckpoint(cp, &state); err != nil {
+			return TableConfig{}, err
+		}
+		return ParseTableConfig(state.CurrentMetadata.Configuration)
+	}
+	return DefaultTableConfig(), nil
+}
+
+// inCommitTimestamp returns the in-commit timestamp of a commit made while in-commit
+// timestamps were enabled.
+func (t *Table) inCommitTimestamp(version int64) (int64, error) {
+	infos, _, err := t.readCommit(version)
+	if err != nil {
+		return 0, err
+	}
+	return t.commitTimestamp(version, infos, true)
 }
 
-func createSeekers(schema *schema.Schema, idx index, reader *file.RowGroupReader, seekers map[index]*viewer.Seeker) {
+// commitTimestamp returns the timestamp of a commit in milliseconds since the epoch.
+// When inCommit is set, the commit is at or after the version that enabled in-commit
+// timestamps and its in-commit timestamp takes precedence over the commitInfo timestamp.
+// The modification time of the commit file is used when the commit carries neither.
+func (t *Table) commitTimestamp(version int64, infos []CommitInfo, inCommit bool) (int64, error) {
+	if inCommit {
+		for _, ci := range infos {
+			if ts, ok := ci.InCommitTimestamp(); ok {
+				return ts, nil
+			}
+		}
+	}
+
+	for _, ci := range infos {
+		if ts, ok := ci.Timestamp(); ok {
+			return ts, nil
+		}
+	}
 
-	for _, a := range ActionPaths {
-		i := schema.ColumnIndexByName(a)
-		r := reader.Column(i)
-		idx.path = a
-		seekers[idx] = viewer.CreateDumper(r)
+	fi, err := t.Storage.Stat(commitPathForVersion(version))
+	if err != nil {
+		return 0, err
 	}
+	return fi.ModTime().UnixMilli(), nil
 }
 
-func findActionType(reader *file.RowGroupReader, idx index, seekers map[index]*viewer.Seeker) (string, bool) {
-
-	var action string
-	for _, a := range ActionPaths {
-		idx.path = a
-		seeker := seekers[idx]
-		if v, ok := seeker.Next(); !ok {
-			return "", false
-		} else {
-			if v != nil && action == "" {
-				action = a
+// readCommit decodes only the commitInfo and metaData actions of a commit file. md is nil
+// when the commit does not change the metadata.
+func (t *Table) readCommit(version int64) (infos []CommitInfo, md *Metadata, err error) {
+	scanner, c, err := t.Storage.GetObject(commitPathForVersion(version))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer c()
+
+	for scanner.Scan() {
+		var ac map[string]json.RawMessage
+		err = json.Unmarshal(scanner.Bytes(), &ac)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if v, ok := ac["commitInfo"]; ok {
+			ci, err := deserializeAction[CommitInfo](v)
+			if err != nil {
+				return nil, nil, err
+			}
+			infos = append(infos, ci)
+		}
+		if v, ok := ac["metaData"]; ok {
+			m, err := deserializeAction[Metadata](v)
+			if err != nil {
+				return nil, nil, err
 			}
+			md = &m
 		}
 	}
-	return action, true
+	return infos, md, scanner.Err()
 }
 
 func (t *Table) incrementalState(fromVersion int64) (*TableState, error) {
@@ -184,7 +536,7 @@ func (t *Table) incrementalState(fromVersion int64) (*TableState, error) {
 	}
 	defer c()
 
-	newState := TableState{}
+	newState := newTableState()
 	for scanner.Scan() {
 		var ac map[string]json.RawMessage
 		err = json.Unmarshal(scanner.Bytes(), &ac)
@@ -193,67 +545,145 @@ func (t *Table) incrementalState(fromVersion int64) (*TableState, error) {
 		}
 
 		for k, v := range ac {
-			switch k {
-			case "add":
-				add, err := deserializeAction[AddAction](v)
-				if err != nil {
-					return nil, err
-				}
-				newState.Files = append(t.State.Files, add)
-			case "remove":
-				rm, err := deserializeAction[RemoveAction](v)
-				if err != nil {
-					return nil, err
-				}
-				newState.Tombstones[rm.Path] = rm
-			case "metaData":
-				md, err := deserializeAction[Metadata](v)
-				if err != nil {
-					return nil, err
-				}
-				newState.CurrentMetadata = md
-			case "commitInfo":
-				ci, err := deserializeAction[CommitInfo](v)
-				if err != nil {
-					return nil, err
-				}
-				newState.CommitInfos = append(t.State.CommitInfos, ci)
-			case "protocol":
-				p, err := deserializeAction[Protocol](v)
-				if err != nil {
-					return nil, err
-				}
-				newState.MinReaderVersion = p.MinReaderVersion
-				newState.MinWriterVersion = p.MinWriterVersion
+			err = newState.applyAction(k, v)
+			if err != nil {
+				return nil, err
 			}
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 	return &newState, nil
 }
 
-func (t *Table) mergeState(s *TableState) {
-
-	// remove files from the table that have a tombstone in new state
-	t.State.Files = slices.Filter(t.State.Files, func(f AddAction) bool {
-		for _, v := range s.Tombstones {
-			if f.Path == v.Path {
-				return false
-			}
+// applyAction decodes a single action of the given kind into the state. Action kinds
+// that are not part of a table snapshot are ignored.
+func (s *TableState) applyAction(kind string, v []byte) error {
+	switch kind {
+	case "add":
+		add, err := deserializeAction[AddAction](v)
+		if err != nil {
+			return err
+		}
+		s.Files = append(s.Files, add)
+	case "remove":
+		rm, err := deserializeAction[RemoveAction](v)
+		if err != nil {
+			return err
+		}
+		s.Tombstones[rm.Path] = rm
+	case "metaData":
+		md, err := deserializeAction[Metadata](v)
+		if err != nil {
+			return err
+		}
+		s.CurrentMetadata = md
+	case "commitInfo":
+		ci, err := deserializeAction[CommitInfo](v)
+		if err != nil {
+			return err
+		}
+		s.CommitInfos = append(s.CommitInfos, ci)
+	case "protocol":
+		p, err := deserializeAction[Protocol](v)
+		if err != nil {
+			return err
+		}
+		s.MinReaderVersion = p.MinReaderVersion
+		s.MinWriterVersion = p.MinWriterVersion
+		s.ReaderFeatures = p.ReaderFeatures
+		s.WriterFeatures = p.WriterFeatures
+	case "txn":
+		txn, err := deserializeAction[SetTransaction](v)
+		if err != nil {
+			return err
+		}
+		s.AppTransactionVersion[txn.AppID] = txn.Version
+	case "domainMetadata":
+		dm, err := deserializeAction[DomainMetadata](v)
+		if err != nil {
+			return err
 		}
-		return true
+		s.DomainMetadata[dm.Domain] = dm
+	}
+	return nil
+}
+
+func (s *TableState) merge(n *TableState) {
+
+	// files that are removed or re-added by the new state replace the current entries
+	replaced := make(map[string]struct{}, len(n.Tombstones)+len(n.Files))
+	for k := range n.Tombstones {
+		replaced[k] = struct{}{}
+	}
+	for _, v := range n.Files {
+		replaced[v.Path] = struct{}{}
+	}
+	s.Files = slices.Filter(s.Files, func(f AddAction) bool {
+		_, ok := replaced[f.Path]
+		return !ok
 	})
 
 	// add all new tombstones
-	for k, v := range s.Tombstones {
-		t.State.Tombstones[k] = v
+	for k, v := range n.Tombstones {
+		s.Tombstones[k] = v
 	}
 
 	// remove from tombstones the ones that have a new file
-	for _, v := range s.Files {
-		delete(t.State.Tombstones, v.Path)
+	for _, v := range n.Files {
+		delete(s.Tombstones, v.Path)
+	}
+
+	s.Files = append(s.Files, n.Files...)
+	s.CommitInfos = append(s.CommitInfos, n.CommitInfos...)
+
+	if n.MinReaderVersion > 0 {
+		s.MinReaderVersion = n.MinReaderVersion
+		s.MinWriterVersion = n.MinWriterVersion
+		s.ReaderFeatures = n.ReaderFeatures
+		s.WriterFeatures = n.WriterFeatures
+	}
+
+	if n.CurrentMetadata.ID != uuid.Nil {
+		s.CurrentMetadata = n.CurrentMetadata
+	}
+
+	for k, v := range n.AppTransactionVersion {
+		s.AppTransactionVersion[k] = v
+	}
+
+	for k, v := range n.DomainMetadata {
+		if v.Removed {
+			delete(s.DomainMetadata, k)
+			continue
+		}
+		s.DomainMetadata[k] = v
 	}
+}
+
+// clone returns a copy of the state that can be merged into without affecting the
+// original. Actions themselves are shared as they are never modified once read.
+func (s *TableState) clone() TableState {
+	c := *s
+	c.Files = make([]AddAction, len(s.Files))
+	copy(c.Files, s.Files)
+	c.CommitInfos = make([]CommitInfo, len(s.CommitInfos))
+	copy(c.CommitInfos, s.CommitInfos)
 
-	t.State.Files = append(t.State.Files, s.Files...)
+	c.Tombstones = make(map[string]RemoveAction, len(s.Tombstones))
+	for k, v := range s.Tombstones {
+		c.Tombstones[k] = v
+	}
+	c.AppTransactionVersion = make(map[string]int64, len(s.AppTransactionVersion))
+	for k, v := range s.AppTransactionVersion {
+		c.AppTransactionVersion[k] = v
+	}
+	c.DomainMetadata = make(map[string]DomainMetadata, len(s.DomainMetadata))
+	for k, v := range s.DomainMetadata {
+		c.DomainMetadata[k] = v
+	}
+	return c
 }
 
 func commitPathForVersion(version int64) string {
@@ -262,6 +692,10 @@ func commitPathForVersion(version int64) string {
 }
 
 func checkpointPathsForCheckpoint(cp Checkpoint) []string {
+	if cp.V2Checkpoint != nil {
+		return []string{filepath.Join(LogDir, filepath.Base(cp.V2Checkpoint.Path))}
+	}
+
 	s := fmt.Sprintf("%020d", cp.Version)
 	prefix := filepath.Join(LogDir, s)
 	var paths []string
@@ -1,8 +1,15 @@
 package delta
 
 import (
-	"github.com/delta-golang/delta-go/delta/storage"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/delta-golang/delta-go/delta/storage"
 )
 
 func TestGetLastCheckpoint(t *testing.T) {
@@ -17,3 +24,224 @@ func TestGetLastCheckpoint(t *testing.T) {
 		t.Errorf("incorrect version or size")
 	}
 }
+
+func TestVersionAtTimestampAdjustment(t *testing.T) {
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, LogDir)
+	if err := os.Mkdir(logDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// version 1 goes back in time and carries an in-commit timestamp before they were
+	// enabled, version 2 has no commitInfo, so its file modification time is used
+	// instead, and enables in-commit timestamps from version 3 on
+	commits := []string{
+		`{"commitInfo":{"timestamp":1000}}`,
+		`{"commitInfo":{"timestamp":900,"inCommitTimestamp":100}}`,
+		`{"protocol":{"minReaderVersion":1,"minWriterVersion":2}}` + "\n" +
+			`{"metaData":{"id":"c1b4b7f2-7d67-4e4b-a9a8-2d1c1a3e0c11","format":{"provider":"parquet","options":{}},"schemaString":"{\"type\":\"struct\",\"fields\":[]}","partitionColumns":[],"configuration":{"delta.enableInCommitTimestamps":"true","delta.inCommitTimestampEnablementVersion":"3"}}}`,
+		`{"commitInfo":{"timestamp":5000,"inCommitTimestamp":3000}}`,
+		`{"commitInfo":{"timestamp":6000,"inCommitTimestamp":4000}}`,
+	}
+	for i, c := range commits {
+		p := filepath.Join(logDir, fmt.Sprintf("%020d.json", i))
+		if err := os.WriteFile(p, []byte(c+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mtime := time.UnixMilli(2000)
+	if err := os.Chtimes(filepath.Join(logDir, fmt.Sprintf("%020d.json", 2)), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl := NewTable(dir)
+	tests := []struct {
+		ts      int64
+		version int64
+	}{
+		{ts: 1000, version: 0},
+		{ts: 1001, version: 1},
+		{ts: 1999, version: 1},
+		{ts: 2000, version: 2},
+		{ts: 2999, version: 2},
+		{ts: 3000, version: 3},
+		{ts: 4999, version: 4},
+	}
+	for _, tt := range tests {
+		v, err := tbl.VersionAtTimestamp(time.UnixMilli(tt.ts))
+		if err != nil {
+			t.Fatalf("error resolving timestamp %d: %s", tt.ts, err)
+		}
+		if v != tt.version {
+			t.Errorf("timestamp %d: expected version %d, got %d", tt.ts, tt.version, v)
+		}
+	}
+
+	var notFound *TimestampNotFoundError
+	if _, err := tbl.VersionAtTimestamp(time.UnixMilli(999)); !errors.As(err, &notFound) {
+		t.Errorf("expected TimestampNotFoundError before the first commit, got %v", err)
+	}
+}
+
+func TestVersionAtTimestampCumulativeAdjustment(t *testing.T) {
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, LogDir)
+	if err := os.Mkdir(logDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// the clock goes back across two commits: 1000, 900 and 950 adjust to 1000, 1001
+	// and 1002
+	for i, ts := range []int64{1000, 900, 950, 2000} {
+		p := filepath.Join(logDir, fmt.Sprintf("%020d.json", i))
+		if err := os.WriteFile(p, []byte(fmt.Sprintf(`{"commitInfo":{"timestamp":%d}}`+"\n", ts)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tbl := NewTable(dir)
+	tests := []struct {
+		ts      int64
+		version int64
+	}{
+		{ts: 1000, version: 0},
+		{ts: 1001, version: 1},
+		{ts: 1002, version: 2},
+		{ts: 1999, version: 2},
+		{ts: 2000, version: 3},
+	}
+	for _, tt := range tests {
+		v, err := tbl.VersionAtTimestamp(time.UnixMilli(tt.ts))
+		if err != nil {
+			t.Fatalf("error resolving timestamp %d: %s", tt.ts, err)
+		}
+		if v != tt.version {
+			t.Errorf("timestamp %d: expected version %d, got %d", tt.ts, tt.version, v)
+		}
+	}
+}
+
+func copyCommits(t *testing.T, src, dst string, versions ...int64) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dst, LogDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range versions {
+		b, err := os.ReadFile(filepath.Join(src, commitPathForVersion(v)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dst, commitPathForVersion(v)), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTableUpdate(t *testing.T) {
+	src := "../tests/data/simple_table"
+	dir := t.TempDir()
+	copyCommits(t, src, dir, 0, 1, 2)
+
+	tbl, err := LoadTable(dir)
+	if err != nil {
+		t.Fatalf("error loading table: %s", err)
+	}
+	old := tbl.Snapshot()
+
+	// nothing new to apply keeps the same snapshot
+	if err := tbl.Update(context.Background()); err != nil {
+		t.Fatalf("error updating table: %s", err)
+	}
+	if tbl.Snapshot() != old {
+		t.Errorf("expected update without new commits to keep the snapshot")
+	}
+
+	copyCommits(t, src, dir, 3, 4)
+	if err := tbl.Update(context.Background()); err != nil {
+		t.Fatalf("error updating table: %s", err)
+	}
+
+	cur := tbl.Snapshot()
+	if cur.Version() != 4 || len(cur.Files()) != 5 {
+		t.Errorf("expected version 4 with 5 files, got version %d with %d files", cur.Version(), len(cur.Files()))
+	}
+	if old.Version() != 2 || len(old.Files()) != 6 {
+		t.Errorf("previous snapshot changed to version %d with %d files", old.Version(), len(old.Files()))
+	}
+}
+
+func TestRestoreCheckpoint(t *testing.T) {
+	tbl := NewTable("../tests/data/simple_table_with_checkpoint")
+	state := newTableState()
+	err := tbl.restoreCheckpoint(Checkpoint{Version: 10, Size: 13}, &state)
+	if err != nil {
+		t.Fatalf("error restoring checkpoint: %s", err)
+	}
+
+	// the checkpoint must hold exactly what replaying the commits up to it produces
+	expected := newTableState()
+	for v := int64(0); v <= 10; v++ {
+		inc, err := tbl.incrementalState(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected.merge(inc)
+	}
+
+	if len(state.Files) != len(expected.Files) {
+		t.Fatalf("expected %d files, got %d", len(expected.Files), len(state.Files))
+	}
+	paths := make(map[string]AddAction)
+	for _, f := range expected.Files {
+		paths[f.Path] = f
+	}
+	for _, f := range state.Files {
+		e, ok := paths[f.Path]
+		if !ok {
+			t.Errorf("unexpected file %s in checkpoint", f.Path)
+			continue
+		}
+		if f.Size != e.Size || f.ModificationTime != e.ModificationTime {
+			t.Errorf("file %s differs: got %+v, expected %+v", f.Path, f, e)
+		}
+	}
+
+	if len(state.Tombstones) != len(expected.Tombstones) {
+		t.Errorf("expected %d tombstones, got %d", len(expected.Tombstones), len(state.Tombstones))
+	}
+	if state.CurrentMetadata.ID != expected.CurrentMetadata.ID || state.CurrentMetadata.SchemaString != expected.CurrentMetadata.SchemaString {
+		t.Errorf("unexpected metadata %+v", state.CurrentMetadata)
+	}
+	if state.MinReaderVersion != 1 || state.MinWriterVersion != 2 {
+		t.Errorf("unexpected protocol %d/%d", state.MinReaderVersion, state.MinWriterVersion)
+	}
+}
+
+func TestLoadCorruptCheckpoint(t *testing.T) {
+	src := "../tests/data/simple_table_with_checkpoint"
+	dir := t.TempDir()
+	copyCommits(t, src, dir, 10)
+	if err := os.WriteFile(filepath.Join(dir, LogDir, "00000000000000000010.checkpoint.parquet"), []byte("corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the commits before the checkpoint are gone, so the checkpoint error is reported
+	_, err := LoadTable(dir)
+	var restoreErr *CheckpointRestoreError
+	if !errors.As(err, &restoreErr) || restoreErr.Version != 10 || restoreErr.Err == nil {
+		t.Fatalf("expected CheckpointRestoreError for version 10, got %v", err)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the replay error to wrap os.ErrNotExist, got %v", err)
+	}
+
+	// with every commit present the corrupt checkpoint is skipped
+	copyCommits(t, src, dir, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	tbl, err := LoadTable(dir)
+	if err != nil {
+		t.Fatalf("error loading table: %s", err)
+	}
+	if tbl.Version() != 10 {
+		t.Errorf("expected version 10, got %d", tbl.Version())
+	}
+}
@@ -0,0 +1,367 @@
+package delta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/delta-golang/delta-go/delta/schema"
+	"github.com/google/uuid"
+)
+
+// maxCommitAttempts bounds how often a commit is retried at a later version after
+// losing the race for a version to a non-conflicting concurrent writer.
+const maxCommitAttempts = 10
+
+// Transaction stages changes against a snapshot of a table and commits them atomically
+// as the next version of the table. A transaction is not safe for concurrent use.
+type Transaction struct {
+	table    *Table
+	snapshot *Snapshot
+
+	metadata *Metadata
+	protocol *Protocol
+	adds     []AddAction
+	removes  []RemoveAction
+
+	// overwriteSchema is set when the staged schema replaces the table schema, which
+	// requires every file of the snapshot to be removed
+	overwriteSchema bool
+
+	// preparedRows counts the rows validated by PrepareRecord
+	preparedRows int64
+	prepared     bool
+}
+
+// ConcurrentModificationError is returned by Commit when a concurrent writer committed a
+// change the transaction conflicts with, such as a new metaData or protocol action. The
+// transaction has to be rebuilt from a fresh snapshot.
+type ConcurrentModificationError struct {
+	// Version is the version committed by the concurrent writer.
+	Version int64
+	Reason  string
+}
+
+func (e *ConcurrentModificationError) Error() string {
+	return fmt.Sprintf("concurrent modification in version %d: %s", e.Version, e.Reason)
+}
+
+// NewTransaction starts a transaction that reads the current snapshot of the table.
+func (t *Table) NewTransaction() (*Transaction, error) {
+	s := t.Snapshot()
+	if s == nil || s.version < 0 {
+		return nil, errors.New("table must be loaded before starting a transaction")
+	}
+	return &Transaction{table: t, snapshot: s}, nil
+}
+
+// Snapshot returns the snapshot the transaction reads from.
+func (tx *Transaction) Snapshot() *Snapshot {
+	return tx.snapshot
+}
+
+// Metadata returns the metadata the transaction will commit, or the metadata of its
+// snapshot if it does not change it.
+func (tx *Transaction) Metadata() Metadata {
+	if tx.metadata != nil {
+		return *tx.metadata
+	}
+	return tx.snapshot.Metadata()
+}
+
+// Protocol returns the protocol the transaction will commit, or the protocol of its
+// snapshot if it does not change it.
+func (tx *Transaction) Protocol() Protocol {
+	if tx.protocol != nil {
+		return *tx.protocol
+	}
+	return tx.snapshot.Protocol()
+}
+
+// UpdateMetadata stages a metaData action that replaces the table metadata.
+func (tx *Transaction) UpdateMetadata(md Metadata) {
+	md = md.clone()
+	tx.metadata = &md
+}
+
+// UpdateProtocol stages a protocol action.
+func (tx *Transaction) UpdateProtocol(p Protocol) {
+	tx.protocol = &p
+}
+
+// AddFiles stages add actions for data files the caller has already written.
+func (tx *Transaction) AddFiles(adds ...AddAction) {
+	tx.adds = append(tx.adds, adds...)
+}
+
+// PrepareRecord assigns the identity values and computes the generated columns missing
+// from rec, and validates the rows against the constraints of the table before they are
+// written to a data file staged with AddFiles. It returns the record to write, with the
+// columns in schema order, which must be released by the caller. The schema and
+// constraints are those of the metadata the transaction will commit.
+//
+// Commit refuses to add data to a table with constraints or identity columns unless it
+// was prepared: every add action with data changes must hold rows returned by
+// PrepareRecord, and if the actions record their number of rows, the counts must match.
+func (tx *Transaction) PrepareRecord(rec arrow.Record, mem memory.Allocator) (arrow.Record, error) {
+	withIdentity, err := tx.AssignIdentityValues(rec, mem)
+	if err != nil {
+		return nil, err
+	}
+	defer withIdentity.Release()
+
+	md := tx.Metadata()
+	sch, err := schema.Parse(md.SchemaString)
+	if err != nil {
+		return nil, err
+	}
+	constraints, err := ParseConstraints(md.Configuration, sch)
+	if err != nil {
+		return nil, err
+	}
+
+	filled, err := FillGeneratedColumns(sch, withIdentity, mem)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckConstraints(constraints, filled); err != nil {
+		filled.Release()
+		return nil, err
+	}
+
+	tx.preparedRows += filled.NumRows()
+	tx.prepared = true
+	return filled, nil
+}
+
+// requiresPreparation reports whether data added to the metadata the transaction will
+// commit must go through PrepareRecord: the table has constraints or identity columns.
+func (tx *Transaction) requiresPreparation() (bool, error) {
+	md := tx.Metadata()
+	sch, err := schema.Parse(md.SchemaString)
+	if err != nil {
+		return false, err
+	}
+	constraints, err := ParseConstraints(md.Configuration, sch)
+	if err != nil {
+		return false, err
+	}
+	identity, err := ParseIdentityColumns(sch)
+	if err != nil {
+		return false, err
+	}
+	return len(constraints) > 0 || len(identity) > 0, nil
+}
+
+// RemoveFiles stages remove actions for data files of the snapshot. The deletion
+// timestamp is set to the current time if it is zero.
+func (tx *Transaction) RemoveFiles(removes ...RemoveAction) {
+	now := time.Now().UnixMilli()
+	for _, rm := range removes {
+		if rm.DeletionTimestamp == 0 {
+			rm.DeletionTimestamp = now
+		}
+		tx.removes = append(tx.removes, rm)
+	}
+}
+
+// Commit writes the staged actions as the next version of the table and refreshes the
+// table's snapshot. If another writer committed that version first, its actions are
+// checked for conflicts and the commit is retried at the following version. It returns
+// the committed version. Once the commit is written, Commit succeeds even if the refresh
+// fails; the snapshot then stays at its old version until the next Update.
+func (tx *Transaction) Commit(ctx context.Context, operation string) (int64, error) {
+	if err := tx.validate(); err != nil {
+		return -1, err
+	}
+
+	version := tx.snapshot.version + 1
+	for attempt := 0; attempt < maxCommitAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return -1, err
+		}
+
+		data, err := tx.encode(operation)
+		if err != nil {
+			return -1, err
+		}
+
+		err = tx.table.Storage.PutObjectIfAbsent(commitPathForVersion(version), data)
+		if err == nil {
+			_ = tx.table.Update(ctx)
+			return version, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return -1, fmt.Errorf("writing commit %d: %w", version, err)
+		}
+
+		if err := tx.checkConflicts(version); err != nil {
+			return -1, err
+		}
+		version++
+	}
+	return -1, fmt.Errorf("commit failed after %d attempts due to concurrent writers", maxCommitAttempts)
+}
+
+// validate checks that the staged actions can be committed on top of the snapshot and
+// that the transaction enforces the writer features of the table.
+func (tx *Transaction) validate() error {
+	if err := tx.Protocol().CheckWriteSupport(); err != nil {
+		return err
+	}
+	conf, err := ParseTableConfig(tx.Metadata().Configuration)
+	if err != nil {
+		return err
+	}
+
+	var dataRemoves, dataAdds bool
+	for _, rm := range tx.removes {
+		if !rm.DataChange {
+			continue
+		}
+		if conf.AppendOnly {
+			return &AppendOnlyError{Path: rm.Path}
+		}
+		dataRemoves = true
+	}
+	for _, add := range tx.adds {
+		dataAdds = dataAdds || add.DataChange
+	}
+	if conf.EnableChangeDataFeed && dataRemoves && dataAdds {
+		// rewriting files would need change data files to tell unchanged rows apart
+		return &UnsupportedProtocolError{WriterVersion: tx.Protocol().MinWriterVersion, Features: []string{FeatureChangeDataFeed}}
+	}
+
+	if err := tx.validatePrepared(); err != nil {
+		return err
+	}
+
+	if tx.overwriteSchema {
+		removed := make(map[string]bool, len(tx.removes))
+		for _, rm := range tx.removes {
+			removed[rm.Path] = true
+		}
+		for _, f := range tx.snapshot.Files() {
+			if !removed[f.Path] {
+				return &SchemaMismatchError{Reason: fmt.Sprintf("overwriting the schema requires replacing every file, %s is not removed", f.Path)}
+			}
+		}
+	}
+	return nil
+}
+
+// validatePrepared checks that the data added to a table with constraints or identity
+// columns went through PrepareRecord: the numRecords statistics of the added files must
+// add up to the prepared rows.
+func (tx *Transaction) validatePrepared() error {
+	var dataAdds []AddAction
+	for _, add := range tx.adds {
+		if add.DataChange {
+			dataAdds = append(dataAdds, add)
+		}
+	}
+	if len(dataAdds) == 0 {
+		return nil
+	}
+
+	required, err := tx.requiresPreparation()
+	if err != nil || !required {
+		return err
+	}
+	if !tx.prepared {
+		return &UnpreparedDataError{Reason: "the table has constraints or identity columns and no rows were prepared"}
+	}
+
+	var rows int64
+	for _, add := range dataAdds {
+		n, ok := add.numRecords()
+		if !ok {
+			return &UnpreparedDataError{Reason: fmt.Sprintf("added file %s has no numRecords statistic to match against the prepared rows", add.Path)}
+		}
+		rows += n
+	}
+	if rows != tx.preparedRows {
+		return &UnpreparedDataError{Reason: fmt.Sprintf("%d rows were prepared but the added files hold %d", tx.preparedRows, rows)}
+	}
+	return nil
+}
+
+// checkConflicts reads the commit a concurrent writer made at version and reports whether
+// the transaction can still be committed after it.
+func (tx *Transaction) checkConflicts(version int64) error {
+	winner, err := tx.table.incrementalState(version)
+	if err != nil {
+		return fmt.Errorf("reading concurrent commit %d: %w", version, err)
+	}
+
+	if winner.MinReaderVersion > 0 {
+		return &ConcurrentModificationError{Version: version, Reason: "the protocol was changed"}
+	}
+	if winner.CurrentMetadata.ID != uuid.Nil {
+		return &ConcurrentModificationError{Version: version, Reason: "the table metadata was changed"}
+	}
+	if tx.metadata != nil && len(winner.Files) > 0 {
+		// the added files were written for the old schema and constraints
+		return &ConcurrentModificationError{Version: version, Reason: "files were added while the transaction changes the table metadata"}
+	}
+	if len(tx.removes) > 0 && (len(winner.Files) > 0 || len(winner.Tombstones) > 0) {
+		return &ConcurrentModificationError{Version: version, Reason: "files were added or removed while the transaction removes files"}
+	}
+	return nil
+}
+
+// encode returns the commit file contents: one JSON action per line.
+func (tx *Transaction) encode(operation string) ([]byte, error) {
+	info := CommitInfo{
+		"timestamp":     time.Now().UnixMilli(),
+		"operation":     operation,
+		"readVersion":   tx.snapshot.version,
+		"isBlindAppend": tx.metadata == nil && tx.protocol == nil && len(tx.removes) == 0,
+	}
+
+	actions := []map[string]interface{}{{"commitInfo": info}}
+	if tx.protocol != nil {
+		actions = append(actions, map[string]interface{}{"protocol": tx.protocol})
+	}
+	if tx.metadata != nil {
+		md := *tx.metadata
+		if md.Configuration == nil {
+			md.Configuration = map[string]string{}
+		}
+		if md.Format.Options == nil {
+			md.Format.Options = map[string]string{}
+		}
+		if md.PartitionColumns == nil {
+			md.PartitionColumns = []string{}
+		}
+		actions = append(actions, map[string]interface{}{"metaData": md})
+	}
+	for _, rm := range tx.removes {
+		if rm.PartitionValues == nil {
+			rm.PartitionValues = map[string]string{}
+		}
+		actions = append(actions, map[string]interface{}{"remove": rm})
+	}
+	for _, add := range tx.adds {
+		if add.PartitionValues == nil {
+			add.PartitionValues = map[string]string{}
+		}
+		actions = append(actions, map[string]interface{}{"add": add})
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	for _, a := range actions {
+		if err := enc.Encode(a); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,171 @@
+package delta
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// createTestTable writes the first commit of a table with the given schema and table
+// properties and loads it.
+func createTestTable(t *testing.T, schemaString string, conf map[string]string) *Table {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, LogDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if conf == nil {
+		conf = map[string]string{}
+	}
+	md, err := json.Marshal(map[string]interface{}{"metaData": map[string]interface{}{
+		"id":               "c1b4b7f2-7d67-4e4b-a9a8-2d1c1a3e0c11",
+		"format":           map[string]interface{}{"provider": "parquet", "options": map[string]string{}},
+		"schemaString":     schemaString,
+		"partitionColumns": []string{},
+		"configuration":    conf,
+		"createdTime":      1,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := `{"protocol":{"minReaderVersion":1,"minWriterVersion":2}}` + "\n" + string(md) + "\n"
+	if err := os.WriteFile(filepath.Join(dir, commitPathForVersion(0)), []byte(commit), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl, err := LoadTable(dir)
+	if err != nil {
+		t.Fatalf("error loading table: %s", err)
+	}
+	return tbl
+}
+
+func TestTransactionCommit(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[{"name":"a","type":"long","nullable":true,"metadata":{}}]}`, nil)
+	ctx := context.Background()
+
+	// blind appends from the same snapshot do not conflict
+	tx1, _ := tbl.NewTransaction()
+	tx2, _ := tbl.NewTransaction()
+	tx1.AddFiles(AddAction{Action: Action{Path: "part-1.parquet", Size: 1, DataChange: true}})
+	tx2.AddFiles(AddAction{Action: Action{Path: "part-2.parquet", Size: 1, DataChange: true}})
+
+	if v, err := tx1.Commit(ctx, "WRITE"); err != nil || v != 1 {
+		t.Fatalf("expected commit at version 1, got %d (%v)", v, err)
+	}
+	if v, err := tx2.Commit(ctx, "WRITE"); err != nil || v != 2 {
+		t.Fatalf("expected commit at version 2, got %d (%v)", v, err)
+	}
+	if tbl.Version() != 2 || len(tbl.Snapshot().Files()) != 2 {
+		t.Errorf("expected version 2 with 2 files, got version %d with %v", tbl.Version(), tbl.Snapshot().Files())
+	}
+	infos := tbl.Snapshot().CommitInfos()
+	if op, _ := infos[len(infos)-1]["operation"].(string); op != "WRITE" {
+		t.Errorf("expected WRITE operation in commit info, got %v", infos[len(infos)-1])
+	}
+
+	// a metadata change conflicts with every transaction that read an older snapshot
+	tx1, _ = tbl.NewTransaction()
+	tx2, _ = tbl.NewTransaction()
+	md := tx1.Metadata()
+	md.Description = "changed"
+	tx1.UpdateMetadata(md)
+	if _, err := tx1.Commit(ctx, "SET TBLPROPERTIES"); err != nil {
+		t.Fatalf("error committing metadata: %s", err)
+	}
+	if tbl.Snapshot().Metadata().Description != "changed" {
+		t.Errorf("expected committed metadata to be loaded, got %+v", tbl.Snapshot().Metadata())
+	}
+
+	tx2.AddFiles(AddAction{Action: Action{Path: "part-3.parquet", Size: 1, DataChange: true}})
+	_, err := tx2.Commit(ctx, "WRITE")
+	var conflict *ConcurrentModificationError
+	if !errors.As(err, &conflict) || conflict.Version != 3 {
+		t.Errorf("expected ConcurrentModificationError at version 3, got %v", err)
+	}
+
+	// so does a metadata change committed after files were added from an older snapshot
+	tx1, _ = tbl.NewTransaction()
+	tx2, _ = tbl.NewTransaction()
+	tx1.AddFiles(AddAction{Action: Action{Path: "part-4.parquet", Size: 1, DataChange: true}})
+	if _, err := tx1.Commit(ctx, "WRITE"); err != nil {
+		t.Fatalf("error appending: %s", err)
+	}
+	md = tx2.Metadata()
+	md.Description = "changed again"
+	tx2.UpdateMetadata(md)
+	_, err = tx2.Commit(ctx, "SET TBLPROPERTIES")
+	if !errors.As(err, &conflict) || conflict.Version != 4 {
+		t.Errorf("expected ConcurrentModificationError at version 4, got %v", err)
+	}
+}
+
+func TestTransactionCommitRefreshFailure(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[{"name":"a","type":"long","nullable":true,"metadata":{}}]}`, nil)
+
+	// a corrupt commit after ours makes the refresh fail, but ours has landed
+	if err := os.WriteFile(filepath.Join(tbl.URI, commitPathForVersion(2)), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tx, _ := tbl.NewTransaction()
+	tx.AddFiles(AddAction{Action: Action{Path: "part-1.parquet", Size: 1, DataChange: true}})
+	if v, err := tx.Commit(context.Background(), "WRITE"); err != nil || v != 1 {
+		t.Errorf("expected commit at version 1, got %d (%v)", v, err)
+	}
+	if _, err := os.Stat(filepath.Join(tbl.URI, commitPathForVersion(1))); err != nil {
+		t.Errorf("expected commit 1 to be written: %s", err)
+	}
+}
+
+func TestTransactionWriteSupport(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[{"name":"a","type":"long","nullable":true,"metadata":{}}]}`,
+		map[string]string{AppendOnlyKey: "true"})
+	ctx := context.Background()
+	var unsupported *UnsupportedProtocolError
+
+	// writer features the transaction does not enforce are rejected
+	tx, _ := tbl.NewTransaction()
+	tx.UpdateProtocol(Protocol{MinReaderVersion: 3, MinWriterVersion: 7,
+		ReaderFeatures: []string{"deletionVectors"}, WriterFeatures: []string{FeatureAppendOnly, "deletionVectors"}})
+	if _, err := tx.Commit(ctx, "WRITE"); !errors.As(err, &unsupported) || len(unsupported.Features) != 1 || unsupported.Features[0] != "deletionVectors" {
+		t.Errorf("expected unsupported writer feature deletionVectors, got %v", err)
+	}
+	tx.UpdateProtocol(Protocol{MinReaderVersion: 1, MinWriterVersion: 8})
+	if _, err := tx.Commit(ctx, "WRITE"); !errors.As(err, &unsupported) || unsupported.WriterVersion != 8 {
+		t.Errorf("expected unsupported writer version 8, got %v", err)
+	}
+
+	tx, _ = tbl.NewTransaction()
+	tx.AddFiles(AddAction{Action: Action{Path: "part-1.parquet", Size: 1, DataChange: true}})
+	if _, err := tx.Commit(ctx, "WRITE"); err != nil {
+		t.Fatalf("error appending: %s", err)
+	}
+
+	// an append-only table does not allow data to be removed, only rearranged
+	tx, _ = tbl.NewTransaction()
+	tx.RemoveFiles(RemoveAction{Action: Action{Path: "part-1.parquet", DataChange: true}})
+	var appendOnly *AppendOnlyError
+	if _, err := tx.Commit(ctx, "DELETE"); !errors.As(err, &appendOnly) || appendOnly.Path != "part-1.parquet" {
+		t.Errorf("expected AppendOnlyError, got %v", err)
+	}
+	tx, _ = tbl.NewTransaction()
+	tx.RemoveFiles(RemoveAction{Action: Action{Path: "part-1.parquet"}})
+	tx.AddFiles(AddAction{Action: Action{Path: "part-2.parquet", Size: 1}})
+	if _, err := tx.Commit(ctx, "OPTIMIZE"); err != nil {
+		t.Errorf("error compacting an append-only table: %s", err)
+	}
+
+	// rewriting data with the change data feed enabled would need change data files
+	tbl = createTestTable(t, `{"type":"struct","fields":[{"name":"a","type":"long","nullable":true,"metadata":{}}]}`,
+		map[string]string{EnableChangeDataFeedKey: "true"})
+	tx, _ = tbl.NewTransaction()
+	tx.RemoveFiles(RemoveAction{Action: Action{Path: "part-1.parquet", DataChange: true}})
+	tx.AddFiles(AddAction{Action: Action{Path: "part-2.parquet", Size: 1, DataChange: true}})
+	if _, err := tx.Commit(ctx, "UPDATE"); !errors.As(err, &unsupported) || unsupported.Features[0] != FeatureChangeDataFeed {
+		t.Errorf("expected unsupported change data feed, got %v", err)
+	}
+}
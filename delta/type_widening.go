@@ -0,0 +1,237 @@
+package delta
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/decimal128"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+// TypeChangesKey is the field metadata key that records the type changes applied to a
+// column by type widening. Data files written before a change store the column with the
+// old type.
+const TypeChangesKey = "delta.typeChanges"
+
+// TypeChange is a change of a column type applied without rewriting data files.
+type TypeChange struct {
+	FromType schema.DataType
+	ToType   schema.DataType
+	// FieldPath is set when the change applies to a nested element of the column, such
+	// as "element" for arrays or "key" and "value" for maps.
+	FieldPath string
+}
+
+// integralRanks orders the integral types by width.
+var integralRanks = map[schema.DataType]int{
+	schema.Byte:    1,
+	schema.Short:   2,
+	schema.Integer: 3,
+	schema.Long:    4,
+}
+
+// CanWiden reports whether every value of type from can be read as type to. Supported
+// widenings are byte to short to integer to long, float to double, date to timestamp_ntz
+// and decimals that grow in precision without losing integer digits.
+func CanWiden(from, to schema.DataType) bool {
+	if fr, ok := integralRanks[from]; ok {
+		tr, ok := integralRanks[to]
+		return ok && fr < tr
+	}
+
+	switch {
+	case from == schema.Float:
+		return to == schema.Double
+	case from == schema.Date:
+		return to == schema.TimestampNtz
+	}
+
+	fd, ok := from.(schema.DecimalType)
+	if !ok {
+		return false
+	}
+	td, ok := to.(schema.DecimalType)
+	return ok && fd != td && td.Scale >= fd.Scale && td.Precision-td.Scale >= fd.Precision-fd.Scale
+}
+
+// ParseTypeChanges returns the type changes recorded in the metadata of a field, oldest
+// first.
+func ParseTypeChanges(f schema.StructField) ([]TypeChange, error) {
+	raw, ok := f.Metadata[TypeChangesKey]
+	if !ok {
+		return nil, nil
+	}
+
+	entries, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("type changes of column %s: expected a list, got %T", f.Name, raw)
+	}
+	changes := make([]TypeChange, len(entries))
+	for i, e := range entries {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("type changes of column %s: expected an object, got %T", f.Name, e)
+		}
+		from, _ := m["fromType"].(string)
+		to, _ := m["toType"].(string)
+
+		var err error
+		if changes[i].FromType, err = schema.ParseTypeName(from); err != nil {
+			return nil, fmt.Errorf("type changes of column %s: %w", f.Name, err)
+		}
+		if changes[i].ToType, err = schema.ParseTypeName(to); err != nil {
+			return nil, fmt.Errorf("type changes of column %s: %w", f.Name, err)
+		}
+		changes[i].FieldPath, _ = m["fieldPath"].(string)
+	}
+	return changes, nil
+}
+
+func hasTypeChanges(s *schema.StructType) bool {
+	for _, f := range s.Fields {
+		if _, ok := f.Metadata[TypeChangesKey]; ok {
+			return true
+		}
+		if nested := structOf(f.Type); nested != nil && hasTypeChanges(nested) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeColumnType widens the type of a column without rewriting data files; see
+// CanWiden for the supported changes. The change is recorded in the field metadata so
+// that readers upcast values of files written before it. It requires the
+// delta.enableTypeWidening table property.
+type ChangeColumnType struct {
+	Path []string
+	Type schema.DataType
+}
+
+// SetTableProperties sets table properties.
+type SetTableProperties struct {
+	Properties map[string]string
+}
+
+func (c ChangeColumnType) operation() string { return "CHANGE COLUMN" }
+
+func (c ChangeColumnType) apply(a *alteration) error {
+	conf, err := ParseTableConfig(a.md.Configuration)
+	if err != nil {
+		return err
+	}
+	if !conf.EnableTypeWidening {
+		return fmt.Errorf("changing the type of column %s requires %s to be true", pathString(c.Path), EnableTypeWideningKey)
+	}
+
+	parent, i, err := a.lookupField(c.Path)
+	if err != nil {
+		return err
+	}
+	f := &parent.Fields[i]
+	if f.Type == c.Type {
+		return nil
+	}
+	if !CanWiden(f.Type, c.Type) {
+		return fmt.Errorf("cannot change the type of column %s from %s to %s", pathString(c.Path), f.Type, c.Type)
+	}
+
+	f.Metadata = cloneFieldMetadata(f.Metadata)
+	changes, _ := f.Metadata[TypeChangesKey].([]interface{})
+	f.Metadata[TypeChangesKey] = append(append([]interface{}(nil), changes...), map[string]interface{}{
+		"fromType": f.Type.Name(),
+		"toType":   c.Type.Name(),
+	})
+	f.Type = c.Type
+	return nil
+}
+
+func (c SetTableProperties) operation() string { return "SET TBLPROPERTIES" }
+
+func (c SetTableProperties) apply(a *alteration) error {
+	if a.md.Configuration == nil {
+		a.md.Configuration = make(map[string]string, len(c.Properties))
+	}
+	for k, v := range c.Properties {
+		a.md.Configuration[k] = v
+	}
+	return nil
+}
+
+// widenLeaf converts arr, the primitive values at fieldPath of a field in a data file, to
+// target, the Arrow type of dt, if changes record a change at fieldPath from the type of
+// arr. ok is false if they do not.
+func widenLeaf(dt schema.DataType, changes []TypeChange, fieldPath string, arr arrow.Array, target arrow.DataType, mem memory.Allocator) (widened arrow.Array, ok bool, err error) {
+	from, err := schema.FromArrowType(arr.DataType())
+	if err != nil || !CanWiden(from, dt) {
+		return nil, false, nil
+	}
+	for _, c := range changes {
+		if c.FieldPath == fieldPath && c.FromType == from {
+			widened, err := widenArray(arr, target, mem)
+			return widened, err == nil, err
+		}
+	}
+	return nil, false, nil
+}
+
+func joinFieldPath(fieldPath, name string) string {
+	if fieldPath == "" {
+		return name
+	}
+	return fieldPath + "." + name
+}
+
+// widenArray converts arr to the wider type to. The conversion must be one allowed by
+// CanWiden.
+func widenArray(arr arrow.Array, to arrow.DataType, mem memory.Allocator) (arrow.Array, error) {
+	b := array.NewBuilder(mem, to)
+	defer b.Release()
+	b.Reserve(arr.Len())
+
+	for i := 0; i < arr.Len(); i++ {
+		if arr.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+
+		switch b := b.(type) {
+		case *array.Int16Builder:
+			b.Append(int16(integralValue(arr, i)))
+		case *array.Int32Builder:
+			b.Append(int32(integralValue(arr, i)))
+		case *array.Int64Builder:
+			b.Append(integralValue(arr, i))
+		case *array.Float64Builder:
+			b.Append(float64(arr.(*array.Float32).Value(i)))
+		case *array.TimestampBuilder:
+			days := int64(arr.(*array.Date32).Value(i))
+			b.Append(arrow.Timestamp(days * 24 * 60 * 60 * 1e6))
+		case *array.Decimal128Builder:
+			fromScale := arr.DataType().(*arrow.Decimal128Type).Scale
+			toScale := to.(*arrow.Decimal128Type).Scale
+			v := arr.(*array.Decimal128).Value(i).BigInt()
+			v.Mul(v, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(toScale-fromScale)), nil))
+			b.Append(decimal128.FromBigInt(v))
+		default:
+			return nil, fmt.Errorf("cannot widen %s to %s", arr.DataType().Name(), to.Name())
+		}
+	}
+	return b.NewArray(), nil
+}
+
+func integralValue(arr arrow.Array, i int) int64 {
+	switch a := arr.(type) {
+	case *array.Int8:
+		return int64(a.Value(i))
+	case *array.Int16:
+		return int64(a.Value(i))
+	case *array.Int32:
+		return int64(a.Value(i))
+	default:
+		return arr.(*array.Int64).Value(i)
+	}
+}
@@ -0,0 +1,194 @@
+package delta
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/delta-golang/delta-go/delta/schema"
+)
+
+func TestCanWiden(t *testing.T) {
+	tests := []struct {
+		from, to schema.DataType
+		ok       bool
+	}{
+		{schema.Byte, schema.Short, true},
+		{schema.Integer, schema.Long, true},
+		{schema.Long, schema.Integer, false},
+		{schema.Float, schema.Double, true},
+		{schema.Integer, schema.Double, false},
+		{schema.Date, schema.TimestampNtz, true},
+		{schema.Date, schema.Timestamp, false},
+		{schema.DecimalType{Precision: 10, Scale: 2}, schema.DecimalType{Precision: 12, Scale: 4}, true},
+		{schema.DecimalType{Precision: 10, Scale: 2}, schema.DecimalType{Precision: 12, Scale: 5}, false},
+		{schema.DecimalType{Precision: 10, Scale: 2}, schema.DecimalType{Precision: 10, Scale: 2}, false},
+	}
+	for _, tt := range tests {
+		if got := CanWiden(tt.from, tt.to); got != tt.ok {
+			t.Errorf("%s to %s: expected %t, got %t", tt.from, tt.to, tt.ok, got)
+		}
+	}
+}
+
+// scanRecord scans the current snapshot of tbl, which must fit in one record, and returns
+// the reader positioned on that record. The reader must be released by the caller.
+func scanRecord(tbl *Table) (*ScanReader, error) {
+	r, err := tbl.Snapshot().Scan(ScanOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if !r.Next() {
+		r.Release()
+		if r.Err() != nil {
+			return nil, r.Err()
+		}
+		return nil, io.EOF
+	}
+	return r, nil
+}
+
+func TestChangeColumnType(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[`+
+		`{"name":"count","type":"integer","nullable":true,"metadata":{}},`+
+		`{"name":"d","type":"date","nullable":true,"metadata":{}},`+
+		`{"name":"amount","type":"decimal(5,1)","nullable":true,"metadata":{}},`+
+		`{"name":"s","type":{"type":"struct","fields":[{"name":"f","type":"float","nullable":true,"metadata":{}}]},"nullable":true,"metadata":{}}]}`, nil)
+	ctx := context.Background()
+
+	// a data file written before the type changes
+	file := arrow.NewSchema([]arrow.Field{
+		{Name: "count", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "d", Type: arrow.FixedWidthTypes.Date32, Nullable: true},
+		{Name: "amount", Type: &arrow.Decimal128Type{Precision: 5, Scale: 1}, Nullable: true},
+		{Name: "s", Type: arrow.StructOf(arrow.Field{Name: "f", Type: arrow.PrimitiveTypes.Float32, Nullable: true}), Nullable: true},
+	}, nil)
+	data, _, err := array.RecordFromJSON(memory.DefaultAllocator, file, strings.NewReader(
+		`[{"count":7,"d":"1970-01-02","amount":"12.5","s":{"f":1.5}},{"count":null,"d":null,"amount":null,"s":null}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Release()
+	addTestDataFile(t, tbl, "part-0.parquet", data)
+
+	widen := ChangeColumnType{Path: []string{"count"}, Type: schema.Long}
+	if _, err := tbl.AlterTable(ctx, widen); err == nil || !strings.Contains(err.Error(), EnableTypeWideningKey) {
+		t.Errorf("expected error without %s, got %v", EnableTypeWideningKey, err)
+	}
+
+	_, err = tbl.AlterTable(ctx,
+		SetTableProperties{Properties: map[string]string{EnableTypeWideningKey: "true"}},
+		widen,
+		ChangeColumnType{Path: []string{"d"}, Type: schema.TimestampNtz},
+		ChangeColumnType{Path: []string{"amount"}, Type: schema.DecimalType{Precision: 8, Scale: 3}},
+		ChangeColumnType{Path: []string{"s", "f"}, Type: schema.Double},
+	)
+	if err != nil {
+		t.Fatalf("error changing column types: %s", err)
+	}
+	if _, err := tbl.AlterTable(ctx, ChangeColumnType{Path: []string{"count"}, Type: schema.Integer}); err == nil {
+		t.Errorf("expected error narrowing a column")
+	}
+
+	expected := Protocol{
+		MinReaderVersion: 3,
+		MinWriterVersion: 7,
+		ReaderFeatures:   []string{FeatureTimestampNtz, FeatureTypeWidening},
+		WriterFeatures:   []string{FeatureAppendOnly, FeatureInvariants, FeatureTimestampNtz, FeatureTypeWidening},
+	}
+	if p := tbl.Snapshot().Protocol(); !reflect.DeepEqual(p, expected) {
+		t.Errorf("expected protocol %+v, got %+v", expected, p)
+	}
+
+	sch, _ := tbl.Snapshot().Schema()
+	f, _ := sch.Field("count")
+	changes, err := ParseTypeChanges(f)
+	if err != nil || f.Type != schema.Long || len(changes) != 1 || changes[0].FromType != schema.Integer || changes[0].ToType != schema.Long {
+		t.Errorf("expected a recorded change from integer to long, got %s %+v (%v)", f.Type, changes, err)
+	}
+
+	r, err := scanRecord(tbl)
+	if err != nil {
+		t.Fatalf("error scanning widened columns: %s", err)
+	}
+	defer r.Release()
+	rec := r.Record()
+
+	if v := rec.Column(0).(*array.Int64); v.Value(0) != 7 || !v.IsNull(1) {
+		t.Errorf("expected widened count [7 null], got %s", v)
+	}
+	if v := rec.Column(1).(*array.Timestamp); v.Value(0) != 86400*1e6 || !v.IsNull(1) {
+		t.Errorf("expected widened date 1970-01-02 00:00:00, got %s", v)
+	}
+	if v := rec.Column(2).(*array.Decimal128); v.Value(0).BigInt().Int64() != 12500 {
+		t.Errorf("expected widened amount 12.500, got %s", v.Value(0).BigInt())
+	}
+	s := rec.Column(3).(*array.Struct)
+	if v := s.Field(0).(*array.Float64); v.Value(0) != 1.5 || !s.IsNull(1) {
+		t.Errorf("expected widened s.f [1.5 null], got %s", s)
+	}
+}
+
+func TestScanWidenedNestedColumns(t *testing.T) {
+	tbl := createTestTable(t, `{"type":"struct","fields":[`+
+		`{"name":"xs","type":{"type":"array","elementType":"long","containsNull":true},"nullable":true,`+
+		`"metadata":{"delta.typeChanges":[{"fromType":"integer","toType":"long","fieldPath":"element"}]}},`+
+		`{"name":"m","type":{"type":"map","keyType":"string","valueType":{"type":"array","elementType":"double","containsNull":true},"valueContainsNull":true},"nullable":true,`+
+		`"metadata":{"delta.typeChanges":[{"fromType":"float","toType":"double","fieldPath":"value.element"}]}},`+
+		`{"name":"s","type":{"type":"struct","fields":[{"name":"n","type":"long","nullable":true,"metadata":{"delta.typeChanges":[{"fromType":"short","toType":"long"}]}}]},"nullable":true,"metadata":{}}]}`, nil)
+
+	file := arrow.NewSchema([]arrow.Field{
+		{Name: "xs", Type: arrow.ListOf(arrow.PrimitiveTypes.Int32), Nullable: true},
+		{Name: "m", Type: arrow.MapOf(arrow.BinaryTypes.String, arrow.ListOf(arrow.PrimitiveTypes.Float32)), Nullable: true},
+		{Name: "s", Type: arrow.StructOf(arrow.Field{Name: "n", Type: arrow.PrimitiveTypes.Int16, Nullable: true}), Nullable: true},
+	}, nil)
+	data, _, err := array.RecordFromJSON(memory.DefaultAllocator, file, strings.NewReader(`[
+		{"xs":[2,3],"m":[{"key":"a","value":[0.5]},{"key":"b","value":[1.5,2.5]}],"s":null},
+		{"xs":null,"m":null,"s":{"n":3}}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Release()
+	addTestDataFile(t, tbl, "part-0.parquet", data)
+
+	r, err := scanRecord(tbl)
+	if err != nil {
+		t.Fatalf("error scanning widened columns: %s", err)
+	}
+	defer r.Release()
+	rec := r.Record()
+
+	xs := rec.Column(0).(*array.List)
+	if values := xs.ListValues().(*array.Int64).Int64Values(); !reflect.DeepEqual(values, []int64{2, 3}) || xs.Len() != 2 || !xs.IsNull(1) {
+		t.Errorf("unexpected widened xs %s", xs)
+	}
+	m := rec.Column(1).(*array.Map)
+	if v := m.Items().(*array.List).ListValues().(*array.Float64); !reflect.DeepEqual(v.Float64Values(), []float64{0.5, 1.5, 2.5}) || m.Len() != 2 || !m.IsNull(1) {
+		t.Errorf("unexpected widened m %s", m)
+	}
+	s := rec.Column(2).(*array.Struct)
+	if n := s.Field(0).(*array.Int64); !s.IsNull(0) || n.Value(1) != 3 {
+		t.Errorf("unexpected widened s %s", s)
+	}
+
+	// narrower types without a recorded change are not upcast
+	tbl = createTestTable(t, `{"type":"struct","fields":[`+
+		`{"name":"s","type":{"type":"struct","fields":[{"name":"f","type":"long","nullable":true,"metadata":{}}]},"nullable":true,"metadata":{}}]}`, nil)
+	bad := arrow.NewSchema([]arrow.Field{{Name: "s", Type: arrow.StructOf(arrow.Field{Name: "f", Type: arrow.PrimitiveTypes.Int32, Nullable: true}), Nullable: true}}, nil)
+	data, _, err = array.RecordFromJSON(memory.DefaultAllocator, bad, strings.NewReader(`[{"s":{"f":1}}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer data.Release()
+	addTestDataFile(t, tbl, "part-0.parquet", data)
+	if r, err := scanRecord(tbl); err == nil {
+		r.Release()
+		t.Errorf("expected error for a type without a recorded change")
+	}
+}
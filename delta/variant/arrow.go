@@ -0,0 +1,145 @@
+package variant
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/delta-golang/delta-go/delta/expr"
+)
+
+// FromArrow decodes the variant at index i of arr, a struct with a binary metadata field
+// and a binary value field. Shredded variants are supported as well: they have a
+// typed_value field holding the value as an Arrow primitive, a struct of shredded object
+// fields, or a list of shredded elements, and the value field holds what was not
+// shredded.
+func FromArrow(arr arrow.Array, i int) (interface{}, error) {
+	st, ok := arr.(*array.Struct)
+	if !ok {
+		return nil, fmt.Errorf("variant: expected a struct column, got %s", arr.DataType().Name())
+	}
+	if st.IsNull(i) {
+		return nil, nil
+	}
+
+	metadata, ok := structField(st, "metadata").(*array.Binary)
+	if !ok || metadata.IsNull(i) {
+		return nil, fmt.Errorf("variant: missing metadata")
+	}
+	m, err := ParseMetadata(metadata.Value(i))
+	if err != nil {
+		return nil, err
+	}
+	v, _, err := m.decodeShredded(st, i)
+	return v, err
+}
+
+// decodeShredded decodes the value and typed_value fields of a shredded group. present is
+// false when both are NULL, which marks a missing object field.
+func (m *Metadata) decodeShredded(group *array.Struct, i int) (v interface{}, present bool, err error) {
+	value, _ := structField(group, "value").(*array.Binary)
+	typed := structField(group, "typed_value")
+
+	hasValue := value != nil && value.IsValid(i)
+	if typed == nil || typed.IsNull(i) {
+		if !hasValue {
+			return nil, false, nil
+		}
+		v, err := m.Decode(value.Value(i))
+		return v, true, err
+	}
+
+	switch t := typed.(type) {
+	case *array.Struct:
+		obj := make(map[string]interface{})
+		if hasValue {
+			// a partially shredded object holds its other fields in value
+			rest, err := m.Decode(value.Value(i))
+			if err != nil {
+				return nil, false, err
+			}
+			restObj, ok := rest.(map[string]interface{})
+			if !ok {
+				return nil, false, fmt.Errorf("variant: shredded object has a non-object value")
+			}
+			for k, v := range restObj {
+				obj[k] = v
+			}
+		}
+
+		fields := t.DataType().(*arrow.StructType).Fields()
+		for j, f := range fields {
+			fieldGroup, ok := t.Field(j).(*array.Struct)
+			if !ok {
+				return nil, false, fmt.Errorf("variant: shredded field %s is not a struct", f.Name)
+			}
+			fv, present, err := m.decodeShredded(fieldGroup, i)
+			if err != nil {
+				return nil, false, err
+			}
+			if present {
+				obj[f.Name] = fv
+			}
+		}
+		return obj, true, nil
+	case *array.List:
+		elements, ok := t.ListValues().(*array.Struct)
+		if !ok {
+			return nil, false, fmt.Errorf("variant: shredded array elements are not structs")
+		}
+		offsets := t.Offsets()
+		arr := make([]interface{}, 0, offsets[i+1]-offsets[i])
+		for j := int(offsets[i]); j < int(offsets[i+1]); j++ {
+			ev, _, err := m.decodeShredded(elements, j)
+			if err != nil {
+				return nil, false, err
+			}
+			arr = append(arr, ev)
+		}
+		return arr, true, nil
+	}
+
+	v, err = primitiveValue(typed, i)
+	return v, true, err
+}
+
+// primitiveValue converts a shredded primitive to the Go value Decode returns for it.
+func primitiveValue(arr arrow.Array, i int) (interface{}, error) {
+	switch a := arr.(type) {
+	case *array.Boolean:
+		return a.Value(i), nil
+	case *array.Int8:
+		return int64(a.Value(i)), nil
+	case *array.Int16:
+		return int64(a.Value(i)), nil
+	case *array.Int32:
+		return int64(a.Value(i)), nil
+	case *array.Int64:
+		return a.Value(i), nil
+	case *array.Float32:
+		return a.Value(i), nil
+	case *array.Float64:
+		return a.Value(i), nil
+	case *array.String:
+		return a.Value(i), nil
+	case *array.Binary:
+		return append([]byte(nil), a.Value(i)...), nil
+	case *array.Date32:
+		return time.Unix(int64(a.Value(i))*24*60*60, 0).UTC(), nil
+	case *array.Timestamp:
+		unit := a.DataType().(*arrow.TimestampType).Unit
+		return time.Unix(0, int64(a.Value(i))*int64(unit.Multiplier())).UTC(), nil
+	case *array.Decimal128:
+		return expr.NewDecimal(a.Value(i).BigInt(), int(a.DataType().(*arrow.Decimal128Type).Scale)), nil
+	}
+	return nil, fmt.Errorf("variant: unsupported shredded type %s", arr.DataType().Name())
+}
+
+func structField(st *array.Struct, name string) arrow.Array {
+	t := st.DataType().(*arrow.StructType)
+	if i, ok := t.FieldIdx(name); ok {
+		return st.Field(i)
+	}
+	return nil
+}
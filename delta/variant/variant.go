@@ -0,0 +1,318 @@
+// Package variant decodes values of the variant type, which stores semi-structured data
+// in a binary encoding of two parts: a metadata dictionary of object keys and the value
+// itself.
+//
+// Decoded values are nil, bool, int64, float32, float64, expr.Decimal, string, []byte,
+// uuid.UUID, time.Time for dates and timestamps, time.Duration for times of day,
+// map[string]interface{} for objects and []interface{} for arrays.
+package variant
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/delta-golang/delta-go/delta/expr"
+	"github.com/google/uuid"
+)
+
+var errTruncated = errors.New("variant: truncated encoding")
+
+// Basic types, stored in the low two bits of a value header.
+const (
+	basicPrimitive   = 0
+	basicShortString = 1
+	basicObject      = 2
+	basicArray       = 3
+)
+
+// Primitive type ids, stored in the upper six bits of the header of a primitive value.
+const (
+	primitiveNull              = 0
+	primitiveTrue              = 1
+	primitiveFalse             = 2
+	primitiveInt8              = 3
+	primitiveInt16             = 4
+	primitiveInt32             = 5
+	primitiveInt64             = 6
+	primitiveDouble            = 7
+	primitiveDecimal4          = 8
+	primitiveDecimal8          = 9
+	primitiveDecimal16         = 10
+	primitiveDate              = 11
+	primitiveTimestamp         = 12
+	primitiveTimestampNtz      = 13
+	primitiveFloat             = 14
+	primitiveBinary            = 15
+	primitiveString            = 16
+	primitiveTime              = 17
+	primitiveTimestampNanos    = 18
+	primitiveTimestampNtzNanos = 19
+	primitiveUUID              = 20
+)
+
+// Metadata is the decoded dictionary of object keys of a variant.
+type Metadata struct {
+	keys []string
+}
+
+// ParseMetadata decodes the metadata part of a variant.
+func ParseMetadata(b []byte) (*Metadata, error) {
+	if len(b) < 1 {
+		return nil, errTruncated
+	}
+	if version := b[0] & 0x0f; version != 1 {
+		return nil, fmt.Errorf("variant: unsupported metadata version %d", version)
+	}
+	offsetSize := int(b[0]>>6) + 1
+
+	size, err := readUint(b, 1, offsetSize)
+	if err != nil {
+		return nil, err
+	}
+	offsetsStart := 1 + offsetSize
+	dataStart := offsetsStart + (size+1)*offsetSize
+	if size < 0 || dataStart > len(b) || dataStart < offsetsStart {
+		return nil, errTruncated
+	}
+
+	m := &Metadata{keys: make([]string, size)}
+	for i := range m.keys {
+		start, err := readUint(b, offsetsStart+i*offsetSize, offsetSize)
+		if err != nil {
+			return nil, err
+		}
+		end, err := readUint(b, offsetsStart+(i+1)*offsetSize, offsetSize)
+		if err != nil {
+			return nil, err
+		}
+		if start > end || dataStart+end > len(b) {
+			return nil, errTruncated
+		}
+		m.keys[i] = string(b[dataStart+start : dataStart+end])
+	}
+	return m, nil
+}
+
+// Key returns the object key with dictionary id id.
+func (m *Metadata) Key(id int) (string, error) {
+	if id < 0 || id >= len(m.keys) {
+		return "", fmt.Errorf("variant: key id %d out of range", id)
+	}
+	return m.keys[id], nil
+}
+
+// Decode decodes a variant from its metadata and value parts.
+func Decode(metadata, value []byte) (interface{}, error) {
+	m, err := ParseMetadata(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return m.Decode(value)
+}
+
+// Decode decodes a variant value whose object keys are stored in m.
+func (m *Metadata) Decode(value []byte) (interface{}, error) {
+	if len(value) < 1 {
+		return nil, errTruncated
+	}
+	header := value[0]
+	basic, typ := header&0x03, int(header>>2)
+	b := value[1:]
+
+	switch basic {
+	case basicShortString:
+		if typ > len(b) {
+			return nil, errTruncated
+		}
+		return string(b[:typ]), nil
+	case basicObject:
+		return m.decodeObject(typ, b)
+	case basicArray:
+		return m.decodeArray(typ, b)
+	}
+
+	switch typ {
+	case primitiveNull:
+		return nil, nil
+	case primitiveTrue:
+		return true, nil
+	case primitiveFalse:
+		return false, nil
+	case primitiveInt8:
+		v, err := readInt(b, 1)
+		return v, err
+	case primitiveInt16:
+		v, err := readInt(b, 2)
+		return v, err
+	case primitiveInt32:
+		v, err := readInt(b, 4)
+		return v, err
+	case primitiveInt64:
+		v, err := readInt(b, 8)
+		return v, err
+	case primitiveDouble:
+		if len(b) < 8 {
+			return nil, errTruncated
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case primitiveFloat:
+		if len(b) < 4 {
+			return nil, errTruncated
+		}
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case primitiveDecimal4, primitiveDecimal8, primitiveDecimal16:
+		size := map[int]int{primitiveDecimal4: 4, primitiveDecimal8: 8, primitiveDecimal16: 16}[typ]
+		if len(b) < 1+size {
+			return nil, errTruncated
+		}
+		return expr.NewDecimal(littleEndianInt(b[1:1+size]), int(b[0])), nil
+	case primitiveDate:
+		days, err := readInt(b, 4)
+		return time.Unix(days*24*60*60, 0).UTC(), err
+	case primitiveTimestamp, primitiveTimestampNtz:
+		micros, err := readInt(b, 8)
+		return time.UnixMicro(micros).UTC(), err
+	case primitiveTimestampNanos, primitiveTimestampNtzNanos:
+		nanos, err := readInt(b, 8)
+		return time.Unix(0, nanos).UTC(), err
+	case primitiveTime:
+		micros, err := readInt(b, 8)
+		return time.Duration(micros) * time.Microsecond, err
+	case primitiveBinary, primitiveString:
+		if len(b) < 4 {
+			return nil, errTruncated
+		}
+		n := int(binary.LittleEndian.Uint32(b))
+		if n < 0 || 4+n > len(b) {
+			return nil, errTruncated
+		}
+		if typ == primitiveString {
+			return string(b[4 : 4+n]), nil
+		}
+		return append([]byte(nil), b[4:4+n]...), nil
+	case primitiveUUID:
+		if len(b) < 16 {
+			return nil, errTruncated
+		}
+		var u uuid.UUID
+		copy(u[:], b)
+		return u, nil
+	}
+	return nil, fmt.Errorf("variant: unsupported primitive type %d", typ)
+}
+
+func (m *Metadata) decodeObject(typ int, b []byte) (interface{}, error) {
+	offsetSize := typ&0x03 + 1
+	idSize := (typ>>2)&0x03 + 1
+	countSize := 1
+	if typ&0x10 != 0 {
+		countSize = 4
+	}
+
+	n, err := readUint(b, 0, countSize)
+	if err != nil {
+		return nil, err
+	}
+	idsStart := countSize
+	offsetsStart := idsStart + n*idSize
+	dataStart := offsetsStart + (n+1)*offsetSize
+	if n < 0 || dataStart > len(b) || dataStart < idsStart {
+		return nil, errTruncated
+	}
+
+	obj := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		id, err := readUint(b, idsStart+i*idSize, idSize)
+		if err != nil {
+			return nil, err
+		}
+		key, err := m.Key(id)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := readUint(b, offsetsStart+i*offsetSize, offsetSize)
+		if err != nil {
+			return nil, err
+		}
+		if dataStart+offset >= len(b) {
+			return nil, errTruncated
+		}
+		if obj[key], err = m.Decode(b[dataStart+offset:]); err != nil {
+			return nil, err
+		}
+	}
+	return obj, nil
+}
+
+func (m *Metadata) decodeArray(typ int, b []byte) (interface{}, error) {
+	offsetSize := typ&0x03 + 1
+	countSize := 1
+	if typ&0x04 != 0 {
+		countSize = 4
+	}
+
+	n, err := readUint(b, 0, countSize)
+	if err != nil {
+		return nil, err
+	}
+	offsetsStart := countSize
+	dataStart := offsetsStart + (n+1)*offsetSize
+	if n < 0 || dataStart > len(b) || dataStart < offsetsStart {
+		return nil, errTruncated
+	}
+
+	arr := make([]interface{}, n)
+	for i := range arr {
+		offset, err := readUint(b, offsetsStart+i*offsetSize, offsetSize)
+		if err != nil {
+			return nil, err
+		}
+		if dataStart+offset >= len(b) {
+			return nil, errTruncated
+		}
+		if arr[i], err = m.Decode(b[dataStart+offset:]); err != nil {
+			return nil, err
+		}
+	}
+	return arr, nil
+}
+
+// readUint reads a little-endian unsigned integer of size bytes at offset pos.
+func readUint(b []byte, pos, size int) (int, error) {
+	if pos < 0 || pos+size > len(b) {
+		return 0, errTruncated
+	}
+	var v uint64
+	for i := size - 1; i >= 0; i-- {
+		v = v<<8 | uint64(b[pos+i])
+	}
+	if v > math.MaxInt32 {
+		return 0, fmt.Errorf("variant: size %d out of range", v)
+	}
+	return int(v), nil
+}
+
+// readInt reads a little-endian signed integer of size bytes.
+func readInt(b []byte, size int) (int64, error) {
+	if len(b) < size {
+		return 0, errTruncated
+	}
+	return littleEndianInt(b[:size]).Int64(), nil
+}
+
+// littleEndianInt decodes a little-endian two's complement integer.
+func littleEndianInt(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i, c := range b {
+		be[len(b)-1-i] = c
+	}
+	v := new(big.Int).SetBytes(be)
+	if len(b) > 0 && b[len(b)-1]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(8*len(b))))
+	}
+	return v
+}
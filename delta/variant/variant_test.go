@@ -0,0 +1,141 @@
+package variant
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow/go/v8/arrow"
+	"github.com/apache/arrow/go/v8/arrow/array"
+	"github.com/apache/arrow/go/v8/arrow/memory"
+	"github.com/delta-golang/delta-go/delta/expr"
+)
+
+// metadata with the keys "a" and "b"
+var testMetadata = []byte{0x01, 2, 0, 1, 2, 'a', 'b'}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name  string
+		value []byte
+		want  interface{}
+	}{
+		{"null", []byte{0x00}, nil},
+		{"true", []byte{1 << 2}, true},
+		{"int8", []byte{3 << 2, 0xff}, int64(-1)},
+		{"int32", []byte{5 << 2, 0x00, 0x01, 0x00, 0x00}, int64(256)},
+		{"double", []byte{7 << 2, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f}, 1.5},
+		{"float", []byte{14 << 2, 0, 0, 0xc0, 0x3f}, float32(1.5)},
+		{"date", []byte{11 << 2, 1, 0, 0, 0}, time.Date(1970, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"timestamp", []byte{12 << 2, 0x40, 0x42, 0x0f, 0, 0, 0, 0, 0}, time.Unix(1, 0).UTC()},
+		{"short string", []byte{2<<2 | 1, 'h', 'i'}, "hi"},
+		{"string", []byte{16 << 2, 3, 0, 0, 0, 'a', 'b', 'c'}, "abc"},
+		{"binary", []byte{15 << 2, 1, 0, 0, 0, 0xff}, []byte{0xff}},
+		// {"a": 1, "b": [true, "x"]}
+		{"object", []byte{0x02, 2, 0, 1, 0, 2, 10, 3 << 2, 1, 0x03, 2, 0, 1, 3, 1 << 2, 1<<2 | 1, 'x'},
+			map[string]interface{}{"a": int64(1), "b": []interface{}{true, "x"}}},
+	}
+
+	for _, tt := range tests {
+		got, err := Decode(testMetadata, tt.value)
+		if err != nil {
+			t.Errorf("%s: error decoding: %s", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %#v, got %#v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestDecodeDecimal(t *testing.T) {
+	// -12.34 as decimal4 with scale 2
+	got, err := Decode(testMetadata, []byte{8 << 2, 2, 0x2e, 0xfb, 0xff, 0xff})
+	if err != nil {
+		t.Fatalf("error decoding: %s", err)
+	}
+	d, ok := got.(expr.Decimal)
+	if !ok || d.String() != "-12.34" || d.Float64() != -12.34 {
+		t.Errorf("expected -12.34, got %#v", got)
+	}
+
+	// 0.00123456789012345678901234567 as decimal16 with scale 29 is rounded once
+	value := []byte{10 << 2, 29}
+	unscaled, _ := new(big.Int).SetString("123456789012345678901234567", 10)
+	be := unscaled.FillBytes(make([]byte, 16))
+	for i := len(be) - 1; i >= 0; i-- {
+		value = append(value, be[i])
+	}
+	got, err = Decode(testMetadata, value)
+	if err != nil {
+		t.Fatalf("error decoding: %s", err)
+	}
+	if d, ok := got.(expr.Decimal); !ok || d.Float64() != 0.0012345678901234567 {
+		t.Errorf("expected 0.0012345678901234567, got %#v", got)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	for _, value := range [][]byte{
+		{},
+		{6 << 2, 1, 2},
+		{2<<2 | 1, 'h'},
+		{0x02, 1, 5, 0, 1, 0},
+		{0x03, 2, 0, 1},
+		{63 << 2},
+	} {
+		if _, err := Decode(testMetadata, value); err == nil {
+			t.Errorf("%v: expected error", value)
+		}
+	}
+	if _, err := Decode([]byte{0x02, 0, 0}, []byte{0}); err == nil {
+		t.Errorf("expected error for unsupported metadata version")
+	}
+}
+
+func TestFromArrow(t *testing.T) {
+	field := func(name string, typ arrow.DataType) arrow.Field {
+		return arrow.Field{Name: name, Type: typ, Nullable: true}
+	}
+	shreddedInt := arrow.StructOf(field("value", arrow.BinaryTypes.Binary), field("typed_value", arrow.PrimitiveTypes.Int64))
+	typ := arrow.StructOf(
+		field("metadata", arrow.BinaryTypes.Binary),
+		field("value", arrow.BinaryTypes.Binary),
+		field("typed_value", arrow.StructOf(field("a", shreddedInt))),
+	)
+	sch := arrow.NewSchema([]arrow.Field{field("v", typ)}, nil)
+
+	// row 0: {"a": 5} fully shredded
+	// row 1: {"a": "hi", "b": true}, a stored unshredded and b in the residual object
+	// row 2: "hi", not an object so not shredded
+	// row 3: NULL
+	rec, _, err := array.RecordFromJSON(memory.DefaultAllocator, sch, strings.NewReader(`[
+		{"v": {"metadata": "AQIAAQJhYg==", "value": null, "typed_value": {"a": {"value": null, "typed_value": 5}}}},
+		{"v": {"metadata": "AQIAAQJhYg==", "value": "AgEBAAEE", "typed_value": {"a": {"value": "CWhp", "typed_value": null}}}},
+		{"v": {"metadata": "AQIAAQJhYg==", "value": "CWhp", "typed_value": null}},
+		{"v": null}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+
+	want := []interface{}{
+		map[string]interface{}{"a": int64(5)},
+		map[string]interface{}{"a": "hi", "b": true},
+		"hi",
+		nil,
+	}
+	for i, w := range want {
+		got, err := FromArrow(rec.Column(0), i)
+		if err != nil {
+			t.Errorf("row %d: error decoding: %s", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("row %d: expected %#v, got %#v", i, w, got)
+		}
+	}
+}